@@ -47,6 +47,12 @@ func main() {
 		Timeout:    5 * time.Minute,
 	}
 	exec := terraform.New(config, locker, taskDAO, hub)
+	exec.SetDurationHistory(dao.NewResourceDurationDAO(db))
+	exec.SetOutcomeDAO(dao.NewTaskResourceOutcomeDAO(db))
+	exec.SetDiagnosticDAO(dao.NewTaskDiagnosticDAO(db))
+	exec.SetPatternSource(dao.NewErrorPatternDAO(db))
+	exec.SetResourceDAO(dao.NewTerraformResourceDAO(db))
+	exec.SetEventDAO(dao.NewTaskEventDAO(db))
 	fmt.Println("✓ Terraform executor created")
 	fmt.Printf("✓ Using config: %s\n", workDir)
 
@@ -60,6 +66,11 @@ func main() {
 		ResourceID: 1,
 		Action:     executor.ActionPlan,
 		WorkDir:    workDir,
+		Provider:   "aws",
+		Credentials: map[string]string{
+			"access_key": "test", // LocalStack 接受任意值
+			"secret_key": "test",
+		},
 	}
 
 	result, err := exec.Execute(ctx, planReq)
@@ -8,9 +8,8 @@ terraform {
 }
 
 # 核心配置：所有请求转发给 LocalStack
+# 凭证由 Prism 通过环境变量注入 (AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY)
 provider "aws" {
-  access_key                  = "test"     # 随便填
-  secret_key                  = "test"     # 随便填
   region                      = "us-east-1"
   
   # 关键：跳过真实的验证
@@ -0,0 +1,169 @@
+// Package abstract translates provider-agnostic resource types such as
+// compute.instance into provider resource configs such as aws_instance.
+package abstract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/cylonchau/prism/pkg/transform"
+	"gorm.io/gorm"
+)
+
+// ResourceName 渲染的资源在配置中的名字
+const ResourceName = "this"
+
+// Rendered 抽象资源渲染的云厂商配置
+type Rendered struct {
+	ResourceType string // 云厂商资源类型，如 aws_instance
+	Config       string
+}
+
+// CreateRequest 创建抽象资源的请求，属性使用标准属性名
+type CreateRequest struct {
+	ID         int64
+	Type       string // 抽象类型，如 compute.instance
+	Provider   string
+	RegionId   string
+	Tenant     string
+	Credential string
+	Attributes map[string]string
+}
+
+// Translator 按翻译表将抽象资源渲染为云厂商的 Terraform 配置
+type Translator struct {
+	types     *dao.AbstractTypeDAO
+	resources *dao.TerraformResourceDAO
+}
+
+// NewTranslator 创建翻译器
+func NewTranslator(types *dao.AbstractTypeDAO, resources *dao.TerraformResourceDAO) *Translator {
+	return &Translator{types: types, resources: resources}
+}
+
+// Create 渲染抽象资源并创建 TerraformResource，ResourceType 记录抽象类型
+func (t *Translator) Create(req *CreateRequest) (*models.TerraformResource, error) {
+	rendered, err := t.Render(req.Type, req.Provider, req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	resource := &models.TerraformResource{
+		ID:           req.ID,
+		Provider:     req.Provider,
+		ResourceType: req.Type,
+		RegionId:     req.RegionId,
+		Tenant:       req.Tenant,
+		Credential:   req.Credential,
+		TfConfig:     rendered.Config,
+		Status:       models.ResourceStatusPending,
+	}
+	if err := t.resources.Create(resource); err != nil {
+		return nil, fmt.Errorf("failed to create %s resource: %w", req.Type, err)
+	}
+	return resource, nil
+}
+
+// Render 将标准属性翻译为云厂商资源的配置。未输入的属性使用默认值，值为空的可选属性不渲染；
+// 值为 var.<name> 时渲染为变量引用并声明该变量，用于注入上游资源的属性
+func (t *Translator) Render(typeName, provider string, attrs map[string]string) (*Rendered, error) {
+	abstractType, err := t.types.Get(typeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load abstract type %s: %w", typeName, err)
+	}
+	translation, err := t.types.GetTranslation(abstractType.ID, provider)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("abstract type %s is not available on provider %s", typeName, provider)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load translation of %s for %s: %w", typeName, provider, err)
+	}
+
+	known := make(map[string]bool, len(abstractType.Attributes))
+	for _, a := range abstractType.Attributes {
+		known[a.Name] = true
+	}
+	var unknown []string
+	for name := range attrs {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown attributes of %s: %s", typeName, strings.Join(unknown, ", "))
+	}
+
+	targets := make(map[string]models.AttributeTranslation, len(translation.Attributes))
+	for _, a := range translation.Attributes {
+		targets[a.Attribute] = a
+	}
+
+	body := newBlock()
+	variables := make(map[string]bool)
+	for _, a := range abstractType.Attributes {
+		value, ok := attrs[a.Name]
+		if !ok {
+			value = a.DefaultValue
+		}
+		if value == "" {
+			if a.IsRequired {
+				return nil, fmt.Errorf("attribute %s of %s is required", a.Name, typeName)
+			}
+			continue
+		}
+		target, ok := targets[a.Name]
+		if !ok {
+			return nil, fmt.Errorf("attribute %s of %s is not supported on provider %s", a.Name, typeName, provider)
+		}
+
+		valueType := a.ValueType
+		if target.ValueType != "" {
+			valueType = target.ValueType
+		}
+		if name, ok := reference(value); ok {
+			variables[name] = true
+		} else if value, err = transform.Apply(target.Transform, value); err != nil {
+			return nil, fmt.Errorf("failed to transform attribute %s: %w", a.Name, err)
+		}
+		expr, err := expression(value, valueType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attribute %s: %w", a.Name, err)
+		}
+		if err := body.set(target.Target, expr); err != nil {
+			return nil, fmt.Errorf("invalid translation of attribute %s: %w", a.Name, err)
+		}
+	}
+
+	return &Rendered{
+		ResourceType: translation.ResourceType,
+		Config:       render(translation, body, variables),
+	}, nil
+}
+
+// render 生成完整配置：required_providers、变量声明与资源块
+func render(translation *models.ProviderTranslation, body *block, variables map[string]bool) string {
+	var buf bytes.Buffer
+	if translation.Source != "" {
+		fmt.Fprintf(&buf, "terraform {\n  required_providers {\n    %s = {\n      source = %s\n    }\n  }\n}\n\n",
+			translation.Provider, quote(translation.Source))
+	}
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&buf, "variable %s {}\n\n", quote(name))
+	}
+
+	fmt.Fprintf(&buf, "resource %s %s {\n", quote(translation.ResourceType), quote(ResourceName))
+	body.write(&buf, "  ")
+	buf.WriteString("}\n")
+	return buf.String()
+}
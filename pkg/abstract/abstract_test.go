@@ -0,0 +1,143 @@
+package abstract
+
+import (
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTranslator(t *testing.T) (*Translator, *dao.TerraformResourceDAO) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	types := dao.NewAbstractTypeDAO(db)
+	resources := dao.NewTerraformResourceDAO(db)
+
+	instance := &models.AbstractType{
+		Name: "compute.instance",
+		Attributes: []models.AbstractAttribute{
+			{Name: "name", IsRequired: true},
+			{Name: "size", IsRequired: true, DefaultValue: "small"},
+			{Name: "image", IsRequired: true},
+			{Name: "disk_gb", ValueType: "int"},
+			{Name: "subnet_id"},
+			{Name: "gpu", ValueType: "bool"},
+		},
+	}
+	require.NoError(t, types.Create(instance))
+	require.NoError(t, types.SetTranslation(&models.ProviderTranslation{
+		TypeID:       instance.ID,
+		Provider:     "aws",
+		ResourceType: "aws_instance",
+		Attributes: []models.AttributeTranslation{
+			{Attribute: "name", Target: "tags[Name]"},
+			{Attribute: "size", Target: "instance_type", Transform: `map:{"small":"t3.small","large":"m5.large"}`},
+			{Attribute: "image", Target: "ami"},
+			{Attribute: "disk_gb", Target: "root_block_device.volume_size"},
+			{Attribute: "subnet_id", Target: "subnet_id"},
+		},
+	}))
+	require.NoError(t, types.SetTranslation(&models.ProviderTranslation{
+		TypeID:       instance.ID,
+		Provider:     "tencentcloud",
+		ResourceType: "tencentcloud_instance",
+		Source:       "tencentcloudstack/tencentcloud",
+		Attributes: []models.AttributeTranslation{
+			{Attribute: "name", Target: "instance_name"},
+			{Attribute: "size", Target: "instance_type", Transform: `map:{"small":"S5.SMALL2","large":"S5.LARGE8"}`},
+			{Attribute: "image", Target: "image_id"},
+			{Attribute: "disk_gb", Target: "system_disk_size", ValueType: "number"},
+		},
+	}))
+	return NewTranslator(types, resources), resources
+}
+
+func TestTranslator_Render(t *testing.T) {
+	translator, _ := setupTranslator(t)
+	attrs := map[string]string{"name": "web", "image": "ami-1", "disk_gb": "20", "subnet_id": "var.subnet_id"}
+
+	rendered, err := translator.Render("compute.instance", "aws", attrs)
+	require.NoError(t, err)
+	assert.Equal(t, "aws_instance", rendered.ResourceType)
+	assert.Equal(t, `variable "subnet_id" {}
+
+resource "aws_instance" "this" {
+  ami = "ami-1"
+  instance_type = "t3.small"
+  subnet_id = var.subnet_id
+  tags = {
+    Name = "web"
+  }
+
+  root_block_device {
+    volume_size = 20
+  }
+}
+`, rendered.Config)
+
+	delete(attrs, "subnet_id")
+	attrs["size"] = "large"
+	rendered, err = translator.Render("compute.instance", "tencentcloud", attrs)
+	require.NoError(t, err)
+	assert.Equal(t, `terraform {
+  required_providers {
+    tencentcloud = {
+      source = "tencentcloudstack/tencentcloud"
+    }
+  }
+}
+
+resource "tencentcloud_instance" "this" {
+  image_id = "ami-1"
+  instance_name = "web"
+  instance_type = "S5.LARGE8"
+  system_disk_size = 20
+}
+`, rendered.Config)
+}
+
+func TestTranslator_RenderErrors(t *testing.T) {
+	translator, _ := setupTranslator(t)
+
+	tests := []struct {
+		typeName, provider string
+		attrs              map[string]string
+		err                string
+	}{
+		{"compute.gpu", "aws", nil, "failed to load abstract type"},
+		{"compute.instance", "gcp", nil, "not available on provider gcp"},
+		{"compute.instance", "aws", map[string]string{"name": "web"}, "attribute image of compute.instance is required"},
+		{"compute.instance", "aws", map[string]string{"name": "web", "image": "i", "cpu": "2"}, "unknown attributes of compute.instance: cpu"},
+		{"compute.instance", "aws", map[string]string{"name": "web", "image": "i", "size": "huge"}, `no mapping for "huge"`},
+		{"compute.instance", "aws", map[string]string{"name": "web", "image": "i", "disk_gb": "big"}, "invalid attribute disk_gb"},
+		{"compute.instance", "aws", map[string]string{"name": "web", "image": "i", "gpu": "true"}, "attribute gpu of compute.instance is not supported on provider aws"},
+	}
+	for _, tt := range tests {
+		_, err := translator.Render(tt.typeName, tt.provider, tt.attrs)
+		assert.ErrorContains(t, err, tt.err)
+	}
+}
+
+func TestTranslator_Create(t *testing.T) {
+	translator, resources := setupTranslator(t)
+
+	resource, err := translator.Create(&CreateRequest{
+		ID:         7,
+		Type:       "compute.instance",
+		Provider:   "aws",
+		Tenant:     "team-a",
+		Attributes: map[string]string{"name": "web", "image": "ami-1"},
+	})
+	require.NoError(t, err)
+
+	stored, err := resources.Get(7)
+	require.NoError(t, err)
+	assert.Equal(t, "compute.instance", stored.ResourceType)
+	assert.Equal(t, "team-a", stored.Tenant)
+	assert.Equal(t, resource.TfConfig, stored.TfConfig)
+	assert.Contains(t, stored.TfConfig, `resource "aws_instance" "this"`)
+}
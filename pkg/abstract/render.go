@@ -0,0 +1,236 @@
+package abstract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	mapKeyPattern     = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\[([^\]]+)\]$`)
+	referencePattern  = regexp.MustCompile(`^var\.([A-Za-z_][A-Za-z0-9_]*)$`)
+)
+
+// block 待渲染的 HCL 块
+type block struct {
+	attrs  map[string]string            // 属性名 → 表达式
+	maps   map[string]map[string]string // map 属性 → 键 → 表达式
+	blocks map[string]*block            // 嵌套块
+}
+
+func newBlock() *block {
+	return &block{
+		attrs:  make(map[string]string),
+		maps:   make(map[string]map[string]string),
+		blocks: make(map[string]*block),
+	}
+}
+
+// set 按属性路径设置表达式，见 models.AttributeTranslation.Target
+func (b *block) set(target, expr string) error {
+	segments := strings.Split(target, ".")
+	for _, name := range segments[:len(segments)-1] {
+		if !identifierPattern.MatchString(name) {
+			return fmt.Errorf("invalid block name %q in %s", name, target)
+		}
+		if b.defined(name) {
+			return fmt.Errorf("%s conflicts with attribute %s", target, name)
+		}
+		child, ok := b.blocks[name]
+		if !ok {
+			child = newBlock()
+			b.blocks[name] = child
+		}
+		b = child
+	}
+
+	last := segments[len(segments)-1]
+	if m := mapKeyPattern.FindStringSubmatch(last); m != nil {
+		name, key := m[1], m[2]
+		if _, ok := b.attrs[name]; ok || b.blocks[name] != nil {
+			return fmt.Errorf("%s conflicts with attribute %s", target, name)
+		}
+		if b.maps[name] == nil {
+			b.maps[name] = make(map[string]string)
+		}
+		if _, ok := b.maps[name][key]; ok {
+			return fmt.Errorf("duplicate target %s", target)
+		}
+		b.maps[name][key] = expr
+		return nil
+	}
+	if !identifierPattern.MatchString(last) {
+		return fmt.Errorf("invalid attribute name %q in %s", last, target)
+	}
+	if b.defined(last) || b.blocks[last] != nil {
+		return fmt.Errorf("duplicate target %s", target)
+	}
+	b.attrs[last] = expr
+	return nil
+}
+
+func (b *block) defined(name string) bool {
+	_, attr := b.attrs[name]
+	_, m := b.maps[name]
+	return attr || m
+}
+
+func (b *block) write(buf *bytes.Buffer, indent string) {
+	names := make([]string, 0, len(b.attrs)+len(b.maps))
+	for name := range b.attrs {
+		names = append(names, name)
+	}
+	for name := range b.maps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if expr, ok := b.attrs[name]; ok {
+			fmt.Fprintf(buf, "%s%s = %s\n", indent, name, expr)
+			continue
+		}
+		entries := b.maps[name]
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(buf, "%s%s = {\n", indent, name)
+		for _, key := range keys {
+			fmt.Fprintf(buf, "%s  %s = %s\n", indent, objectKey(key), entries[key])
+		}
+		fmt.Fprintf(buf, "%s}\n", indent)
+	}
+
+	children := make([]string, 0, len(b.blocks))
+	for name := range b.blocks {
+		children = append(children, name)
+	}
+	sort.Strings(children)
+	for _, name := range children {
+		fmt.Fprintf(buf, "\n%s%s {\n", indent, name)
+		b.blocks[name].write(buf, indent+"  ")
+		fmt.Fprintf(buf, "%s}\n", indent)
+	}
+}
+
+// reference 判断值是否为变量引用 var.<name>，上游资源的属性以变量注入 (见 dag 包)
+func reference(value string) (string, bool) {
+	if m := referencePattern.FindStringSubmatch(value); m != nil {
+		return m[1], true
+	}
+	return "", false
+}
+
+// expression 将值按类型渲染为 HCL 表达式，list 与 map 的值为 JSON
+func expression(value, valueType string) (string, error) {
+	if _, ok := reference(value); ok {
+		return value, nil
+	}
+	switch valueType {
+	case "", "string":
+		return quote(value), nil
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", fmt.Errorf("expected int: %w", err)
+		}
+		return value, nil
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("expected number: %w", err)
+		}
+		return value, nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("expected bool: %w", err)
+		}
+		return strconv.FormatBool(b), nil
+	case "list", "map", "json":
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.UseNumber()
+		var v interface{}
+		if err := decoder.Decode(&v); err != nil {
+			return "", fmt.Errorf("expected JSON %s: %w", valueType, err)
+		}
+		return literal(v), nil
+	}
+	return "", fmt.Errorf("unsupported value type: %s", valueType)
+}
+
+// literal 将 JSON 值渲染为 HCL 字面量
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return quote(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = literal(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = objectKey(key) + " = " + literal(v[key])
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	}
+	return quote(fmt.Sprint(v))
+}
+
+func objectKey(key string) string {
+	if identifierPattern.MatchString(key) {
+		return key
+	}
+	return quote(key)
+}
+
+// quote 渲染 HCL 字符串，转义模板序列 ${ 与 %{
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '$', '%':
+			b.WriteRune(r)
+			if strings.HasPrefix(s[i+1:], "{") {
+				b.WriteRune(r)
+			}
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package abstract
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpression(t *testing.T) {
+	tests := []struct {
+		value, valueType, want string
+	}{
+		{"web", "string", `"web"`},
+		{`say "hi" ${x} %{y} $z`, "", `"say \"hi\" $${x} %%{y} $z"`},
+		{"a\nb\x01", "string", `"a\nb\u0001"`},
+		{"42", "int", "42"},
+		{"1.5", "number", "1.5"},
+		{"TRUE", "bool", "true"},
+		{`["a", 1, null]`, "list", `["a", 1, null]`},
+		{`{"env":"dev","k-8":{"a b":true}}`, "map", `{ env = "dev", k-8 = { "a b" = true } }`},
+		{"var.subnet_id", "string", "var.subnet_id"},
+	}
+	for _, tt := range tests {
+		got, err := expression(tt.value, tt.valueType)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got)
+	}
+
+	for _, tt := range [][2]string{{"x", "int"}, {"x", "number"}, {"x", "bool"}, {"[", "list"}, {"x", "set"}} {
+		_, err := expression(tt[0], tt[1])
+		assert.Error(t, err, tt)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	b := newBlock()
+	require.NoError(t, b.set("instance_type", `"t3.micro"`))
+	require.NoError(t, b.set("tags[Name]", `"web"`))
+	require.NoError(t, b.set("tags[cost-center]", `"42"`))
+	require.NoError(t, b.set("root_block_device.volume_size", "20"))
+	require.NoError(t, b.set("ami", `"ami-1"`))
+
+	assert.Error(t, b.set("ami", `"ami-2"`))
+	assert.Error(t, b.set("tags", "{}"))
+	assert.Error(t, b.set("ami.size", "1"))
+	assert.Error(t, b.set("root_block_device", "1"))
+	assert.Error(t, b.set("bad name", "1"))
+
+	var buf bytes.Buffer
+	b.write(&buf, "")
+	assert.Equal(t, `ami = "ami-1"
+instance_type = "t3.micro"
+tags = {
+  Name = "web"
+  cost-center = "42"
+}
+
+root_block_device {
+  volume_size = 20
+}
+`, buf.String())
+}
@@ -0,0 +1,163 @@
+// Package blueprint publishes versioned multi-resource templates and instantiates
+// them as TerraformResources wired by ResourceDependencies.
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor/dag"
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// IDGenerator 生成 TerraformResource 的 ID，如雪花算法
+type IDGenerator func() int64
+
+// InstantiateRequest 实例化请求，Version 为 0 时使用最新版本
+type InstantiateRequest struct {
+	Blueprint  string
+	Version    int
+	Name       string // 实例名
+	Tenant     string
+	Credential string
+	Inputs     map[string]string // 参数输入，见 resolve
+}
+
+// Service 管理蓝图的发布、实例化与升级
+type Service struct {
+	blueprints *dao.BlueprintDAO
+	metadata   *dao.TerraformConfigMetadataDAO
+	resources  *dao.TerraformResourceDAO
+	params     *dao.TerraformResourceParamDAO
+	ids        IDGenerator
+}
+
+// NewService 创建蓝图服务，metadata 为 nil 时参数均视为可选的字符串
+func NewService(blueprints *dao.BlueprintDAO, metadata *dao.TerraformConfigMetadataDAO, resources *dao.TerraformResourceDAO, params *dao.TerraformResourceParamDAO, ids IDGenerator) *Service {
+	return &Service{
+		blueprints: blueprints,
+		metadata:   metadata,
+		resources:  resources,
+		params:     params,
+		ids:        ids,
+	}
+}
+
+// Publish 检查并发布蓝图，Version 为 0 时发布为下一个版本
+func (s *Service) Publish(blueprint *models.Blueprint) error {
+	if err := check(blueprint); err != nil {
+		return fmt.Errorf("invalid blueprint %s: %w", blueprint.Name, err)
+	}
+	if err := s.blueprints.Create(blueprint); err != nil {
+		return fmt.Errorf("failed to publish blueprint %s: %w", blueprint.Name, err)
+	}
+	return nil
+}
+
+// Instantiate 在一个事务中创建蓝图的全部资源、参数与依赖
+func (s *Service) Instantiate(req *InstantiateRequest) (*models.BlueprintInstance, error) {
+	blueprint, err := s.blueprint(req.Blueprint, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	params, err := s.resolve(blueprint, req.Inputs)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := json.Marshal(req.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &models.BlueprintInstance{
+		Name:          req.Name,
+		BlueprintName: blueprint.Name,
+		Version:       blueprint.Version,
+		Tenant:        req.Tenant,
+		Credential:    req.Credential,
+		Inputs:        string(inputs),
+	}
+	var resources []dao.InstanceResource
+	for i := range blueprint.Resources {
+		r := &blueprint.Resources[i]
+		resources = append(resources, s.newResource(instance, r, params[r.Name]))
+	}
+	if err := s.blueprints.CreateInstance(instance, resources, blueprint.Wires); err != nil {
+		return nil, fmt.Errorf("failed to instantiate blueprint %s: %w", blueprint.Name, err)
+	}
+	return instance, nil
+}
+
+// blueprint 加载指定版本的蓝图，version 为 0 时加载最新版本
+func (s *Service) blueprint(name string, version int) (*models.Blueprint, error) {
+	var blueprint *models.Blueprint
+	var err error
+	if version == 0 {
+		blueprint, err = s.blueprints.Latest(name)
+	} else {
+		blueprint, err = s.blueprints.Get(name, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blueprint %s version %d: %w", name, version, err)
+	}
+	return blueprint, nil
+}
+
+// newResource 由蓝图资源构建实例的新资源
+func (s *Service) newResource(instance *models.BlueprintInstance, r *models.BlueprintResource, params []models.TerraformResourceParam) dao.InstanceResource {
+	return dao.InstanceResource{
+		Name: r.Name,
+		Resource: &models.TerraformResource{
+			ID:           s.ids(),
+			Provider:     r.Provider,
+			ResourceType: r.ResourceType,
+			RegionId:     r.RegionId,
+			Tenant:       instance.Tenant,
+			Credential:   instance.Credential,
+			TfConfig:     r.TfConfig,
+			Status:       models.ResourceStatusPending,
+		},
+		Params: params,
+	}
+}
+
+// check 检查蓝图内的资源名唯一，参数与依赖引用的资源存在且依赖无环
+func check(blueprint *models.Blueprint) error {
+	if len(blueprint.Resources) == 0 {
+		return fmt.Errorf("no resources")
+	}
+	index := make(map[string]int64, len(blueprint.Resources))
+	for i, r := range blueprint.Resources {
+		if r.Name == "" {
+			return fmt.Errorf("resource %d has no name", i)
+		}
+		if _, ok := index[r.Name]; ok {
+			return fmt.Errorf("duplicate resource %s", r.Name)
+		}
+		index[r.Name] = int64(i + 1)
+	}
+	for _, p := range blueprint.Params {
+		if _, ok := index[p.Resource]; !ok {
+			return fmt.Errorf("param %s references unknown resource %s", p.ParamName, p.Resource)
+		}
+	}
+
+	var deps []models.ResourceDependency
+	for _, wire := range blueprint.Wires {
+		resourceID, ok := index[wire.Resource]
+		dependsOnID, ok2 := index[wire.DependsOn]
+		if !ok || !ok2 {
+			return fmt.Errorf("wire %s -> %s references an unknown resource", wire.Resource, wire.DependsOn)
+		}
+		deps = append(deps, models.ResourceDependency{ResourceID: resourceID, DependsOnID: dependsOnID})
+	}
+	ids := make([]int64, 0, len(index))
+	for _, id := range index {
+		ids = append(ids, id)
+	}
+	if _, err := dag.NewGraph(deps).Levels(ids); err != nil {
+		return fmt.Errorf("wires form a dependency cycle")
+	}
+	return nil
+}
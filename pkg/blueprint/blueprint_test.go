@@ -0,0 +1,161 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type fixture struct {
+	service   *Service
+	resources *dao.TerraformResourceDAO
+	params    *dao.TerraformResourceParamDAO
+	deps      *dao.ResourceDependencyDAO
+}
+
+func setupService(t *testing.T) *fixture {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	metadata := dao.NewTerraformConfigMetadataDAO(db)
+	for i, meta := range []models.TerraformConfigMetadata{
+		{Attribute: "cidr_block", ValueType: "string", IsRequired: true, ValidationRule: `{"pattern":"^\\d+\\.\\d+\\.\\d+\\.\\d+/\\d+$"}`},
+		{Attribute: "instance_type", ValueType: "string", DefaultValue: "t3.micro", ValidationRule: `{"enum":["t3.micro","t3.large"]}`},
+		{Attribute: "node_count", ValueType: "int", DefaultValue: "2", ValidationRule: `{"min":1,"max":10}`},
+	} {
+		meta.ID = int64(i + 1)
+		require.NoError(t, metadata.Create(&meta))
+	}
+
+	next := int64(100)
+	f := &fixture{
+		resources: dao.NewTerraformResourceDAO(db),
+		params:    dao.NewTerraformResourceParamDAO(db),
+		deps:      dao.NewResourceDependencyDAO(db),
+	}
+	f.service = NewService(dao.NewBlueprintDAO(db), metadata, f.resources, f.params, func() int64 {
+		next++
+		return next
+	})
+	return f
+}
+
+// stack network + bastion + k8s 节点组
+func stack() *models.Blueprint {
+	return &models.Blueprint{
+		Name: "stack",
+		Resources: []models.BlueprintResource{
+			{Name: "network", Provider: "aws", ResourceType: "vpc", TfConfig: "# network"},
+			{Name: "bastion", Provider: "aws", ResourceType: "ec2", TfConfig: "# bastion"},
+			{Name: "nodes", Provider: "aws", ResourceType: "eks_node_group", TfConfig: "# nodes"},
+		},
+		Params: []models.BlueprintParam{
+			{Resource: "network", ParamName: "cidr", Attribute: "cidr_block"},
+			{Resource: "bastion", ParamName: "instance_type"},
+			{Resource: "nodes", ParamName: "instance_type", DefaultValue: "t3.large"},
+			{Resource: "nodes", ParamName: "node_count"},
+			{Resource: "nodes", ParamName: "label"},
+		},
+		Wires: []models.BlueprintWire{
+			{Resource: "bastion", DependsOn: "network", Attribute: "subnet_id"},
+			{Resource: "nodes", DependsOn: "network", Attribute: "subnet_id", ParamName: "subnet_ids"},
+		},
+	}
+}
+
+func paramMap(t *testing.T, params *dao.TerraformResourceParamDAO, resourceID int64) map[string]string {
+	stored, err := params.ListByResourceID(resourceID)
+	require.NoError(t, err)
+	values := make(map[string]string)
+	for _, p := range stored {
+		values[p.ParamName] = p.ParamValue
+	}
+	return values
+}
+
+func resourceIDs(instance *models.BlueprintInstance) map[string]int64 {
+	ids := make(map[string]int64)
+	for _, r := range instance.Resources {
+		ids[r.Resource] = r.ResourceID
+	}
+	return ids
+}
+
+func TestService_Publish(t *testing.T) {
+	f := setupService(t)
+
+	require.NoError(t, f.service.Publish(stack()))
+	next := stack()
+	require.NoError(t, f.service.Publish(next))
+	assert.Equal(t, 2, next.Version)
+
+	tests := []struct {
+		name   string
+		modify func(*models.Blueprint)
+	}{
+		{"empty", func(b *models.Blueprint) { b.Resources = nil }},
+		{"duplicate", func(b *models.Blueprint) { b.Resources = append(b.Resources, b.Resources[0]) }},
+		{"unknown param resource", func(b *models.Blueprint) { b.Params[0].Resource = "db" }},
+		{"unknown wire resource", func(b *models.Blueprint) { b.Wires[0].DependsOn = "db" }},
+		{"cycle", func(b *models.Blueprint) {
+			b.Wires = append(b.Wires, models.BlueprintWire{Resource: "network", DependsOn: "bastion"})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blueprint := stack()
+			tt.modify(blueprint)
+			assert.Error(t, f.service.Publish(blueprint))
+		})
+	}
+}
+
+func TestService_Instantiate(t *testing.T) {
+	f := setupService(t)
+	require.NoError(t, f.service.Publish(stack()))
+
+	_, err := f.service.Instantiate(&InstantiateRequest{Blueprint: "stack", Name: "dev"})
+	assert.ErrorContains(t, err, "param network.cidr is required")
+	_, err = f.service.Instantiate(&InstantiateRequest{Blueprint: "stack", Name: "dev", Inputs: map[string]string{"cidr": "10.0.0.0/16", "nodes.size": "3"}})
+	assert.ErrorContains(t, err, "unknown blueprint inputs: nodes.size")
+
+	instance, err := f.service.Instantiate(&InstantiateRequest{
+		Blueprint:  "stack",
+		Name:       "dev",
+		Tenant:     "team-a",
+		Credential: "aws-dev",
+		Inputs:     map[string]string{"cidr": "10.0.0.0/16", "bastion.instance_type": "t3.large", "node_count": "3"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, instance.Version)
+
+	ids := resourceIDs(instance)
+	require.Len(t, ids, 3)
+	network, err := f.resources.Get(ids["network"])
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", network.Tenant)
+	assert.Equal(t, "aws-dev", network.Credential)
+	assert.Equal(t, "# network", network.TfConfig)
+	assert.Equal(t, models.ResourceStatusPending, network.Status)
+
+	assert.Equal(t, map[string]string{"cidr": "10.0.0.0/16"}, paramMap(t, f.params, ids["network"]))
+	assert.Equal(t, map[string]string{"instance_type": "t3.large"}, paramMap(t, f.params, ids["bastion"]))
+	// 蓝图默认值优先于元数据默认值，未输入且无默认值的参数不写入
+	assert.Equal(t, map[string]string{"instance_type": "t3.large", "node_count": "3"}, paramMap(t, f.params, ids["nodes"]))
+
+	deps, err := f.deps.ListByResource(ids["nodes"])
+	require.NoError(t, err)
+	require.Len(t, deps, 1)
+	assert.Equal(t, ids["network"], deps[0].DependsOnID)
+	assert.Equal(t, "subnet_ids", deps[0].ParamName)
+
+	// 同名实例创建失败时不留下资源
+	_, err = f.service.Instantiate(&InstantiateRequest{Blueprint: "stack", Name: "dev", Inputs: map[string]string{"cidr": "10.0.0.0/16"}})
+	assert.Error(t, err)
+	_, err = f.resources.Get(ids["nodes"] + 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// Rule TerraformConfigMetadata.ValidationRule 的 JSON 格式，字段均可选
+type Rule struct {
+	Pattern string   `json:"pattern,omitempty"` // 值须匹配的正则
+	Enum    []string `json:"enum,omitempty"`    // 允许的取值
+	Min     *float64 `json:"min,omitempty"`     // 数值下限
+	Max     *float64 `json:"max,omitempty"`     // 数值上限
+}
+
+// valueType 返回元数据的值类型，没有元数据时为 string
+func valueType(meta *models.TerraformConfigMetadata) string {
+	if meta == nil || strings.TrimSpace(meta.ValueType) == "" {
+		return "string"
+	}
+	return strings.TrimSpace(meta.ValueType)
+}
+
+// validate 按元数据的类型与验证规则检查参数值，secret:// 引用在执行前才解析，不做检查
+func validate(value string, meta *models.TerraformConfigMetadata) error {
+	if meta == nil || strings.HasPrefix(value, "secret://") {
+		return nil
+	}
+
+	var err error
+	switch valueType(meta) {
+	case "int":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "number", "float":
+		_, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	case "json", "list", "map":
+		if !json.Valid([]byte(value)) {
+			err = errors.New("invalid JSON")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("expected %s: %w", valueType(meta), err)
+	}
+
+	if meta.ValidationRule == "" {
+		return nil
+	}
+	var rule Rule
+	if err := json.Unmarshal([]byte(meta.ValidationRule), &rule); err != nil {
+		return fmt.Errorf("invalid validation rule of %s: %w", meta.Attribute, err)
+	}
+	if rule.Pattern != "" {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid validation pattern of %s: %w", meta.Attribute, err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("does not match %s", rule.Pattern)
+		}
+	}
+	if len(rule.Enum) > 0 && !slices.Contains(rule.Enum, value) {
+		return fmt.Errorf("must be one of %s", strings.Join(rule.Enum, ", "))
+	}
+	if rule.Min != nil || rule.Max != nil {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected number: %w", err)
+		}
+		if rule.Min != nil && number < *rule.Min {
+			return fmt.Errorf("must be at least %v", *rule.Min)
+		}
+		if rule.Max != nil && number > *rule.Max {
+			return fmt.Errorf("must be at most %v", *rule.Max)
+		}
+	}
+	return nil
+}
+
+// resolve 按蓝图参数定义解析各资源的参数。输入以 <资源名>.<参数名> 指定单个资源的参数，
+// 或以 <参数名> 指定所有同名参数；未输入时依次使用蓝图与元数据的默认值，值为空的参数不写入
+func (s *Service) resolve(blueprint *models.Blueprint, inputs map[string]string) (map[string][]models.TerraformResourceParam, error) {
+	var unknown []string
+	for key := range inputs {
+		if !declares(blueprint, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown blueprint inputs: %s", strings.Join(unknown, ", "))
+	}
+
+	params := make(map[string][]models.TerraformResourceParam)
+	for _, p := range blueprint.Params {
+		meta, err := s.meta(p)
+		if err != nil {
+			return nil, err
+		}
+
+		value, ok := inputs[p.Resource+"."+p.ParamName]
+		if !ok {
+			value, ok = inputs[p.ParamName]
+		}
+		if !ok {
+			value = p.DefaultValue
+			if value == "" && meta != nil {
+				value = meta.DefaultValue
+			}
+		}
+		if value == "" {
+			if meta != nil && meta.IsRequired {
+				return nil, fmt.Errorf("param %s.%s is required", p.Resource, p.ParamName)
+			}
+			continue
+		}
+		if err := validate(value, meta); err != nil {
+			return nil, fmt.Errorf("invalid param %s.%s: %w", p.Resource, p.ParamName, err)
+		}
+
+		params[p.Resource] = append(params[p.Resource], models.TerraformResourceParam{
+			ParamName:  p.ParamName,
+			ParamValue: value,
+			ValueType:  valueType(meta),
+		})
+	}
+	return params, nil
+}
+
+// declares 判断蓝图是否声明了输入 key，key 为 <resource>.<param> 或 <param>
+func declares(blueprint *models.Blueprint, key string) bool {
+	for _, p := range blueprint.Params {
+		if key == p.Resource+"."+p.ParamName || key == p.ParamName {
+			return true
+		}
+	}
+	return false
+}
+
+// meta 返回参数的元数据，没有元数据的参数为可选的字符串
+func (s *Service) meta(p models.BlueprintParam) (*models.TerraformConfigMetadata, error) {
+	if s.metadata == nil {
+		return nil, nil
+	}
+	attribute := p.Attribute
+	if attribute == "" {
+		attribute = p.ParamName
+	}
+	meta, err := s.metadata.GetByAttribute(attribute)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metadata of %s: %w", attribute, err)
+	}
+	return meta, nil
+}
@@ -0,0 +1,34 @@
+package blueprint
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		value string
+		meta  models.TerraformConfigMetadata
+		valid bool
+	}{
+		{"3", models.TerraformConfigMetadata{ValueType: "int"}, true},
+		{"three", models.TerraformConfigMetadata{ValueType: "int"}, false},
+		{"true", models.TerraformConfigMetadata{ValueType: "bool   "}, true},
+		{`{"a":1}`, models.TerraformConfigMetadata{ValueType: "json"}, true},
+		{`{"a":`, models.TerraformConfigMetadata{ValueType: "map"}, false},
+		{"11", models.TerraformConfigMetadata{ValueType: "int", ValidationRule: `{"min":1,"max":10}`}, false},
+		{"0.5", models.TerraformConfigMetadata{ValueType: "number", ValidationRule: `{"min":1}`}, false},
+		{"t3.nano", models.TerraformConfigMetadata{ValidationRule: `{"enum":["t3.micro"]}`}, false},
+		{"prod-1", models.TerraformConfigMetadata{ValidationRule: `{"pattern":"^[a-z]+-\\d$"}`}, true},
+		{"Prod", models.TerraformConfigMetadata{ValidationRule: `{"pattern":"^[a-z]+$"}`}, false},
+		{"secret://vault/db#password", models.TerraformConfigMetadata{ValueType: "int"}, true},
+		{"x", models.TerraformConfigMetadata{ValidationRule: `not json`}, false},
+	}
+	for _, tt := range tests {
+		err := validate(tt.value, &tt.meta)
+		assert.Equal(t, tt.valid, err == nil, "%s %+v: %v", tt.value, tt.meta, err)
+	}
+	assert.NoError(t, validate("anything", nil))
+}
@@ -0,0 +1,213 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// ChangeAction 升级对实例资源的变更
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create" // 新版本新增的资源
+	ChangeUpdate ChangeAction = "update" // 配置或参数变更的资源
+	ChangeDelete ChangeAction = "delete" // 新版本移除的资源，升级后从实例分离，需另行销毁
+	ChangeNone   ChangeAction = "no-op"
+)
+
+// ParamChange 参数变更，From 或 To 为空表示新增或移除
+type ParamChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ResourceChange 单个资源的变更，新增的资源没有 ResourceID
+type ResourceChange struct {
+	Resource      string        `json:"resource"`
+	ResourceID    int64         `json:"resource_id,omitempty"`
+	Action        ChangeAction  `json:"action"`
+	ConfigChanged bool          `json:"config_changed"`
+	Params        []ParamChange `json:"params,omitempty"`
+}
+
+// UpgradePlan 实例升级计划，由 PlanUpgrade 生成，Upgrade 执行
+type UpgradePlan struct {
+	Instance    string            `json:"instance"`
+	FromVersion int               `json:"from_version"`
+	ToVersion   int               `json:"to_version"`
+	Inputs      map[string]string `json:"inputs"`
+	Changes     []ResourceChange  `json:"changes"`
+
+	instance  *models.BlueprintInstance
+	blueprint *models.Blueprint
+	params    map[string][]models.TerraformResourceParam
+}
+
+// HasChanges 判断升级是否变更资源
+func (p *UpgradePlan) HasChanges() bool {
+	for _, c := range p.Changes {
+		if c.Action != ChangeNone {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanUpgrade 计划将实例升级到蓝图的指定版本，version 为 0 时升级到最新版本。
+// 实例化时的输入与 inputs 合并后按新版本重新解析参数，新版本不再声明的已有输入被丢弃，
+// 只有 inputs 中的未知输入报错。不修改任何数据
+func (s *Service) PlanUpgrade(name string, version int, inputs map[string]string) (*UpgradePlan, error) {
+	instance, err := s.blueprints.GetInstance(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load instance %s: %w", name, err)
+	}
+	blueprint, err := s.blueprint(instance.BlueprintName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]string)
+	if instance.Inputs != "" {
+		if err := json.Unmarshal([]byte(instance.Inputs), &stored); err != nil {
+			return nil, fmt.Errorf("invalid inputs of instance %s: %w", name, err)
+		}
+	}
+	merged := make(map[string]string, len(stored)+len(inputs))
+	for k, v := range stored {
+		if declares(blueprint, k) {
+			merged[k] = v
+		}
+	}
+	for k, v := range inputs {
+		merged[k] = v
+	}
+	params, err := s.resolve(blueprint, merged)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &UpgradePlan{
+		Instance:    name,
+		FromVersion: instance.Version,
+		ToVersion:   blueprint.Version,
+		Inputs:      merged,
+		instance:    instance,
+		blueprint:   blueprint,
+		params:      params,
+	}
+	mapped := make(map[string]int64, len(instance.Resources))
+	for _, r := range instance.Resources {
+		mapped[r.Resource] = r.ResourceID
+	}
+
+	for i := range blueprint.Resources {
+		r := &blueprint.Resources[i]
+		id, ok := mapped[r.Name]
+		if !ok {
+			plan.Changes = append(plan.Changes, ResourceChange{Resource: r.Name, Action: ChangeCreate, Params: diffParams(nil, params[r.Name])})
+			continue
+		}
+		delete(mapped, r.Name)
+
+		current, err := s.resources.Get(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load resource %s: %w", r.Name, err)
+		}
+		currentParams, err := s.params.ListByResourceID(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load params of resource %s: %w", r.Name, err)
+		}
+		change := ResourceChange{
+			Resource:   r.Name,
+			ResourceID: id,
+			Action:     ChangeNone,
+			ConfigChanged: current.TfConfig != r.TfConfig || current.Provider != r.Provider ||
+				current.ResourceType != r.ResourceType || current.RegionId != r.RegionId,
+			Params: diffParams(currentParams, params[r.Name]),
+		}
+		if change.ConfigChanged || len(change.Params) > 0 {
+			change.Action = ChangeUpdate
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	var removed []string
+	for name := range mapped {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		plan.Changes = append(plan.Changes, ResourceChange{Resource: name, ResourceID: mapped[name], Action: ChangeDelete})
+	}
+	return plan, nil
+}
+
+// Upgrade 执行升级计划。实例在计划后被升级过时返回 dao.ErrStaleInstance，需重新计划。
+// 升级只更新资源记录，变更的资源需再次 apply，移除的资源需另行 destroy
+func (s *Service) Upgrade(plan *UpgradePlan) (*models.BlueprintInstance, error) {
+	if plan.instance == nil || plan.blueprint == nil {
+		return nil, fmt.Errorf("upgrade plan of %s was not created by PlanUpgrade", plan.Instance)
+	}
+	inputs, err := json.Marshal(plan.Inputs)
+	if err != nil {
+		return nil, err
+	}
+	instance := *plan.instance
+	instance.Version = plan.ToVersion
+	instance.Inputs = string(inputs)
+
+	resources := make(map[string]*models.BlueprintResource, len(plan.blueprint.Resources))
+	for i := range plan.blueprint.Resources {
+		resources[plan.blueprint.Resources[i].Name] = &plan.blueprint.Resources[i]
+	}
+	var create, update []dao.InstanceResource
+	var remove []string
+	for _, c := range plan.Changes {
+		switch c.Action {
+		case ChangeCreate:
+			create = append(create, s.newResource(&instance, resources[c.Resource], plan.params[c.Resource]))
+		case ChangeUpdate:
+			r := s.newResource(&instance, resources[c.Resource], plan.params[c.Resource])
+			r.Resource.ID = c.ResourceID
+			update = append(update, r)
+		case ChangeDelete:
+			remove = append(remove, c.Resource)
+		}
+	}
+
+	if err := s.blueprints.UpgradeInstance(&instance, plan.FromVersion, create, update, remove, plan.blueprint.Wires); err != nil {
+		return nil, fmt.Errorf("failed to upgrade instance %s: %w", plan.Instance, err)
+	}
+	return s.blueprints.GetInstance(plan.Instance)
+}
+
+// diffParams 比较资源当前与升级后的参数
+func diffParams(current, next []models.TerraformResourceParam) []ParamChange {
+	from := make(map[string]string, len(current))
+	for _, p := range current {
+		from[p.ParamName] = p.ParamValue
+	}
+	to := make(map[string]string, len(next))
+	for _, p := range next {
+		to[p.ParamName] = p.ParamValue
+	}
+
+	var changes []ParamChange
+	for name, value := range to {
+		if old, ok := from[name]; !ok || old != value {
+			changes = append(changes, ParamChange{Name: name, From: old, To: value})
+		}
+	}
+	for name, value := range from {
+		if _, ok := to[name]; !ok {
+			changes = append(changes, ParamChange{Name: name, From: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
@@ -0,0 +1,85 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Upgrade(t *testing.T) {
+	f := setupService(t)
+	require.NoError(t, f.service.Publish(stack()))
+	instance, err := f.service.Instantiate(&InstantiateRequest{Blueprint: "stack", Name: "dev", Inputs: map[string]string{"cidr": "10.0.0.0/16", "bastion.instance_type": "t3.micro"}})
+	require.NoError(t, err)
+	ids := resourceIDs(instance)
+
+	// v2：移除 bastion，新增 registry，节点组配置与默认节点数变更
+	v2 := stack()
+	v2.Resources = []models.BlueprintResource{
+		v2.Resources[0],
+		{Name: "nodes", Provider: "aws", ResourceType: "eks_node_group", TfConfig: "# nodes v2"},
+		{Name: "registry", Provider: "aws", ResourceType: "ecr", TfConfig: "# registry"},
+	}
+	v2.Params = []models.BlueprintParam{
+		{Resource: "network", ParamName: "cidr", Attribute: "cidr_block"},
+		{Resource: "nodes", ParamName: "instance_type", DefaultValue: "t3.large"},
+		{Resource: "nodes", ParamName: "node_count", DefaultValue: "4"},
+	}
+	v2.Wires = []models.BlueprintWire{
+		{Resource: "nodes", DependsOn: "network", Attribute: "subnet_id", ParamName: "subnet_ids"},
+		{Resource: "registry", DependsOn: "network"},
+	}
+	require.NoError(t, f.service.Publish(v2))
+
+	_, err = f.service.PlanUpgrade("dev", 0, map[string]string{"bastion.instance_type": "t3.large"})
+	assert.ErrorContains(t, err, "unknown blueprint inputs")
+
+	// 新版本不再声明的已有输入被丢弃
+	plan, err := f.service.PlanUpgrade("dev", 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"cidr": "10.0.0.0/16"}, plan.Inputs)
+	assert.True(t, plan.HasChanges())
+	assert.Equal(t, 1, plan.FromVersion)
+	assert.Equal(t, 2, plan.ToVersion)
+	assert.Equal(t, []ResourceChange{
+		{Resource: "network", ResourceID: ids["network"], Action: ChangeNone, Params: nil},
+		{Resource: "nodes", ResourceID: ids["nodes"], Action: ChangeUpdate, ConfigChanged: true, Params: []ParamChange{{Name: "node_count", From: "2", To: "4"}}},
+		{Resource: "registry", Action: ChangeCreate},
+		{Resource: "bastion", ResourceID: ids["bastion"], Action: ChangeDelete},
+	}, plan.Changes)
+
+	// 计划不修改数据
+	nodes, _ := f.resources.Get(ids["nodes"])
+	assert.Equal(t, "# nodes", nodes.TfConfig)
+
+	upgraded, err := f.service.Upgrade(plan)
+	require.NoError(t, err)
+	assert.Equal(t, 2, upgraded.Version)
+	upgradedIDs := resourceIDs(upgraded)
+	assert.Len(t, upgradedIDs, 3)
+	assert.Equal(t, ids["nodes"], upgradedIDs["nodes"])
+
+	nodes, _ = f.resources.Get(ids["nodes"])
+	assert.Equal(t, "# nodes v2", nodes.TfConfig)
+	assert.Equal(t, map[string]string{"instance_type": "t3.large", "node_count": "4"}, paramMap(t, f.params, ids["nodes"]))
+
+	// 移除的资源保留以便销毁，但不再属于实例的依赖
+	_, err = f.resources.Get(ids["bastion"])
+	assert.NoError(t, err)
+	deps, _ := f.deps.ListByResource(ids["bastion"])
+	assert.Empty(t, deps)
+	deps, _ = f.deps.ListByResource(upgradedIDs["registry"])
+	require.Len(t, deps, 1)
+	assert.Equal(t, ids["network"], deps[0].DependsOnID)
+
+	// 过期的计划不能执行
+	_, err = f.service.Upgrade(plan)
+	assert.ErrorIs(t, err, dao.ErrStaleInstance)
+
+	plan, err = f.service.PlanUpgrade("dev", 2, map[string]string{"cidr": "10.1.0.0/16"})
+	require.NoError(t, err)
+	assert.Equal(t, []ParamChange{{Name: "cidr", From: "10.0.0.0/16", To: "10.1.0.0/16"}}, plan.Changes[0].Params)
+}
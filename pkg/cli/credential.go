@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/encrypt"
+	"github.com/cylonchau/prism/pkg/logger"
+	"github.com/cylonchau/prism/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+// credentialCmd represents the credential command
+var credentialCmd = &cobra.Command{
+	Use:   "credential",
+	Short: "Manage encrypted cloud credentials",
+	Long:  `Manage the master keys protecting cloud provider credentials stored in the database.`,
+}
+
+// credentialGenKeyCmd generates a new master key
+var credentialGenKeyCmd = &cobra.Command{
+	Use:   "genkey",
+	Short: "Generate a new master key",
+	Long: `Generate a random base64-encoded AES-256 master key.
+
+To rotate keys, put the new key on the first line of the master key file,
+keep the old keys below it, then run "prism credential rotate".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := encrypt.GenerateKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+		return nil
+	},
+}
+
+// credentialRotateCmd re-encrypts data keys with the current master key
+var credentialRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Re-encrypt credentials with the current master key",
+	Long:  `Rotate re-wraps every credential data key that is not protected by the first (primary) master key.`,
+	RunE:  runCredentialRotate,
+}
+
+func init() {
+	credentialCmd.AddCommand(credentialGenKeyCmd)
+	credentialCmd.AddCommand(credentialRotateCmd)
+	rootCmd.AddCommand(credentialCmd)
+}
+
+func runCredentialRotate(cmd *cobra.Command, args []string) error {
+	keyring, err := encrypt.LoadKeyring(masterKeyFile)
+	if err != nil {
+		logger.Error("Failed to load master key", logger.Err(err))
+		return err
+	}
+
+	dbStore := store.GetInstance()
+	if err := dbStore.Initialize(getStoreConfig()); err != nil {
+		logger.Error("Failed to initialize database", logger.Err(err))
+		return err
+	}
+	defer dbStore.Close()
+
+	rotated, err := dao.NewCloudCredentialDAO(dbStore.GetDB(), keyring).Rotate()
+	if err != nil {
+		logger.Error("Credential rotation failed", logger.Err(err))
+		return err
+	}
+
+	logger.Info("Credential rotation completed",
+		logger.String("primary_key", keyring.PrimaryID()),
+		logger.Int("rotated", rotated))
+	return nil
+}
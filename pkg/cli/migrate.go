@@ -36,16 +36,41 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 
 	// Run migrations
 	allModels := []interface{}{
+		&models.AbstractAttribute{},
+		&models.AbstractType{},
+		&models.AttributeMappingRule{},
+		&models.AttributeTranslation{},
+		&models.Blueprint{},
+		&models.BlueprintInstance{},
+		&models.BlueprintInstanceResource{},
+		&models.BlueprintParam{},
+		&models.BlueprintResource{},
+		&models.BlueprintWire{},
+		&models.CloudCredential{},
+		&models.DataKey{},
+		&models.ErrorPattern{},
 		&models.ExecutionLock{},
 		&models.ExecutionTask{},
+		&models.Pipeline{},
 		&models.Provider{},
+		&models.ProviderTranslation{},
 		&models.Plugin{},
+		&models.ResourceDependency{},
+		&models.ResourceDuration{},
+		&models.ResourceTransition{},
+		&models.RetryPolicy{},
+		&models.TaskAttempt{},
+		&models.TaskDiagnostic{},
+		&models.TaskEvent{},
+		&models.TaskLog{},
+		&models.TaskResourceOutcome{},
 		&models.TerraformConfig{},
 		&models.TerraformConfigMetadata{},
 		&models.TerraformConfigParam{},
 		&models.TerraformResource{},
 		&models.TerraformResourceAttribute{},
 		&models.TerraformResourceOutput{},
+		&models.TerraformResourceParam{},
 	}
 
 	logger.Info("Starting model migration", logger.Int("count", len(allModels)))
@@ -70,14 +95,62 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 
 func getModelName(model interface{}) string {
 	switch model.(type) {
+	case *models.AbstractAttribute:
+		return "AbstractAttribute"
+	case *models.AbstractType:
+		return "AbstractType"
+	case *models.AttributeMappingRule:
+		return "AttributeMappingRule"
+	case *models.AttributeTranslation:
+		return "AttributeTranslation"
+	case *models.Blueprint:
+		return "Blueprint"
+	case *models.BlueprintInstance:
+		return "BlueprintInstance"
+	case *models.BlueprintInstanceResource:
+		return "BlueprintInstanceResource"
+	case *models.BlueprintParam:
+		return "BlueprintParam"
+	case *models.BlueprintResource:
+		return "BlueprintResource"
+	case *models.BlueprintWire:
+		return "BlueprintWire"
+	case *models.CloudCredential:
+		return "CloudCredential"
+	case *models.DataKey:
+		return "DataKey"
+	case *models.ErrorPattern:
+		return "ErrorPattern"
 	case *models.ExecutionLock:
 		return "ExecutionLock"
 	case *models.ExecutionTask:
 		return "ExecutionTask"
+	case *models.Pipeline:
+		return "Pipeline"
 	case *models.Provider:
 		return "Provider"
+	case *models.ProviderTranslation:
+		return "ProviderTranslation"
 	case *models.Plugin:
 		return "Plugin"
+	case *models.ResourceDependency:
+		return "ResourceDependency"
+	case *models.ResourceDuration:
+		return "ResourceDuration"
+	case *models.ResourceTransition:
+		return "ResourceTransition"
+	case *models.RetryPolicy:
+		return "RetryPolicy"
+	case *models.TaskAttempt:
+		return "TaskAttempt"
+	case *models.TaskDiagnostic:
+		return "TaskDiagnostic"
+	case *models.TaskEvent:
+		return "TaskEvent"
+	case *models.TaskLog:
+		return "TaskLog"
+	case *models.TaskResourceOutcome:
+		return "TaskResourceOutcome"
 	case *models.TerraformConfig:
 		return "TerraformConfig"
 	case *models.TerraformConfigMetadata:
@@ -90,6 +163,8 @@ func getModelName(model interface{}) string {
 		return "TerraformResourceAttribute"
 	case *models.TerraformResourceOutput:
 		return "TerraformResourceOutput"
+	case *models.TerraformResourceParam:
+		return "TerraformResourceParam"
 	default:
 		return "Unknown"
 	}
@@ -16,6 +16,8 @@ var (
 	dbUser  string
 	dbPass  string
 	dbFile  string
+
+	masterKeyFile string
 )
 
 // rootCmd represents the base command
@@ -43,6 +45,9 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&dbUser, "db-user", "root", "database user")
 	rootCmd.PersistentFlags().StringVar(&dbPass, "db-pass", "", "database password")
 	rootCmd.PersistentFlags().StringVar(&dbFile, "db-file", "prism", "sqlite database file path (without .db extension)")
+
+	// Encryption flags
+	rootCmd.PersistentFlags().StringVar(&masterKeyFile, "master-key-file", "", "master key file (default reads PRISM_MASTER_KEY)")
 }
 
 // GetDBConfig returns database config from flags
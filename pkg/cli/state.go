@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/encrypt"
+	"github.com/cylonchau/prism/pkg/logger"
+	"github.com/cylonchau/prism/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+var rekeyTenant string
+
+// stateCmd represents the state command
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Manage encrypted Terraform state",
+	Long:  `Manage the encryption of Terraform state and saved plans stored in the database.`,
+}
+
+// stateRekeyCmd re-encrypts state with new data keys
+var stateRekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Re-encrypt Terraform state with new data keys",
+	Long: `Rekey re-wraps every data key with the current (primary) master key, then
+generates a new data key per tenant and re-encrypts the state and saved plan of
+its resources. Plaintext state written before encryption was enabled is
+encrypted as well.`,
+	RunE: runStateRekey,
+}
+
+func init() {
+	stateRekeyCmd.Flags().StringVar(&rekeyTenant, "tenant", "", "only rekey this tenant (default all tenants)")
+
+	stateCmd.AddCommand(stateRekeyCmd)
+	rootCmd.AddCommand(stateCmd)
+}
+
+func runStateRekey(cmd *cobra.Command, args []string) error {
+	keyring, err := encrypt.LoadKeyring(masterKeyFile)
+	if err != nil {
+		logger.Error("Failed to load master key", logger.Err(err))
+		return err
+	}
+
+	dbStore := store.GetInstance()
+	if err := dbStore.Initialize(getStoreConfig()); err != nil {
+		logger.Error("Failed to initialize database", logger.Err(err))
+		return err
+	}
+	defer dbStore.Close()
+
+	db := dbStore.GetDB()
+	cipher := dao.NewStateCipher(db, keyring)
+	resourceDAO := dao.NewTerraformResourceDAO(db)
+	resourceDAO.SetStateCipher(cipher)
+
+	rotated, err := cipher.Rotate()
+	if err != nil {
+		logger.Error("Data key rotation failed", logger.Err(err))
+		return err
+	}
+
+	tenants := []string{rekeyTenant}
+	if !cmd.Flags().Changed("tenant") {
+		if tenants, err = resourceDAO.ListTenants(); err != nil {
+			logger.Error("Failed to list tenants", logger.Err(err))
+			return err
+		}
+	}
+
+	for _, tenant := range tenants {
+		rekeyed, err := resourceDAO.Rekey(tenant)
+		if err != nil {
+			logger.Error("State rekey failed", logger.String("tenant", tenant), logger.Err(err))
+			return err
+		}
+		logger.Info("Tenant state rekeyed",
+			logger.String("tenant", tenant),
+			logger.Int("resources", rekeyed))
+	}
+
+	logger.Info("State rekey completed",
+		logger.String("primary_key", keyring.PrimaryID()),
+		logger.Int("data_keys_rewrapped", rotated),
+		logger.Int("tenants", len(tenants)))
+	return nil
+}
@@ -0,0 +1,88 @@
+package dao
+
+import (
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// AbstractTypeDAO provides abstract type and provider translation data access operations.
+type AbstractTypeDAO struct {
+	db *gorm.DB
+}
+
+// NewAbstractTypeDAO creates a new abstract type DAO.
+func NewAbstractTypeDAO(db *gorm.DB) *AbstractTypeDAO {
+	db.AutoMigrate(
+		&models.AbstractType{},
+		&models.AbstractAttribute{},
+		&models.ProviderTranslation{},
+		&models.AttributeTranslation{},
+	)
+	return &AbstractTypeDAO{db: db}
+}
+
+// Create creates an abstract type with its attributes.
+func (d *AbstractTypeDAO) Create(abstractType *models.AbstractType) error {
+	return d.db.Create(abstractType).Error
+}
+
+// Get retrieves an abstract type with its attributes by name.
+func (d *AbstractTypeDAO) Get(name string) (*models.AbstractType, error) {
+	var abstractType models.AbstractType
+	result := d.db.Preload("Attributes", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("name = ?", name).First(&abstractType)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &abstractType, nil
+}
+
+// List lists all abstract types without their attributes.
+func (d *AbstractTypeDAO) List() ([]models.AbstractType, error) {
+	var types []models.AbstractType
+	result := d.db.Order("name ASC").Find(&types)
+	return types, result.Error
+}
+
+// SetTranslation creates or replaces the translation of an abstract type for a provider.
+func (d *AbstractTypeDAO) SetTranslation(translation *models.ProviderTranslation) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.ProviderTranslation
+		err := tx.Where("type_id = ? AND provider = ?", translation.TypeID, translation.Provider).Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			if err := tx.Where("translation_id = ?", existing.ID).Delete(&models.AttributeTranslation{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&existing).Error; err != nil {
+				return err
+			}
+		}
+		translation.ID = 0
+		for i := range translation.Attributes {
+			translation.Attributes[i].ID = 0
+		}
+		return tx.Create(translation).Error
+	})
+}
+
+// GetTranslation retrieves the translation of an abstract type for a provider.
+func (d *AbstractTypeDAO) GetTranslation(typeID int64, provider string) (*models.ProviderTranslation, error) {
+	var translation models.ProviderTranslation
+	result := d.db.Preload("Attributes", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("type_id = ? AND provider = ?", typeID, provider).First(&translation)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &translation, nil
+}
+
+// ListProviders lists the providers an abstract type can be translated to.
+func (d *AbstractTypeDAO) ListProviders(typeID int64) ([]string, error) {
+	var providers []string
+	result := d.db.Model(&models.ProviderTranslation{}).Where("type_id = ?", typeID).
+		Order("provider ASC").Pluck("provider", &providers)
+	return providers, result.Error
+}
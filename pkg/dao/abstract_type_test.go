@@ -0,0 +1,55 @@
+package dao
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbstractTypeDAO(t *testing.T) {
+	db := setupSQLiteDB(t)
+	dao := NewAbstractTypeDAO(db)
+
+	instance := &models.AbstractType{
+		Name:       "compute.instance",
+		Attributes: []models.AbstractAttribute{{Name: "size", IsRequired: true}, {Name: "image"}},
+	}
+	require.NoError(t, dao.Create(instance))
+	require.NoError(t, dao.Create(&models.AbstractType{Name: "network.vpc"}))
+
+	got, err := dao.Get("compute.instance")
+	require.NoError(t, err)
+	assert.Len(t, got.Attributes, 2)
+	assert.Equal(t, "size", got.Attributes[0].Name)
+	types, _ := dao.List()
+	assert.Len(t, types, 2)
+
+	require.NoError(t, dao.SetTranslation(&models.ProviderTranslation{
+		TypeID:       instance.ID,
+		Provider:     "aws",
+		ResourceType: "aws_instance",
+		Attributes:   []models.AttributeTranslation{{Attribute: "size", Target: "instance_type"}, {Attribute: "image", Target: "ami"}},
+	}))
+	// 再次设置时替换原有映射
+	require.NoError(t, dao.SetTranslation(&models.ProviderTranslation{
+		TypeID:       instance.ID,
+		Provider:     "aws",
+		ResourceType: "aws_instance",
+		Attributes:   []models.AttributeTranslation{{Attribute: "size", Target: "instance_type"}},
+	}))
+	require.NoError(t, dao.SetTranslation(&models.ProviderTranslation{TypeID: instance.ID, Provider: "tencentcloud", ResourceType: "tencentcloud_instance"}))
+
+	translation, err := dao.GetTranslation(instance.ID, "aws")
+	require.NoError(t, err)
+	assert.Equal(t, "aws_instance", translation.ResourceType)
+	assert.Len(t, translation.Attributes, 1)
+	var count int64
+	db.Model(&models.AttributeTranslation{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	providers, err := dao.ListProviders(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws", "tencentcloud"}, providers)
+}
@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"fmt"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/cylonchau/prism/pkg/transform"
+	"gorm.io/gorm"
+)
+
+// AttributeMappingRuleDAO provides attribute mapping rule access operations.
+type AttributeMappingRuleDAO struct {
+	db *gorm.DB
+}
+
+// NewAttributeMappingRuleDAO creates a new attribute mapping rule DAO.
+func NewAttributeMappingRuleDAO(db *gorm.DB) *AttributeMappingRuleDAO {
+	db.AutoMigrate(&models.AttributeMappingRule{})
+	return &AttributeMappingRuleDAO{db: db}
+}
+
+// Create creates a rule after validating the transform expression.
+func (d *AttributeMappingRuleDAO) Create(rule *models.AttributeMappingRule) error {
+	if err := checkMappingRule(rule); err != nil {
+		return err
+	}
+	return d.db.Create(rule).Error
+}
+
+// Update updates a rule after validating the transform expression.
+func (d *AttributeMappingRuleDAO) Update(rule *models.AttributeMappingRule) error {
+	if err := checkMappingRule(rule); err != nil {
+		return err
+	}
+	return d.db.Save(rule).Error
+}
+
+// Get retrieves a rule by ID.
+func (d *AttributeMappingRuleDAO) Get(id int64) (*models.AttributeMappingRule, error) {
+	var rule models.AttributeMappingRule
+	if err := d.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// List lists all rules.
+func (d *AttributeMappingRuleDAO) List() ([]models.AttributeMappingRule, error) {
+	var rules []models.AttributeMappingRule
+	result := d.db.Order("provider ASC, resource_type ASC, canonical_name ASC").Find(&rules)
+	return rules, result.Error
+}
+
+// ListEnabled lists enabled rules of a provider.
+func (d *AttributeMappingRuleDAO) ListEnabled(provider string) ([]models.AttributeMappingRule, error) {
+	var rules []models.AttributeMappingRule
+	result := d.db.Where("enabled = ? AND provider = ?", true, provider).
+		Order("resource_type ASC, canonical_name ASC").
+		Find(&rules)
+	return rules, result.Error
+}
+
+// SetEnabled enables or disables a rule.
+func (d *AttributeMappingRuleDAO) SetEnabled(id int64, enabled bool) error {
+	return d.db.Model(&models.AttributeMappingRule{}).Where("id = ?", id).Update("enabled", enabled).Error
+}
+
+// Delete deletes a rule.
+func (d *AttributeMappingRuleDAO) Delete(id int64) error {
+	return d.db.Delete(&models.AttributeMappingRule{}, id).Error
+}
+
+func checkMappingRule(rule *models.AttributeMappingRule) error {
+	if rule.Provider == "" || rule.ResourceType == "" || rule.SourcePath == "" || rule.CanonicalName == "" {
+		return fmt.Errorf("provider, resource type, source path and canonical name are required")
+	}
+	if _, err := transform.Compile(rule.Transform); err != nil {
+		return fmt.Errorf("invalid transform: %w", err)
+	}
+	return nil
+}
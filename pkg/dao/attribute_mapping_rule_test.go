@@ -0,0 +1,46 @@
+package dao
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributeMappingRuleDAO(t *testing.T) {
+	dao := NewAttributeMappingRuleDAO(setupSQLiteDB(t))
+
+	assert.Error(t, dao.Create(&models.AttributeMappingRule{Provider: "aws", ResourceType: "aws_instance", CanonicalName: "hostname"}))
+	assert.Error(t, dao.Create(&models.AttributeMappingRule{Provider: "aws", ResourceType: "aws_instance", SourcePath: "tags.Name", CanonicalName: "hostname", Transform: "bogus"}))
+
+	hostname := &models.AttributeMappingRule{Provider: "aws", ResourceType: "aws_instance", SourcePath: "tags.Name", CanonicalName: "hostname", Enabled: true}
+	disk := &models.AttributeMappingRule{Provider: "aws", ResourceType: "aws_instance", SourcePath: "root_block_device.0.volume_size", CanonicalName: "disk_mb", Transform: "unit:GiB:MiB", Enabled: true}
+	other := &models.AttributeMappingRule{Provider: "alicloud", ResourceType: "alicloud_instance", SourcePath: "instance_name", CanonicalName: "hostname", Enabled: true}
+	disabled := &models.AttributeMappingRule{Provider: "aws", ResourceType: "aws_instance", SourcePath: "private_ip", CanonicalName: "private_ip"}
+	for _, r := range []*models.AttributeMappingRule{hostname, disk, other, disabled} {
+		assert.NoError(t, dao.Create(r))
+	}
+	assert.Error(t, dao.Create(&models.AttributeMappingRule{Provider: "aws", ResourceType: "aws_instance", SourcePath: "id", CanonicalName: "hostname"}))
+	assert.Error(t, dao.Create(&models.AttributeMappingRule{Provider: "aws", ResourceType: "aws_instance", SourcePath: "tags.Name", CanonicalName: "name"}))
+
+	rules, err := dao.ListEnabled("aws")
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, disk.ID, rules[0].ID)
+
+	hostname.Transform = "lower"
+	assert.NoError(t, dao.Update(hostname))
+	got, err := dao.Get(hostname.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "lower", got.Transform)
+	hostname.Transform = "scale:x"
+	assert.Error(t, dao.Update(hostname))
+
+	assert.NoError(t, dao.SetEnabled(disk.ID, false))
+	rules, _ = dao.ListEnabled("aws")
+	assert.Len(t, rules, 1)
+
+	assert.NoError(t, dao.Delete(other.ID))
+	all, _ := dao.List()
+	assert.Len(t, all, 3)
+}
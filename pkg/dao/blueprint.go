@@ -0,0 +1,230 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// ErrStaleInstance is returned when an instance changed since its upgrade was planned.
+var ErrStaleInstance = errors.New("blueprint instance changed since planned")
+
+// InstanceResource is a resource with its params written when instantiating
+// or upgrading a blueprint instance, Name is the resource name in the blueprint.
+type InstanceResource struct {
+	Name     string
+	Resource *models.TerraformResource
+	Params   []models.TerraformResourceParam
+}
+
+// BlueprintDAO provides blueprint and blueprint instance data access operations.
+type BlueprintDAO struct {
+	db *gorm.DB
+}
+
+// NewBlueprintDAO creates a new blueprint DAO.
+func NewBlueprintDAO(db *gorm.DB) *BlueprintDAO {
+	db.AutoMigrate(
+		&models.Blueprint{},
+		&models.BlueprintResource{},
+		&models.BlueprintParam{},
+		&models.BlueprintWire{},
+		&models.BlueprintInstance{},
+		&models.BlueprintInstanceResource{},
+		&models.TerraformResource{},
+		&models.TerraformResourceParam{},
+		&models.ResourceDependency{},
+	)
+	return &BlueprintDAO{db: db}
+}
+
+// Create creates a blueprint with its resources, params and wires.
+// A zero Version publishes the next version of the blueprint name.
+func (d *BlueprintDAO) Create(blueprint *models.Blueprint) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if blueprint.Version == 0 {
+			var latest int
+			if err := tx.Model(&models.Blueprint{}).Where("name = ?", blueprint.Name).
+				Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+				return err
+			}
+			blueprint.Version = latest + 1
+		}
+		return tx.Create(blueprint).Error
+	})
+}
+
+// Get retrieves a blueprint version with its resources, params and wires.
+func (d *BlueprintDAO) Get(name string, version int) (*models.Blueprint, error) {
+	var blueprint models.Blueprint
+	result := d.preload().Where("name = ? AND version = ?", name, version).First(&blueprint)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &blueprint, nil
+}
+
+// Latest retrieves the latest version of a blueprint.
+func (d *BlueprintDAO) Latest(name string) (*models.Blueprint, error) {
+	var blueprint models.Blueprint
+	result := d.preload().Where("name = ?", name).Order("version DESC").First(&blueprint)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &blueprint, nil
+}
+
+// ListVersions lists all versions of a blueprint without their contents.
+func (d *BlueprintDAO) ListVersions(name string) ([]models.Blueprint, error) {
+	var blueprints []models.Blueprint
+	result := d.db.Where("name = ?", name).Order("version ASC").Find(&blueprints)
+	return blueprints, result.Error
+}
+
+// GetInstance retrieves an instance with its resource mapping.
+func (d *BlueprintDAO) GetInstance(name string) (*models.BlueprintInstance, error) {
+	var instance models.BlueprintInstance
+	result := d.db.Preload("Resources").Where("name = ?", name).First(&instance)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &instance, nil
+}
+
+// CreateInstance creates an instance, its resources and params, and the
+// dependencies between them from the blueprint wires in one transaction.
+func (d *BlueprintDAO) CreateInstance(instance *models.BlueprintInstance, resources []InstanceResource, wires []models.BlueprintWire) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Resources").Create(instance).Error; err != nil {
+			return err
+		}
+		ids, err := createInstanceResources(tx, instance.ID, resources)
+		if err != nil {
+			return err
+		}
+		instance.Resources = nil
+		for _, r := range resources {
+			instance.Resources = append(instance.Resources, models.BlueprintInstanceResource{InstanceID: instance.ID, Resource: r.Name, ResourceID: ids[r.Name]})
+		}
+		return createWires(tx, ids, wires)
+	})
+}
+
+// UpgradeInstance moves an instance from fromVersion to the version and inputs
+// set on instance: it creates and updates resources, detaches removed resources
+// and rebuilds the dependencies between instance resources from wires.
+// Detached resources are kept so that they can still be destroyed.
+func (d *BlueprintDAO) UpgradeInstance(instance *models.BlueprintInstance, fromVersion int, create, update []InstanceResource, remove []string, wires []models.BlueprintWire) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.BlueprintInstance{}).
+			Where("id = ? AND version = ?", instance.ID, fromVersion).
+			Updates(map[string]interface{}{"version": instance.Version, "inputs": instance.Inputs})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("instance %s: %w", instance.Name, ErrStaleInstance)
+		}
+
+		var mapping []models.BlueprintInstanceResource
+		if err := tx.Where("instance_id = ?", instance.ID).Find(&mapping).Error; err != nil {
+			return err
+		}
+		ids := make(map[string]int64, len(mapping))
+		for _, m := range mapping {
+			ids[m.Resource] = m.ResourceID
+		}
+
+		// 实例内部的依赖按新版本重建，与实例外资源的依赖保留
+		var all []int64
+		for _, id := range ids {
+			all = append(all, id)
+		}
+		if len(all) > 0 {
+			if err := tx.Where("resource_id IN ? AND depends_on_id IN ?", all, all).
+				Delete(&models.ResourceDependency{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, name := range remove {
+			if err := tx.Where("instance_id = ? AND resource = ?", instance.ID, name).
+				Delete(&models.BlueprintInstanceResource{}).Error; err != nil {
+				return err
+			}
+			delete(ids, name)
+		}
+		for _, r := range update {
+			id, ok := ids[r.Name]
+			if !ok {
+				return fmt.Errorf("instance %s has no resource %s", instance.Name, r.Name)
+			}
+			if err := tx.Model(&models.TerraformResource{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"provider":      r.Resource.Provider,
+				"resource_type": r.Resource.ResourceType,
+				"region_id":     r.Resource.RegionId,
+				"tf_config":     r.Resource.TfConfig,
+			}).Error; err != nil {
+				return err
+			}
+			if err := replaceParams(tx, id, r.Params); err != nil {
+				return err
+			}
+		}
+		created, err := createInstanceResources(tx, instance.ID, create)
+		if err != nil {
+			return err
+		}
+		for name, id := range created {
+			ids[name] = id
+		}
+		return createWires(tx, ids, wires)
+	})
+}
+
+// createInstanceResources 创建资源、参数与实例映射，返回资源名到资源 ID 的映射
+func createInstanceResources(tx *gorm.DB, instanceID int64, resources []InstanceResource) (map[string]int64, error) {
+	ids := make(map[string]int64, len(resources))
+	for _, r := range resources {
+		if err := tx.Create(r.Resource).Error; err != nil {
+			return nil, fmt.Errorf("failed to create resource %s: %w", r.Name, err)
+		}
+		if err := replaceParams(tx, r.Resource.ID, r.Params); err != nil {
+			return nil, fmt.Errorf("failed to create params of resource %s: %w", r.Name, err)
+		}
+		if err := tx.Create(&models.BlueprintInstanceResource{InstanceID: instanceID, Resource: r.Name, ResourceID: r.Resource.ID}).Error; err != nil {
+			return nil, err
+		}
+		ids[r.Name] = r.Resource.ID
+	}
+	return ids, nil
+}
+
+// createWires 将蓝图依赖实例化为资源依赖
+func createWires(tx *gorm.DB, ids map[string]int64, wires []models.BlueprintWire) error {
+	for _, wire := range wires {
+		resourceID, ok := ids[wire.Resource]
+		dependsOnID, ok2 := ids[wire.DependsOn]
+		if !ok || !ok2 {
+			return fmt.Errorf("wire %s -> %s references an unknown resource", wire.Resource, wire.DependsOn)
+		}
+		paramName := wire.ParamName
+		if paramName == "" {
+			paramName = wire.Attribute
+		}
+		dep := &models.ResourceDependency{ResourceID: resourceID, DependsOnID: dependsOnID, Attribute: wire.Attribute, ParamName: paramName}
+		if err := tx.Create(dep).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *BlueprintDAO) preload() *gorm.DB {
+	return d.db.
+		Preload("Resources", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Params", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Wires", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") })
+}
@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlueprintDAO(t *testing.T) {
+	db := setupSQLiteDB(t)
+	dao := NewBlueprintDAO(db)
+
+	v1 := &models.Blueprint{
+		Name:      "stack",
+		Resources: []models.BlueprintResource{{Name: "network", Provider: "aws"}, {Name: "bastion", Provider: "aws"}},
+		Params:    []models.BlueprintParam{{Resource: "network", ParamName: "cidr"}},
+		Wires:     []models.BlueprintWire{{Resource: "bastion", DependsOn: "network", Attribute: "subnet_id"}},
+	}
+	require.NoError(t, dao.Create(v1))
+	assert.Equal(t, 1, v1.Version)
+	v2 := &models.Blueprint{Name: "stack", Resources: []models.BlueprintResource{{Name: "network", Provider: "aws"}}}
+	require.NoError(t, dao.Create(v2))
+	assert.Equal(t, 2, v2.Version)
+
+	latest, err := dao.Latest("stack")
+	require.NoError(t, err)
+	assert.Equal(t, 2, latest.Version)
+	got, err := dao.Get("stack", 1)
+	require.NoError(t, err)
+	assert.Len(t, got.Resources, 2)
+	assert.Len(t, got.Params, 1)
+	assert.Len(t, got.Wires, 1)
+	versions, _ := dao.ListVersions("stack")
+	assert.Len(t, versions, 2)
+
+	instance := &models.BlueprintInstance{Name: "dev", BlueprintName: "stack", Version: 1}
+	err = dao.CreateInstance(instance, []InstanceResource{
+		{Name: "network", Resource: &models.TerraformResource{ID: 11, Provider: "aws"}, Params: []models.TerraformResourceParam{{ParamName: "cidr", ParamValue: "10.0.0.0/16"}}},
+		{Name: "bastion", Resource: &models.TerraformResource{ID: 12, Provider: "aws"}},
+	}, got.Wires)
+	require.NoError(t, err)
+
+	deps := NewResourceDependencyDAO(db)
+	upstream, _ := deps.ListByResource(12)
+	require.Len(t, upstream, 1)
+	assert.Equal(t, int64(11), upstream[0].DependsOnID)
+	assert.Equal(t, "subnet_id", upstream[0].ParamName)
+
+	// 创建失败时整个实例回滚
+	err = dao.CreateInstance(&models.BlueprintInstance{Name: "broken", BlueprintName: "stack", Version: 1}, []InstanceResource{
+		{Name: "network", Resource: &models.TerraformResource{ID: 21, Provider: "aws"}},
+	}, got.Wires)
+	assert.Error(t, err)
+	_, err = dao.GetInstance("broken")
+	assert.Error(t, err)
+	_, err = NewTerraformResourceDAO(db).Get(21)
+	assert.Error(t, err)
+
+	// 升级：移除 bastion，更新 network
+	instance.Version = 2
+	err = dao.UpgradeInstance(instance, 1, nil, []InstanceResource{
+		{Name: "network", Resource: &models.TerraformResource{Provider: "aws", TfConfig: "# v2"}},
+	}, []string{"bastion"}, nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, dao.UpgradeInstance(instance, 1, nil, nil, nil, nil), ErrStaleInstance)
+
+	upgraded, err := dao.GetInstance("dev")
+	require.NoError(t, err)
+	assert.Equal(t, 2, upgraded.Version)
+	require.Len(t, upgraded.Resources, 1)
+	network, _ := NewTerraformResourceDAO(db).Get(11)
+	assert.Equal(t, "# v2", network.TfConfig)
+	params, _ := NewTerraformResourceParamDAO(db).ListByResourceID(11)
+	assert.Empty(t, params)
+	upstream, _ = deps.ListByResource(12)
+	assert.Empty(t, upstream)
+}
@@ -0,0 +1,191 @@
+package dao
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cylonchau/prism/pkg/encrypt"
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// CloudCredentialDAO provides encrypted cloud credential data access operations.
+type CloudCredentialDAO struct {
+	db      *gorm.DB
+	keyring *encrypt.Keyring
+}
+
+// NewCloudCredentialDAO creates a new cloud credential DAO.
+func NewCloudCredentialDAO(db *gorm.DB, keyring *encrypt.Keyring) *CloudCredentialDAO {
+	db.AutoMigrate(&models.CloudCredential{})
+	return &CloudCredentialDAO{db: db, keyring: keyring}
+}
+
+// Create encrypts and stores a new credential.
+func (d *CloudCredentialDAO) Create(provider, name, kind, description string, secret map[string]string) (*models.CloudCredential, error) {
+	cred := &models.CloudCredential{
+		Provider:    provider,
+		Name:        name,
+		Kind:        kind,
+		Description: description,
+	}
+	if err := d.seal(cred, secret); err != nil {
+		return nil, err
+	}
+	result := d.db.Create(cred)
+	return cred, result.Error
+}
+
+// Get retrieves credential by ID.
+func (d *CloudCredentialDAO) Get(id int64) (*models.CloudCredential, error) {
+	var cred models.CloudCredential
+	result := d.db.First(&cred, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &cred, nil
+}
+
+// GetByName retrieves credential by provider and name.
+func (d *CloudCredentialDAO) GetByName(provider, name string) (*models.CloudCredential, error) {
+	var cred models.CloudCredential
+	result := d.db.Where("provider = ? AND name = ?", provider, name).First(&cred)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &cred, nil
+}
+
+// ListByProvider lists credentials by provider.
+func (d *CloudCredentialDAO) ListByProvider(provider string) ([]models.CloudCredential, error) {
+	var creds []models.CloudCredential
+	result := d.db.Where("provider = ?", provider).Find(&creds)
+	return creds, result.Error
+}
+
+// Resolve decrypts the credential fields for provider and name.
+func (d *CloudCredentialDAO) Resolve(provider, name string) (map[string]string, error) {
+	cred, err := d.GetByName(provider, name)
+	if err != nil {
+		return nil, fmt.Errorf("credential %s/%s not found: %w", provider, name, err)
+	}
+	return d.open(cred)
+}
+
+// ResolveForResource decrypts the credential referenced by a resource.
+func (d *CloudCredentialDAO) ResolveForResource(resource *models.TerraformResource) (map[string]string, error) {
+	if resource.Credential == "" {
+		return nil, nil
+	}
+	return d.Resolve(resource.Provider, resource.Credential)
+}
+
+// UpdateSecret re-encrypts the credential fields.
+func (d *CloudCredentialDAO) UpdateSecret(provider, name string, secret map[string]string) error {
+	cred, err := d.GetByName(provider, name)
+	if err != nil {
+		return err
+	}
+	if err := d.seal(cred, secret); err != nil {
+		return err
+	}
+	return d.db.Save(cred).Error
+}
+
+// Delete deletes credential.
+func (d *CloudCredentialDAO) Delete(id int64) error {
+	return d.db.Delete(&models.CloudCredential{}, id).Error
+}
+
+// Rotate re-wraps all data keys not encrypted by the current primary master key.
+// It returns the number of credentials rotated.
+func (d *CloudCredentialDAO) Rotate() (int, error) {
+	var creds []models.CloudCredential
+	if err := d.db.Where("key_id <> ?", d.keyring.PrimaryID()).Find(&creds).Error; err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		for i := range creds {
+			env, err := envelopeOf(&creds[i])
+			if err != nil {
+				return err
+			}
+			env, err = d.keyring.Rewrap(env)
+			if err != nil {
+				return fmt.Errorf("credential %s/%s: %w", creds[i].Provider, creds[i].Name, err)
+			}
+			err = tx.Model(&models.CloudCredential{}).Where("id = ?", creds[i].ID).
+				Updates(map[string]interface{}{
+					"key_id":      env.KeyID,
+					"wrapped_key": base64.StdEncoding.EncodeToString(env.WrappedKey),
+				}).Error
+			if err != nil {
+				return err
+			}
+			rotated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rotated, nil
+}
+
+// seal encrypts secret into cred, binding the ciphertext to provider/name.
+func (d *CloudCredentialDAO) seal(cred *models.CloudCredential, secret map[string]string) error {
+	if d.keyring == nil {
+		return fmt.Errorf("master key not configured")
+	}
+	data, err := json.Marshal(secret)
+	if err != nil {
+		return err
+	}
+	env, err := d.keyring.SealEnvelope(data, credentialAAD(cred))
+	if err != nil {
+		return err
+	}
+	cred.KeyID = env.KeyID
+	cred.WrappedKey = base64.StdEncoding.EncodeToString(env.WrappedKey)
+	cred.Ciphertext = base64.StdEncoding.EncodeToString(env.Ciphertext)
+	return nil
+}
+
+// open decrypts the credential fields.
+func (d *CloudCredentialDAO) open(cred *models.CloudCredential) (map[string]string, error) {
+	if d.keyring == nil {
+		return nil, fmt.Errorf("master key not configured")
+	}
+	env, err := envelopeOf(cred)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.keyring.OpenEnvelope(env, credentialAAD(cred))
+	if err != nil {
+		return nil, fmt.Errorf("credential %s/%s: %w", cred.Provider, cred.Name, err)
+	}
+	secret := make(map[string]string)
+	if err := json.Unmarshal(data, &secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func envelopeOf(cred *models.CloudCredential) (*encrypt.Envelope, error) {
+	wrapped, err := base64.StdEncoding.DecodeString(cred.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped key: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(cred.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+	return &encrypt.Envelope{KeyID: cred.KeyID, WrappedKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+func credentialAAD(cred *models.CloudCredential) []byte {
+	return []byte(cred.Provider + "/" + cred.Name)
+}
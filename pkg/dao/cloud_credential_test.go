@@ -0,0 +1,103 @@
+package dao
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/encrypt"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupSQLiteDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	return db
+}
+
+func newTestKeyring(t *testing.T) (*encrypt.Keyring, []byte) {
+	key, err := encrypt.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyring, err := encrypt.NewKeyring(key)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	return keyring, key
+}
+
+func TestCloudCredentialDAO_CreateResolve(t *testing.T) {
+	keyring, _ := newTestKeyring(t)
+	dao := NewCloudCredentialDAO(setupSQLiteDB(t), keyring)
+
+	cred, err := dao.Create("aws", "prod", "access_key", "production account", map[string]string{
+		"access_key": "AKIA123",
+		"secret_key": "s3cr3t",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, keyring.PrimaryID(), cred.KeyID)
+	assert.False(t, strings.Contains(cred.Ciphertext, "s3cr3t"))
+
+	secret, err := dao.Resolve("aws", "prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "AKIA123", secret["access_key"])
+	assert.Equal(t, "s3cr3t", secret["secret_key"])
+
+	_, err = dao.Resolve("aws", "missing")
+	assert.Error(t, err)
+}
+
+func TestCloudCredentialDAO_ResolveForResource(t *testing.T) {
+	keyring, _ := newTestKeyring(t)
+	dao := NewCloudCredentialDAO(setupSQLiteDB(t), keyring)
+	dao.Create("tencentcloud", "default", "access_key", "", map[string]string{"secret_id": "id", "secret_key": "key"})
+
+	secret, err := dao.ResolveForResource(&models.TerraformResource{Provider: "tencentcloud", Credential: "default"})
+	assert.NoError(t, err)
+	assert.Equal(t, "id", secret["secret_id"])
+
+	secret, err = dao.ResolveForResource(&models.TerraformResource{Provider: "tencentcloud"})
+	assert.NoError(t, err)
+	assert.Nil(t, secret)
+}
+
+func TestCloudCredentialDAO_TamperedScope(t *testing.T) {
+	keyring, _ := newTestKeyring(t)
+	db := setupSQLiteDB(t)
+	dao := NewCloudCredentialDAO(db, keyring)
+	dao.Create("aws", "prod", "access_key", "", map[string]string{"secret_key": "a"})
+
+	// 密文绑定 provider/name，挪用到其他记录无法解密
+	db.Model(&models.CloudCredential{}).Where("name = ?", "prod").Update("name", "dev")
+	_, err := dao.Resolve("aws", "dev")
+	assert.Error(t, err)
+}
+
+func TestCloudCredentialDAO_Rotate(t *testing.T) {
+	oldKeyring, oldKey := newTestKeyring(t)
+	db := setupSQLiteDB(t)
+	NewCloudCredentialDAO(db, oldKeyring).Create("alicloud", "main", "access_key", "", map[string]string{"access_key": "ak", "secret_key": "sk"})
+
+	newKey, _ := encrypt.GenerateKey()
+	rotating, _ := encrypt.NewKeyring(newKey, oldKey)
+	dao := NewCloudCredentialDAO(db, rotating)
+
+	rotated, err := dao.Rotate()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rotated)
+
+	// 轮换后仅使用新密钥即可解密
+	newOnly, _ := encrypt.NewKeyring(newKey)
+	secret, err := NewCloudCredentialDAO(db, newOnly).Resolve("alicloud", "main")
+	assert.NoError(t, err)
+	assert.Equal(t, "sk", secret["secret_key"])
+
+	rotated, err = dao.Rotate()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, rotated)
+}
@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"fmt"
+	"regexp"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// ErrorPatternDAO provides error classification pattern access operations.
+type ErrorPatternDAO struct {
+	db *gorm.DB
+}
+
+// NewErrorPatternDAO creates a new error pattern DAO.
+func NewErrorPatternDAO(db *gorm.DB) *ErrorPatternDAO {
+	db.AutoMigrate(&models.ErrorPattern{})
+	return &ErrorPatternDAO{db: db}
+}
+
+// Create creates a pattern after validating the regular expression.
+func (d *ErrorPatternDAO) Create(pattern *models.ErrorPattern) error {
+	if _, err := regexp.Compile(pattern.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	return d.db.Create(pattern).Error
+}
+
+// Seed creates the patterns whose name is not stored yet. Stored patterns are
+// left as operators edited them; a deleted pattern is created again, use
+// SetEnabled to turn one off.
+func (d *ErrorPatternDAO) Seed(patterns []models.ErrorPattern) error {
+	for _, pattern := range patterns {
+		var count int64
+		if err := d.db.Model(&models.ErrorPattern{}).Where("name = ?", pattern.Name).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check pattern %s: %w", pattern.Name, err)
+		}
+		if count > 0 {
+			continue
+		}
+		if err := d.Create(&pattern); err != nil {
+			return fmt.Errorf("failed to seed pattern %s: %w", pattern.Name, err)
+		}
+	}
+	return nil
+}
+
+// ListByProvider lists enabled patterns of a provider and provider independent
+// patterns, provider specific first, then by priority.
+func (d *ErrorPatternDAO) ListByProvider(provider string) ([]models.ErrorPattern, error) {
+	var patterns []models.ErrorPattern
+	result := d.db.Where("enabled = ? AND provider IN ?", true, []string{provider, ""}).
+		Order("provider DESC, priority DESC, id ASC").
+		Find(&patterns)
+	return patterns, result.Error
+}
+
+// List lists all patterns.
+func (d *ErrorPatternDAO) List() ([]models.ErrorPattern, error) {
+	var patterns []models.ErrorPattern
+	result := d.db.Order("provider ASC, priority DESC, id ASC").Find(&patterns)
+	return patterns, result.Error
+}
+
+// SetEnabled enables or disables a pattern.
+func (d *ErrorPatternDAO) SetEnabled(id int64, enabled bool) error {
+	return d.db.Model(&models.ErrorPattern{}).Where("id = ?", id).Update("enabled", enabled).Error
+}
+
+// Delete deletes a pattern.
+func (d *ErrorPatternDAO) Delete(id int64) error {
+	return d.db.Delete(&models.ErrorPattern{}, id).Error
+}
@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPatternDAO(t *testing.T) {
+	dao := NewErrorPatternDAO(setupSQLiteDB(t))
+
+	assert.Error(t, dao.Create(&models.ErrorPattern{Code: "quota_exceeded", Pattern: "("}))
+
+	global := &models.ErrorPattern{Code: "rate_limited", Pattern: "slow down", Priority: 1, Enabled: true}
+	low := &models.ErrorPattern{Provider: "aws", Code: "auth_failed", Pattern: "ExpiredToken", Enabled: true}
+	high := &models.ErrorPattern{Provider: "aws", Code: "quota_exceeded", Pattern: "VcpuLimitExceeded", Priority: 10, Enabled: true}
+	other := &models.ErrorPattern{Provider: "alicloud", Code: "rate_limited", Pattern: "Throttling", Enabled: true}
+	for _, p := range []*models.ErrorPattern{global, low, high, other} {
+		assert.NoError(t, dao.Create(p))
+	}
+
+	patterns, err := dao.ListByProvider("aws")
+	assert.NoError(t, err)
+	assert.Len(t, patterns, 3)
+	assert.Equal(t, high.ID, patterns[0].ID)
+	assert.Equal(t, low.ID, patterns[1].ID)
+	assert.Equal(t, global.ID, patterns[2].ID)
+
+	assert.NoError(t, dao.SetEnabled(high.ID, false))
+	patterns, _ = dao.ListByProvider("aws")
+	assert.Len(t, patterns, 2)
+
+	assert.NoError(t, dao.Delete(low.ID))
+	all, _ := dao.List()
+	assert.Len(t, all, 3)
+}
+
+func TestErrorPatternDAO_CreateDisabled(t *testing.T) {
+	dao := NewErrorPatternDAO(setupSQLiteDB(t))
+
+	assert.NoError(t, dao.Create(&models.ErrorPattern{Code: "quota_exceeded", Pattern: "quota"}))
+	patterns, err := dao.ListByProvider("aws")
+	assert.NoError(t, err)
+	assert.Empty(t, patterns)
+}
+
+func TestErrorPatternDAO_Seed(t *testing.T) {
+	dao := NewErrorPatternDAO(setupSQLiteDB(t))
+	builtin := []models.ErrorPattern{
+		{Name: "quota", Code: "quota_exceeded", Pattern: "quota", Enabled: true},
+		{Name: "conflict", Code: "conflict", Pattern: "already exists", Enabled: true},
+	}
+
+	assert.NoError(t, dao.Seed(builtin))
+	all, _ := dao.List()
+	assert.Len(t, all, 2)
+
+	// 已有规则保留运维的修改，不被覆盖
+	for _, p := range all {
+		if p.Name == "quota" {
+			assert.NoError(t, dao.SetEnabled(p.ID, false))
+		}
+	}
+	assert.NoError(t, dao.Seed(builtin))
+	all, _ = dao.List()
+	assert.Len(t, all, 2)
+	patterns, _ := dao.ListByProvider("aws")
+	assert.Len(t, patterns, 1)
+	assert.Equal(t, "conflict", patterns[0].Name)
+}
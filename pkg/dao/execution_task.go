@@ -15,7 +15,7 @@ type ExecutionTaskDAO struct {
 
 // NewExecutionTaskDAO creates a new task DAO.
 func NewExecutionTaskDAO(db *gorm.DB) *ExecutionTaskDAO {
-	db.AutoMigrate(&models.ExecutionTask{})
+	db.AutoMigrate(&models.ExecutionTask{}, &models.TaskAttempt{})
 	return &ExecutionTaskDAO{db: db}
 }
 
@@ -31,6 +31,26 @@ func (d *ExecutionTaskDAO) Create(taskID string, resourceID int64, action string
 	return task, result.Error
 }
 
+// CreateChild creates a task linked to a parent task, such as the rollback of a failed apply.
+func (d *ExecutionTaskDAO) CreateChild(taskID, parentTaskID string, resourceID int64, action string) (*models.ExecutionTask, error) {
+	task := &models.ExecutionTask{
+		TaskID:       taskID,
+		ParentTaskID: parentTaskID,
+		ResourceID:   resourceID,
+		Action:       action,
+		Status:       models.TaskStatusPending,
+	}
+	result := d.db.Create(task)
+	return task, result.Error
+}
+
+// ListChildren lists tasks linked to a parent task, pipeline steps in order.
+func (d *ExecutionTaskDAO) ListChildren(parentTaskID string) ([]models.ExecutionTask, error) {
+	var tasks []models.ExecutionTask
+	result := d.db.Where("parent_task_id = ?", parentTaskID).Order("step ASC, created_at ASC").Find(&tasks)
+	return tasks, result.Error
+}
+
 // Get retrieves a task by task ID.
 func (d *ExecutionTaskDAO) Get(taskID string) (*models.ExecutionTask, error) {
 	var task models.ExecutionTask
@@ -41,6 +61,13 @@ func (d *ExecutionTaskDAO) Get(taskID string) (*models.ExecutionTask, error) {
 	return &task, nil
 }
 
+// Exists checks whether a task record exists.
+func (d *ExecutionTaskDAO) Exists(taskID string) (bool, error) {
+	var count int64
+	result := d.db.Model(&models.ExecutionTask{}).Where("task_id = ?", taskID).Count(&count)
+	return count > 0, result.Error
+}
+
 // UpdateStatus updates task status.
 func (d *ExecutionTaskDAO) UpdateStatus(taskID string, status models.TaskStatus) error {
 	return d.db.Model(&models.ExecutionTask{}).
@@ -48,7 +75,7 @@ func (d *ExecutionTaskDAO) UpdateStatus(taskID string, status models.TaskStatus)
 		Update("status", status).Error
 }
 
-// Start marks task as running.
+// Start marks task as running and counts a new attempt.
 func (d *ExecutionTaskDAO) Start(taskID string) error {
 	now := time.Now()
 	return d.db.Model(&models.ExecutionTask{}).
@@ -56,6 +83,7 @@ func (d *ExecutionTaskDAO) Start(taskID string) error {
 		Updates(map[string]interface{}{
 			"status":     models.TaskStatusRunning,
 			"started_at": now,
+			"attempt":    gorm.Expr("attempt + 1"),
 		}).Error
 }
 
@@ -86,20 +114,94 @@ func (d *ExecutionTaskDAO) Complete(taskID string, success bool, output, errMsg
 		}).Error
 }
 
+// SetErrorCode records the classification of a failed task.
+func (d *ExecutionTaskDAO) SetErrorCode(taskID, code string, retryable bool) error {
+	return d.db.Model(&models.ExecutionTask{}).
+		Where("task_id = ?", taskID).
+		Updates(map[string]interface{}{
+			"error_code": code,
+			"permanent":  !retryable,
+		}).Error
+}
+
 // Reset resets a failed task for retry.
 func (d *ExecutionTaskDAO) Reset(taskID string) error {
 	return d.db.Model(&models.ExecutionTask{}).
 		Where("task_id = ?", taskID).
 		Updates(map[string]interface{}{
-			"status":      models.TaskStatusPending,
-			"output":      "",
-			"error":       "",
-			"started_at":  nil,
-			"finished_at": nil,
-			"duration":    0,
+			"status":        models.TaskStatusPending,
+			"output":        "",
+			"error":         "",
+			"error_code":    "",
+			"permanent":     false,
+			"next_retry_at": nil,
+			"started_at":    nil,
+			"finished_at":   nil,
+			"duration":      0,
 		}).Error
 }
 
+// RecordAttempt appends the current result of a completed task to its attempt history.
+func (d *ExecutionTaskDAO) RecordAttempt(taskID string) error {
+	task, err := d.Get(taskID)
+	if err != nil {
+		return err
+	}
+	return d.db.Create(&models.TaskAttempt{
+		TaskID:     task.TaskID,
+		Attempt:    task.Attempt,
+		Status:     task.Status,
+		ErrorCode:  task.ErrorCode,
+		Error:      task.Error,
+		StartedAt:  task.StartedAt,
+		FinishedAt: task.FinishedAt,
+		Duration:   task.Duration,
+	}).Error
+}
+
+// ListAttempts lists the attempt history of a task.
+func (d *ExecutionTaskDAO) ListAttempts(taskID string) ([]models.TaskAttempt, error) {
+	var attempts []models.TaskAttempt
+	result := d.db.Where("task_id = ?", taskID).Order("attempt ASC").Find(&attempts)
+	return attempts, result.Error
+}
+
+// ScheduleRetry schedules an automatic retry of a failed task.
+func (d *ExecutionTaskDAO) ScheduleRetry(taskID string, at time.Time) error {
+	return d.db.Model(&models.ExecutionTask{}).
+		Where("task_id = ?", taskID).
+		Update("next_retry_at", at).Error
+}
+
+// ListDueRetries lists failed tasks whose scheduled retry is due.
+func (d *ExecutionTaskDAO) ListDueRetries(now time.Time, limit int) ([]models.ExecutionTask, error) {
+	var tasks []models.ExecutionTask
+	result := d.db.Where("status = ? AND permanent = ? AND next_retry_at <= ?", models.TaskStatusFailed, false, now).
+		Order("next_retry_at ASC").
+		Limit(limit).
+		Find(&tasks)
+	return tasks, result.Error
+}
+
+// ClaimRetry resets a task whose retry is due for re-execution. It returns false
+// if the task has been claimed by another scheduler or is no longer due.
+func (d *ExecutionTaskDAO) ClaimRetry(taskID string, now time.Time) (bool, error) {
+	result := d.db.Model(&models.ExecutionTask{}).
+		Where("task_id = ? AND status = ? AND next_retry_at <= ?", taskID, models.TaskStatusFailed, now).
+		Updates(map[string]interface{}{
+			"status":        models.TaskStatusPending,
+			"output":        "",
+			"error":         "",
+			"error_code":    "",
+			"permanent":     false,
+			"next_retry_at": nil,
+			"started_at":    nil,
+			"finished_at":   nil,
+			"duration":      0,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
 // ListByResource lists tasks for a resource.
 func (d *ExecutionTaskDAO) ListByResource(resourceID int64) ([]models.ExecutionTask, error) {
 	var tasks []models.ExecutionTask
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	models "github.com/cylonchau/prism/pkg/model"
@@ -181,3 +182,72 @@ func TestExecutionTaskDAO_Complete_Error(t *testing.T) {
 	err := dao.Complete("task-1", true, "output", "")
 	assert.Error(t, err)
 }
+
+func TestExecutionTaskDAO_Retry(t *testing.T) {
+	dao := NewExecutionTaskDAO(setupSQLiteDB(t))
+
+	exists, err := dao.Exists("task-1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	dao.Create("task-1", 100, "apply")
+	exists, _ = dao.Exists("task-1")
+	assert.True(t, exists)
+
+	// 第一次执行失败
+	assert.NoError(t, dao.Start("task-1"))
+	assert.NoError(t, dao.Complete("task-1", false, "", "rate exceeded"))
+	assert.NoError(t, dao.SetErrorCode("task-1", "rate_limited", true))
+	assert.NoError(t, dao.RecordAttempt("task-1"))
+
+	now := time.Now()
+	assert.NoError(t, dao.ScheduleRetry("task-1", now.Add(time.Minute)))
+	due, err := dao.ListDueRetries(now, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+
+	later := now.Add(2 * time.Minute)
+	due, _ = dao.ListDueRetries(later, 10)
+	assert.Len(t, due, 1)
+
+	claimed, err := dao.ClaimRetry("task-1", later)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	// 已被领取的重试不能再次领取
+	claimed, _ = dao.ClaimRetry("task-1", later)
+	assert.False(t, claimed)
+
+	task, _ := dao.Get("task-1")
+	assert.Equal(t, models.TaskStatusPending, task.Status)
+	assert.Nil(t, task.NextRetryAt)
+	assert.Empty(t, task.ErrorCode)
+
+	// 第二次执行成功
+	dao.Start("task-1")
+	dao.Complete("task-1", true, "", "")
+	dao.RecordAttempt("task-1")
+
+	attempts, err := dao.ListAttempts("task-1")
+	assert.NoError(t, err)
+	assert.Len(t, attempts, 2)
+	assert.Equal(t, 1, attempts[0].Attempt)
+	assert.Equal(t, "rate_limited", attempts[0].ErrorCode)
+	assert.Equal(t, models.TaskStatusSuccess, attempts[1].Status)
+}
+
+func TestExecutionTaskDAO_Children(t *testing.T) {
+	dao := NewExecutionTaskDAO(setupSQLiteDB(t))
+
+	dao.Create("task-1", 100, "apply")
+	child, err := dao.CreateChild("task-1-rollback", "task-1", 100, "rollback")
+	assert.NoError(t, err)
+	assert.Equal(t, "task-1", child.ParentTaskID)
+
+	children, err := dao.ListChildren("task-1")
+	assert.NoError(t, err)
+	assert.Len(t, children, 1)
+	assert.Equal(t, "task-1-rollback", children[0].TaskID)
+
+	children, _ = dao.ListChildren("task-1-rollback")
+	assert.Empty(t, children)
+}
@@ -0,0 +1,93 @@
+package dao
+
+import (
+	"fmt"
+	"time"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// PipelineDAO provides pipeline data access operations.
+type PipelineDAO struct {
+	db *gorm.DB
+}
+
+// NewPipelineDAO creates a new pipeline DAO.
+func NewPipelineDAO(db *gorm.DB) *PipelineDAO {
+	db.AutoMigrate(&models.Pipeline{}, &models.ExecutionTask{})
+	return &PipelineDAO{db: db}
+}
+
+// StepTaskID returns the task ID of a pipeline step.
+func StepTaskID(pipelineID string, step int, kind string) string {
+	return fmt.Sprintf("%s-%d-%s", pipelineID, step, kind)
+}
+
+// Create creates a pipeline and a pending task for each step kind, in order.
+func (d *PipelineDAO) Create(pipeline *models.Pipeline, steps []string) ([]models.ExecutionTask, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("pipeline has no steps")
+	}
+	pipeline.Status = models.PipelineStatusPending
+	tasks := make([]models.ExecutionTask, 0, len(steps))
+	for i, kind := range steps {
+		tasks = append(tasks, models.ExecutionTask{
+			TaskID:       StepTaskID(pipeline.PipelineID, i+1, kind),
+			ParentTaskID: pipeline.PipelineID,
+			Step:         i + 1,
+			ResourceID:   pipeline.ResourceID,
+			Action:       kind,
+			Status:       models.TaskStatusPending,
+		})
+	}
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(pipeline).Error; err != nil {
+			return err
+		}
+		return tx.Create(&tasks).Error
+	})
+	return tasks, err
+}
+
+// Get retrieves a pipeline by pipeline ID.
+func (d *PipelineDAO) Get(pipelineID string) (*models.Pipeline, error) {
+	var pipeline models.Pipeline
+	result := d.db.Where("pipeline_id = ?", pipelineID).First(&pipeline)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &pipeline, nil
+}
+
+// ListByStatus lists pipelines in a status, newest first.
+func (d *PipelineDAO) ListByStatus(status string) ([]models.Pipeline, error) {
+	var pipelines []models.Pipeline
+	result := d.db.Where("status = ?", status).Order("created_at DESC").Find(&pipelines)
+	return pipelines, result.Error
+}
+
+// UpdateStatus updates the aggregate status and current step of a pipeline.
+// Final statuses set the finish time.
+func (d *PipelineDAO) UpdateStatus(pipelineID, status string, step int, errMsg string) error {
+	updates := map[string]interface{}{
+		"status":      status,
+		"step":        step,
+		"error":       errMsg,
+		"finished_at": nil,
+	}
+	if p := (models.Pipeline{Status: status}); p.IsFinished() {
+		updates["finished_at"] = time.Now()
+	}
+	return d.db.Model(&models.Pipeline{}).Where("pipeline_id = ?", pipelineID).Updates(updates).Error
+}
+
+// SetWorkDir sets the work directory shared by the steps of a pipeline.
+func (d *PipelineDAO) SetWorkDir(pipelineID, workDir string) error {
+	return d.db.Model(&models.Pipeline{}).Where("pipeline_id = ?", pipelineID).Update("work_dir", workDir).Error
+}
+
+// SetApprover records who approved or rejected a pipeline.
+func (d *PipelineDAO) SetApprover(pipelineID, approver string) error {
+	return d.db.Model(&models.Pipeline{}).Where("pipeline_id = ?", pipelineID).Update("approver", approver).Error
+}
@@ -0,0 +1,42 @@
+package dao
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelineDAO(t *testing.T) {
+	db := setupSQLiteDB(t)
+	dao := NewPipelineDAO(db)
+	taskDAO := NewExecutionTaskDAO(db)
+
+	_, err := dao.Create(&models.Pipeline{PipelineID: "pl-1", ResourceID: 1}, nil)
+	assert.Error(t, err)
+
+	tasks, err := dao.Create(&models.Pipeline{PipelineID: "pl-1", ResourceID: 1}, []string{models.StepPlan, models.StepApply})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	steps, err := taskDAO.ListChildren("pl-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "pl-1-1-plan", steps[0].TaskID)
+	assert.Equal(t, 2, steps[1].Step)
+	assert.Equal(t, models.StepApply, steps[1].Action)
+
+	assert.NoError(t, dao.UpdateStatus("pl-1", models.PipelineStatusFailed, 2, "boom"))
+	pipeline, err := dao.Get("pl-1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusFailed, pipeline.Status)
+	assert.Equal(t, "boom", pipeline.Error)
+	assert.NotNil(t, pipeline.FinishedAt)
+
+	assert.NoError(t, dao.UpdateStatus("pl-1", models.PipelineStatusRunning, 2, ""))
+	pipeline, _ = dao.Get("pl-1")
+	assert.Nil(t, pipeline.FinishedAt)
+
+	running, err := dao.ListByStatus(models.PipelineStatusRunning)
+	assert.NoError(t, err)
+	assert.Len(t, running, 1)
+}
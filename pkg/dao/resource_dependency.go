@@ -0,0 +1,102 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// ErrDependencyCycle is returned when a dependency would make the graph cyclic.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// ResourceDependencyDAO provides resource dependency data access operations.
+type ResourceDependencyDAO struct {
+	db *gorm.DB
+}
+
+// NewResourceDependencyDAO creates a new resource dependency DAO.
+func NewResourceDependencyDAO(db *gorm.DB) *ResourceDependencyDAO {
+	db.AutoMigrate(&models.ResourceDependency{})
+	return &ResourceDependencyDAO{db: db}
+}
+
+// Create adds a dependency, rejecting self dependencies and cycles.
+// ParamName defaults to Attribute.
+func (d *ResourceDependencyDAO) Create(dep *models.ResourceDependency) error {
+	if dep.ResourceID == dep.DependsOnID {
+		return fmt.Errorf("resource %d: %w", dep.ResourceID, ErrDependencyCycle)
+	}
+	if dep.ParamName == "" {
+		dep.ParamName = dep.Attribute
+	}
+
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var deps []models.ResourceDependency
+		if err := tx.Find(&deps).Error; err != nil {
+			return err
+		}
+		// 新依赖成环当且仅当上游资源已 (间接) 依赖下游资源
+		if reachable(deps, dep.DependsOnID, dep.ResourceID) {
+			return fmt.Errorf("resource %d depends on %d: %w", dep.DependsOnID, dep.ResourceID, ErrDependencyCycle)
+		}
+		return tx.Create(dep).Error
+	})
+}
+
+// List lists all dependencies.
+func (d *ResourceDependencyDAO) List() ([]models.ResourceDependency, error) {
+	var deps []models.ResourceDependency
+	result := d.db.Order("resource_id ASC, depends_on_id ASC").Find(&deps)
+	return deps, result.Error
+}
+
+// ListByResource lists the upstream dependencies of a resource.
+func (d *ResourceDependencyDAO) ListByResource(resourceID int64) ([]models.ResourceDependency, error) {
+	var deps []models.ResourceDependency
+	result := d.db.Where("resource_id = ?", resourceID).Order("depends_on_id ASC").Find(&deps)
+	return deps, result.Error
+}
+
+// ListDependents lists the dependencies on a resource, i.e. its downstream resources.
+func (d *ResourceDependencyDAO) ListDependents(resourceID int64) ([]models.ResourceDependency, error) {
+	var deps []models.ResourceDependency
+	result := d.db.Where("depends_on_id = ?", resourceID).Order("resource_id ASC").Find(&deps)
+	return deps, result.Error
+}
+
+// Delete deletes a dependency.
+func (d *ResourceDependencyDAO) Delete(id int64) error {
+	return d.db.Delete(&models.ResourceDependency{}, id).Error
+}
+
+// DeleteByResource deletes all dependencies from and on a resource.
+func (d *ResourceDependencyDAO) DeleteByResource(resourceID int64) error {
+	return d.db.Where("resource_id = ? OR depends_on_id = ?", resourceID, resourceID).
+		Delete(&models.ResourceDependency{}).Error
+}
+
+// reachable 判断沿依赖方向能否从 from 到达 to
+func reachable(deps []models.ResourceDependency, from, to int64) bool {
+	upstream := make(map[int64][]int64)
+	for _, dep := range deps {
+		upstream[dep.ResourceID] = append(upstream[dep.ResourceID], dep.DependsOnID)
+	}
+	visited := map[int64]bool{from: true}
+	stack := []int64{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == to {
+			return true
+		}
+		for _, next := range upstream[id] {
+			if !visited[next] {
+				visited[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+	return false
+}
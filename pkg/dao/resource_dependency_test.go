@@ -0,0 +1,35 @@
+package dao
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceDependencyDAO(t *testing.T) {
+	dao := NewResourceDependencyDAO(setupSQLiteDB(t))
+
+	// vpc(1) <- subnet(2) <- instance(3)
+	subnet := &models.ResourceDependency{ResourceID: 2, DependsOnID: 1, Attribute: "vpc_id"}
+	assert.NoError(t, dao.Create(subnet))
+	assert.Equal(t, "vpc_id", subnet.ParamName)
+	assert.NoError(t, dao.Create(&models.ResourceDependency{ResourceID: 3, DependsOnID: 2, Attribute: "subnet_id", ParamName: "instance_subnet"}))
+	assert.NoError(t, dao.Create(&models.ResourceDependency{ResourceID: 3, DependsOnID: 1}))
+
+	assert.ErrorIs(t, dao.Create(&models.ResourceDependency{ResourceID: 1, DependsOnID: 1}), ErrDependencyCycle)
+	assert.ErrorIs(t, dao.Create(&models.ResourceDependency{ResourceID: 1, DependsOnID: 3}), ErrDependencyCycle)
+
+	deps, err := dao.ListByResource(3)
+	assert.NoError(t, err)
+	assert.Len(t, deps, 2)
+	assert.Equal(t, int64(1), deps[0].DependsOnID)
+
+	dependents, err := dao.ListDependents(1)
+	assert.NoError(t, err)
+	assert.Len(t, dependents, 2)
+
+	assert.NoError(t, dao.DeleteByResource(2))
+	all, _ := dao.List()
+	assert.Len(t, all, 1)
+}
@@ -0,0 +1,61 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// durationWeight is the weight of a new sample in the moving average.
+const durationWeight = 0.3
+
+// ResourceDurationDAO provides historical resource duration access operations.
+type ResourceDurationDAO struct {
+	db *gorm.DB
+}
+
+// NewResourceDurationDAO creates a new resource duration DAO.
+func NewResourceDurationDAO(db *gorm.DB) *ResourceDurationDAO {
+	db.AutoMigrate(&models.ResourceDuration{})
+	return &ResourceDurationDAO{db: db}
+}
+
+// Record adds a duration sample of a resource type and action.
+func (d *ResourceDurationDAO) Record(resourceType, action string, elapsed time.Duration) error {
+	ms := elapsed.Milliseconds()
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var rd models.ResourceDuration
+		err := tx.Where("resource_type = ? AND action = ?", resourceType, action).First(&rd).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&models.ResourceDuration{
+				ResourceType: resourceType,
+				Action:       action,
+				Samples:      1,
+				AvgMs:        ms,
+				LastMs:       ms,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+		avg := int64(float64(rd.AvgMs)*(1-durationWeight) + float64(ms)*durationWeight)
+		return tx.Model(&rd).Updates(map[string]interface{}{
+			"samples": rd.Samples + 1,
+			"avg_ms":  avg,
+			"last_ms": ms,
+		}).Error
+	})
+}
+
+// Estimate returns the average duration of a resource type and action.
+// ok is false if no sample has been recorded.
+func (d *ResourceDurationDAO) Estimate(resourceType, action string) (time.Duration, bool) {
+	var rd models.ResourceDuration
+	err := d.db.Where("resource_type = ? AND action = ?", resourceType, action).First(&rd).Error
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(rd.AvgMs) * time.Millisecond, true
+}
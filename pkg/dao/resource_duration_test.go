@@ -0,0 +1,29 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceDurationDAO(t *testing.T) {
+	dao := NewResourceDurationDAO(setupSQLiteDB(t))
+
+	_, ok := dao.Estimate("aws_instance", "create")
+	assert.False(t, ok)
+
+	assert.NoError(t, dao.Record("aws_instance", "create", 10*time.Second))
+	d, ok := dao.Estimate("aws_instance", "create")
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, d)
+
+	// 新样本按权重并入平均值
+	assert.NoError(t, dao.Record("aws_instance", "create", 20*time.Second))
+	d, _ = dao.Estimate("aws_instance", "create")
+	assert.Equal(t, 13*time.Second, d)
+
+	// 不同操作分别统计
+	_, ok = dao.Estimate("aws_instance", "delete")
+	assert.False(t, ok)
+}
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// RetryPolicyDAO provides retry policy data access operations.
+type RetryPolicyDAO struct {
+	db *gorm.DB
+}
+
+// NewRetryPolicyDAO creates a new retry policy DAO.
+func NewRetryPolicyDAO(db *gorm.DB) *RetryPolicyDAO {
+	db.AutoMigrate(&models.RetryPolicy{})
+	return &RetryPolicyDAO{db: db}
+}
+
+// Create creates a new policy.
+func (d *RetryPolicyDAO) Create(policy *models.RetryPolicy) error {
+	return d.db.Create(policy).Error
+}
+
+// Update updates a policy.
+func (d *RetryPolicyDAO) Update(policy *models.RetryPolicy) error {
+	return d.db.Save(policy).Error
+}
+
+// Delete deletes a policy.
+func (d *RetryPolicyDAO) Delete(id int64) error {
+	return d.db.Delete(&models.RetryPolicy{}, id).Error
+}
+
+// List lists all policies.
+func (d *RetryPolicyDAO) List() ([]models.RetryPolicy, error) {
+	var policies []models.RetryPolicy
+	result := d.db.Order("provider ASC, action ASC").Find(&policies)
+	return policies, result.Error
+}
+
+// Match returns the most specific enabled policy for a provider and action:
+// provider and action, provider only, action only, then the global policy.
+// It returns nil if no policy matches.
+func (d *RetryPolicyDAO) Match(provider, action string) (*models.RetryPolicy, error) {
+	var policies []models.RetryPolicy
+	result := d.db.Where("enabled = ? AND provider IN ? AND action IN ?",
+		true, []string{provider, ""}, []string{action, ""}).
+		Find(&policies)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var best *models.RetryPolicy
+	bestScore := -1
+	for i := range policies {
+		score := 0
+		if policies[i].Provider != "" {
+			score += 2
+		}
+		if policies[i].Action != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = &policies[i], score
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,48 @@
+package dao
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyDAO_Match(t *testing.T) {
+	dao := NewRetryPolicyDAO(setupSQLiteDB(t))
+
+	policy, err := dao.Match("aws", "apply")
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+
+	global := &models.RetryPolicy{MaxAttempts: 2}
+	apply := &models.RetryPolicy{Action: "apply", MaxAttempts: 3}
+	aws := &models.RetryPolicy{Provider: "aws", MaxAttempts: 4}
+	awsApply := &models.RetryPolicy{Provider: "aws", Action: "apply", MaxAttempts: 5}
+	for _, p := range []*models.RetryPolicy{global, apply, aws, awsApply} {
+		assert.NoError(t, dao.Create(p))
+	}
+
+	tests := []struct {
+		provider, action string
+		expected         int64
+	}{
+		{"aws", "apply", awsApply.ID},
+		{"aws", "destroy", aws.ID},
+		{"alicloud", "apply", apply.ID},
+		{"alicloud", "destroy", global.ID},
+	}
+	for _, tt := range tests {
+		policy, err := dao.Match(tt.provider, tt.action)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, policy.ID, "%s/%s", tt.provider, tt.action)
+	}
+
+	// 禁用的策略不参与匹配
+	awsApply.Enabled = false
+	assert.NoError(t, dao.Update(awsApply))
+	policy, _ = dao.Match("aws", "apply")
+	assert.Equal(t, aws.ID, policy.ID)
+
+	policies, _ := dao.List()
+	assert.Len(t, policies, 4)
+}
@@ -0,0 +1,229 @@
+package dao
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cylonchau/prism/pkg/encrypt"
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// encryptedPrefix 加密列的前缀，格式: enc:v1:<data_key.id>:<base64(nonce||ciphertext)>
+const encryptedPrefix = "enc:v1:"
+
+// StateCipher encrypts state columns with per-tenant data keys.
+// Data keys are wrapped by the master keyring and stored in data_key.
+type StateCipher struct {
+	db      *gorm.DB
+	keyring *encrypt.Keyring
+
+	mu   sync.Mutex
+	keys map[int64][]byte // 已解密的数据密钥缓存
+}
+
+// NewStateCipher creates a new state cipher.
+func NewStateCipher(db *gorm.DB, keyring *encrypt.Keyring) *StateCipher {
+	db.AutoMigrate(&models.DataKey{})
+	return &StateCipher{db: db, keyring: keyring, keys: make(map[int64][]byte)}
+}
+
+// IsEncrypted reports whether value was produced by StateCipher.Encrypt.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Encrypt encrypts plaintext with the tenant's active data key.
+// aad binds the ciphertext to its row and column. Empty values are kept empty.
+func (c *StateCipher) Encrypt(tenant, aad, plaintext string) (string, error) {
+	return c.encrypt(c.db, tenant, aad, plaintext)
+}
+
+// Decrypt decrypts a value produced by Encrypt. Plaintext values written
+// before encryption was enabled are returned unchanged.
+func (c *StateCipher) Decrypt(aad, value string) (string, error) {
+	return c.decrypt(c.db, aad, value)
+}
+
+func (c *StateCipher) decrypt(db *gorm.DB, aad, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("malformed data key id: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid ciphertext: %w", err)
+	}
+
+	key, err := c.dataKey(db, id)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := encrypt.Open(key, data, []byte(aad))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", aad, err)
+	}
+	return string(plaintext), nil
+}
+
+// NewDataKey generates a new active data key for tenant and retires the previous one.
+// Retired keys are kept so existing ciphertexts remain readable.
+func (c *StateCipher) NewDataKey(tenant string) (*models.DataKey, error) {
+	return c.newDataKey(c.db, tenant)
+}
+
+// Rotate re-wraps all data keys not encrypted by the current primary master key.
+// It returns the number of data keys rotated.
+func (c *StateCipher) Rotate() (int, error) {
+	var keys []models.DataKey
+	if err := c.db.Where("key_id <> ?", c.keyring.PrimaryID()).Find(&keys).Error; err != nil {
+		return 0, err
+	}
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		for i := range keys {
+			wrapped, err := base64.StdEncoding.DecodeString(keys[i].WrappedKey)
+			if err != nil {
+				return fmt.Errorf("data key %d: invalid wrapped key: %w", keys[i].ID, err)
+			}
+			env, err := c.keyring.Rewrap(&encrypt.Envelope{KeyID: keys[i].KeyID, WrappedKey: wrapped})
+			if err != nil {
+				return fmt.Errorf("data key %d: %w", keys[i].ID, err)
+			}
+			err = tx.Model(&models.DataKey{}).Where("id = ?", keys[i].ID).
+				Updates(map[string]interface{}{
+					"key_id":      env.KeyID,
+					"wrapped_key": base64.StdEncoding.EncodeToString(env.WrappedKey),
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(keys), nil
+}
+
+func (c *StateCipher) encrypt(db *gorm.DB, tenant, aad, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	id, key, err := c.activeKey(db, tenant)
+	if err != nil {
+		return "", err
+	}
+	data, err := encrypt.Seal(key, []byte(plaintext), []byte(aad))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d:%s", encryptedPrefix, id, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// activeKey returns the tenant's active data key, creating one on first use.
+func (c *StateCipher) activeKey(db *gorm.DB, tenant string) (int64, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var dk models.DataKey
+	result := db.Where("tenant = ? AND active = ?", tenant, true).Order("id DESC").Limit(1).Find(&dk)
+	if result.Error != nil {
+		return 0, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		created, err := c.createDataKey(db, tenant)
+		if err != nil {
+			return 0, nil, err
+		}
+		return created.ID, c.keys[created.ID], nil
+	}
+	key, err := c.unwrap(&dk)
+	if err != nil {
+		return 0, nil, err
+	}
+	return dk.ID, key, nil
+}
+
+// dataKey returns the data key by ID, active or retired.
+func (c *StateCipher) dataKey(db *gorm.DB, id int64) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[id]; ok {
+		return key, nil
+	}
+	var dk models.DataKey
+	if err := db.First(&dk, id).Error; err != nil {
+		return nil, fmt.Errorf("data key %d not found: %w", id, err)
+	}
+	return c.unwrap(&dk)
+}
+
+func (c *StateCipher) newDataKey(db *gorm.DB, tenant string) (*models.DataKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := db.Model(&models.DataKey{}).Where("tenant = ? AND active = ?", tenant, true).Update("active", false).Error
+	if err != nil {
+		return nil, err
+	}
+	return c.createDataKey(db, tenant)
+}
+
+// createDataKey must be called with c.mu held.
+func (c *StateCipher) createDataKey(db *gorm.DB, tenant string) (*models.DataKey, error) {
+	if c.keyring == nil {
+		return nil, fmt.Errorf("master key not configured")
+	}
+	key, err := encrypt.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := c.keyring.WrapKey(key)
+	if err != nil {
+		return nil, err
+	}
+	dk := &models.DataKey{
+		Tenant:     tenant,
+		KeyID:      keyID,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		Active:     true,
+	}
+	if err := db.Create(dk).Error; err != nil {
+		return nil, err
+	}
+	c.keys[dk.ID] = key
+	return dk, nil
+}
+
+// unwrap must be called with c.mu held.
+func (c *StateCipher) unwrap(dk *models.DataKey) ([]byte, error) {
+	if key, ok := c.keys[dk.ID]; ok {
+		return key, nil
+	}
+	if c.keyring == nil {
+		return nil, fmt.Errorf("master key not configured")
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(dk.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("data key %d: invalid wrapped key: %w", dk.ID, err)
+	}
+	key, err := c.keyring.UnwrapKey(dk.KeyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("data key %d: %w", dk.ID, err)
+	}
+	c.keys[dk.ID] = key
+	return key, nil
+}
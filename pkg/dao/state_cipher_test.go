@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/encrypt"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateCipher_EncryptDecrypt(t *testing.T) {
+	keyring, _ := newTestKeyring(t)
+	cipher := NewStateCipher(setupSQLiteDB(t), keyring)
+
+	sealed, err := cipher.Encrypt("team-a", "aad", `{"password":"p@ss"}`)
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(sealed))
+	assert.False(t, strings.Contains(sealed, "p@ss"))
+
+	plain, err := cipher.Decrypt("aad", sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"password":"p@ss"}`, plain)
+
+	_, err = cipher.Decrypt("other", sealed)
+	assert.Error(t, err)
+
+	// 未加密的历史数据原样返回
+	plain, err = cipher.Decrypt("aad", `{"version":4}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"version":4}`, plain)
+
+	empty, err := cipher.Encrypt("team-a", "aad", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", empty)
+}
+
+func TestStateCipher_PerTenantKeys(t *testing.T) {
+	keyring, _ := newTestKeyring(t)
+	db := setupSQLiteDB(t)
+	cipher := NewStateCipher(db, keyring)
+
+	cipher.Encrypt("team-a", "aad", "a")
+	cipher.Encrypt("team-b", "aad", "b")
+	cipher.Encrypt("team-a", "aad", "a2")
+
+	var keys []models.DataKey
+	db.Find(&keys)
+	assert.Len(t, keys, 2)
+
+	old, _ := cipher.Encrypt("team-a", "aad", "old")
+	_, err := cipher.NewDataKey("team-a")
+	assert.NoError(t, err)
+
+	var active int64
+	db.Model(&models.DataKey{}).Where("tenant = ? AND active = ?", "team-a", true).Count(&active)
+	assert.Equal(t, int64(1), active)
+
+	// 轮换后旧数据密钥仍可解密历史密文
+	plain, err := NewStateCipher(db, keyring).Decrypt("aad", old)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", plain)
+}
+
+func TestStateCipher_Rotate(t *testing.T) {
+	keyring, oldKey := newTestKeyring(t)
+	db := setupSQLiteDB(t)
+	sealed, _ := NewStateCipher(db, keyring).Encrypt("", "aad", "state")
+
+	newKey, _ := encrypt.GenerateKey()
+	rotatedRing, _ := encrypt.NewKeyring(newKey, oldKey)
+	rotated, err := NewStateCipher(db, rotatedRing).Rotate()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rotated)
+
+	onlyNew, _ := encrypt.NewKeyring(newKey)
+	plain, err := NewStateCipher(db, onlyNew).Decrypt("aad", sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "state", plain)
+}
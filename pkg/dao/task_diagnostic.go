@@ -0,0 +1,66 @@
+package dao
+
+import (
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// TaskDiagnosticDAO provides task diagnostic data access operations.
+type TaskDiagnosticDAO struct {
+	db *gorm.DB
+}
+
+// NewTaskDiagnosticDAO creates a new task diagnostic DAO.
+func NewTaskDiagnosticDAO(db *gorm.DB) *TaskDiagnosticDAO {
+	db.AutoMigrate(&models.TaskDiagnostic{})
+	return &TaskDiagnosticDAO{db: db}
+}
+
+// Save replaces the diagnostics of a task.
+func (d *TaskDiagnosticDAO) Save(taskID string, diagnostics []models.TaskDiagnostic) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskDiagnostic{}).Error; err != nil {
+			return err
+		}
+		if len(diagnostics) == 0 {
+			return nil
+		}
+		for i := range diagnostics {
+			diagnostics[i].TaskID = taskID
+		}
+		return tx.Create(&diagnostics).Error
+	})
+}
+
+// ListByTask lists diagnostics of a task in report order.
+// An empty severity lists all diagnostics.
+func (d *TaskDiagnosticDAO) ListByTask(taskID, severity string) ([]models.TaskDiagnostic, error) {
+	var diagnostics []models.TaskDiagnostic
+	query := d.db.Where("task_id = ?", taskID)
+	if severity != "" {
+		query = query.Where("severity = ?", severity)
+	}
+	result := query.Order("id ASC").Find(&diagnostics)
+	return diagnostics, result.Error
+}
+
+// CountBySeverity counts diagnostics of a task by severity.
+func (d *TaskDiagnosticDAO) CountBySeverity(taskID string) (map[string]int64, error) {
+	var rows []struct {
+		Severity string
+		Count    int64
+	}
+	result := d.db.Model(&models.TaskDiagnostic{}).
+		Select("severity, COUNT(*) AS count").
+		Where("task_id = ?", taskID).
+		Group("severity").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Severity] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,38 @@
+package dao
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskDiagnosticDAO(t *testing.T) {
+	dao := NewTaskDiagnosticDAO(setupSQLiteDB(t))
+
+	err := dao.Save("task-1", []models.TaskDiagnostic{
+		{Severity: models.SeverityWarning, Summary: "Argument is deprecated", Address: "aws_s3_bucket.logs"},
+		{Severity: models.SeverityError, Summary: "quota exceeded", Filename: "main.tf", Line: 12},
+		{Severity: models.SeverityWarning, Summary: "Version constraint is deprecated"},
+	})
+	assert.NoError(t, err)
+	dao.Save("task-2", []models.TaskDiagnostic{{Severity: models.SeverityError, Summary: "other"}})
+
+	all, err := dao.ListByTask("task-1", "")
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, "Argument is deprecated", all[0].Summary)
+
+	warnings, err := dao.ListByTask("task-1", models.SeverityWarning)
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 2)
+
+	counts, err := dao.CountBySeverity("task-1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"error": 1, "warning": 2}, counts)
+
+	// 重试时覆盖上一次的诊断
+	assert.NoError(t, dao.Save("task-1", nil))
+	all, _ = dao.ListByTask("task-1", "")
+	assert.Empty(t, all)
+}
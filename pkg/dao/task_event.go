@@ -0,0 +1,88 @@
+package dao
+
+import (
+	"time"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// TaskEventDAO provides task event data access operations.
+type TaskEventDAO struct {
+	db *gorm.DB
+}
+
+// NewTaskEventDAO creates a new task event DAO.
+func NewTaskEventDAO(db *gorm.DB) *TaskEventDAO {
+	db.AutoMigrate(&models.TaskEvent{})
+	return &TaskEventDAO{db: db}
+}
+
+// Record appends an event to the timeline of its task.
+func (d *TaskEventDAO) Record(event *models.TaskEvent) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	return d.db.Create(event).Error
+}
+
+// ListByTask lists the timeline of a task in order.
+func (d *TaskEventDAO) ListByTask(taskID string) ([]models.TaskEvent, error) {
+	var events []models.TaskEvent
+	result := d.db.Where("task_id = ?", taskID).Order("timestamp ASC, id ASC").Find(&events)
+	return events, result.Error
+}
+
+// Durations returns the time a task spent in each state and phase.
+func (d *TaskEventDAO) Durations(taskID string) (map[string]time.Duration, error) {
+	events, err := d.ListByTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	durations := make(map[string]time.Duration)
+	addDurations(durations, events)
+	return durations, nil
+}
+
+// SumDurations returns the total time tasks with events since the given time
+// spent in each state and phase, e.g. lock_wait versus apply.
+func (d *TaskEventDAO) SumDurations(since time.Time) (map[string]time.Duration, error) {
+	var events []models.TaskEvent
+	result := d.db.Where("task_id IN (?)",
+		d.db.Model(&models.TaskEvent{}).Distinct("task_id").Where("timestamp >= ?", since)).
+		Order("task_id ASC, timestamp ASC, id ASC").
+		Find(&events)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	durations := make(map[string]time.Duration)
+	start := 0
+	for i := 1; i <= len(events); i++ {
+		if i == len(events) || events[i].TaskID != events[start].TaskID {
+			addDurations(durations, events[start:i])
+			start = i
+		}
+	}
+	return durations, nil
+}
+
+// addDurations adds the duration of each event of one task. State and phase events
+// are timed separately: an event lasts until the next event of the same kind, the
+// last event of each kind until the last event of the task.
+func addDurations(durations map[string]time.Duration, events []models.TaskEvent) {
+	if len(events) == 0 {
+		return
+	}
+	last := make(map[string]models.TaskEvent)
+	for _, event := range events {
+		if prev, ok := last[event.Kind]; ok {
+			durations[prev.Name] += event.Timestamp.Sub(prev.Timestamp)
+		}
+		last[event.Kind] = event
+	}
+	end := events[len(events)-1].Timestamp
+	for _, event := range last {
+		durations[event.Name] += end.Sub(event.Timestamp)
+	}
+}
@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskEventDAO(t *testing.T) {
+	dao := NewTaskEventDAO(setupSQLiteDB(t))
+
+	start := time.Now().Add(-time.Hour)
+	record := func(taskID, kind, name string, offset time.Duration) {
+		assert.NoError(t, dao.Record(&models.TaskEvent{TaskID: taskID, Kind: kind, Name: name, Timestamp: start.Add(offset)}))
+	}
+	record("task-1", models.TaskEventPhase, models.TaskPhaseQueued, 0)
+	record("task-1", models.TaskEventPhase, models.TaskPhaseLockWait, time.Second)
+	record("task-1", models.TaskEventState, "running", 11*time.Second)
+	record("task-1", models.TaskEventPhase, "init", 12*time.Second)
+	record("task-1", models.TaskEventPhase, "apply", 20*time.Second)
+	record("task-1", models.TaskEventPhase, models.TaskPhaseCompleted, 80*time.Second)
+	record("task-1", models.TaskEventState, "success", 80*time.Second)
+	record("task-2", models.TaskEventPhase, models.TaskPhaseLockWait, 90*time.Second)
+	record("task-2", models.TaskEventState, "failed", 95*time.Second)
+
+	events, err := dao.ListByTask("task-1")
+	assert.NoError(t, err)
+	assert.Len(t, events, 7)
+	assert.Equal(t, "success", events[6].Name)
+
+	durations, err := dao.Durations("task-1")
+	assert.NoError(t, err)
+	// 状态与阶段分别计时，running 持续到下一个状态事件
+	assert.Equal(t, 11*time.Second, durations[models.TaskPhaseLockWait])
+	assert.Equal(t, 8*time.Second, durations["init"])
+	assert.Equal(t, time.Minute, durations["apply"])
+	assert.Equal(t, 69*time.Second, durations["running"])
+	assert.Zero(t, durations["success"])
+
+	total, err := dao.SumDurations(start)
+	assert.NoError(t, err)
+	assert.Equal(t, 16*time.Second, total[models.TaskPhaseLockWait])
+	assert.Equal(t, time.Minute, total["apply"])
+
+	// 只统计 since 之后有事件的任务
+	total, _ = dao.SumDurations(start.Add(85 * time.Second))
+	assert.Equal(t, 5*time.Second, total[models.TaskPhaseLockWait])
+	assert.Zero(t, total["apply"])
+}
@@ -0,0 +1,42 @@
+package dao
+
+import (
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// TaskLogDAO provides task log data access operations.
+type TaskLogDAO struct {
+	db *gorm.DB
+}
+
+// NewTaskLogDAO creates a new task log DAO.
+func NewTaskLogDAO(db *gorm.DB) *TaskLogDAO {
+	db.AutoMigrate(&models.TaskLog{})
+	return &TaskLogDAO{db: db}
+}
+
+// Append appends a log entry.
+func (d *TaskLogDAO) Append(log *models.TaskLog) error {
+	return d.db.Create(log).Error
+}
+
+// ListSince lists log entries of a task with seq greater than seq, in order.
+func (d *TaskLogDAO) ListSince(taskID string, seq int64) ([]models.TaskLog, error) {
+	var logs []models.TaskLog
+	result := d.db.Where("task_id = ? AND seq > ?", taskID, seq).Order("seq ASC").Find(&logs)
+	return logs, result.Error
+}
+
+// LastSeq returns the highest seq of a task, 0 if none.
+func (d *TaskLogDAO) LastSeq(taskID string) (int64, error) {
+	var seq int64
+	result := d.db.Model(&models.TaskLog{}).Where("task_id = ?", taskID).
+		Select("COALESCE(MAX(seq), 0)").Scan(&seq)
+	return seq, result.Error
+}
+
+// DeleteByTask deletes all log entries of a task.
+func (d *TaskLogDAO) DeleteByTask(taskID string) error {
+	return d.db.Where("task_id = ?", taskID).Delete(&models.TaskLog{}).Error
+}
@@ -0,0 +1,38 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskLogDAO(t *testing.T) {
+	dao := NewTaskLogDAO(setupSQLiteDB(t))
+
+	seq, err := dao.LastSeq("task-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), seq)
+
+	for i := int64(1); i <= 3; i++ {
+		assert.NoError(t, dao.Append(&models.TaskLog{TaskID: "task-1", Seq: i, Type: "log", Data: `"line"`, Time: time.Now()}))
+	}
+	dao.Append(&models.TaskLog{TaskID: "task-2", Seq: 1, Type: "log", Time: time.Now()})
+
+	// 同一任务的 seq 唯一
+	assert.Error(t, dao.Append(&models.TaskLog{TaskID: "task-1", Seq: 3, Type: "log", Time: time.Now()}))
+
+	seq, err = dao.LastSeq("task-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), seq)
+
+	logs, err := dao.ListSince("task-1", 1)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 2)
+	assert.Equal(t, int64(2), logs[0].Seq)
+
+	assert.NoError(t, dao.DeleteByTask("task-1"))
+	logs, _ = dao.ListSince("task-1", 0)
+	assert.Empty(t, logs)
+}
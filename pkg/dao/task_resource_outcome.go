@@ -0,0 +1,40 @@
+package dao
+
+import (
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// TaskResourceOutcomeDAO provides per-resource task outcome access operations.
+type TaskResourceOutcomeDAO struct {
+	db *gorm.DB
+}
+
+// NewTaskResourceOutcomeDAO creates a new task resource outcome DAO.
+func NewTaskResourceOutcomeDAO(db *gorm.DB) *TaskResourceOutcomeDAO {
+	db.AutoMigrate(&models.TaskResourceOutcome{})
+	return &TaskResourceOutcomeDAO{db: db}
+}
+
+// Save replaces the outcomes of a task.
+func (d *TaskResourceOutcomeDAO) Save(taskID string, outcomes []models.TaskResourceOutcome) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskResourceOutcome{}).Error; err != nil {
+			return err
+		}
+		if len(outcomes) == 0 {
+			return nil
+		}
+		for i := range outcomes {
+			outcomes[i].TaskID = taskID
+		}
+		return tx.Create(&outcomes).Error
+	})
+}
+
+// ListByTask lists outcomes of a task ordered by address.
+func (d *TaskResourceOutcomeDAO) ListByTask(taskID string) ([]models.TaskResourceOutcome, error) {
+	var outcomes []models.TaskResourceOutcome
+	result := d.db.Where("task_id = ?", taskID).Order("address ASC").Find(&outcomes)
+	return outcomes, result.Error
+}
@@ -0,0 +1,36 @@
+package dao
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskResourceOutcomeDAO(t *testing.T) {
+	dao := NewTaskResourceOutcomeDAO(setupSQLiteDB(t))
+
+	err := dao.Save("task-1", []models.TaskResourceOutcome{
+		{Address: "aws_instance.web", Outcome: "failed", Error: "quota exceeded"},
+		{Address: "aws_eip.web", Outcome: "created", CloudID: "eipalloc-1"},
+	})
+	assert.NoError(t, err)
+
+	outcomes, err := dao.ListByTask("task-1")
+	assert.NoError(t, err)
+	assert.Len(t, outcomes, 2)
+	assert.Equal(t, "aws_eip.web", outcomes[0].Address)
+	assert.Equal(t, "task-1", outcomes[0].TaskID)
+
+	// 重试时覆盖上一次的结果
+	assert.NoError(t, dao.Save("task-1", []models.TaskResourceOutcome{
+		{Address: "aws_instance.web", Outcome: "created"},
+	}))
+	outcomes, _ = dao.ListByTask("task-1")
+	assert.Len(t, outcomes, 1)
+	assert.Equal(t, "created", outcomes[0].Outcome)
+
+	assert.NoError(t, dao.Save("task-1", nil))
+	outcomes, _ = dao.ListByTask("task-1")
+	assert.Empty(t, outcomes)
+}
@@ -1,23 +1,40 @@
 package dao
 
 import (
+	"errors"
+	"fmt"
+
 	models "github.com/cylonchau/prism/pkg/model"
 	"gorm.io/gorm"
 )
 
+// ErrInvalidTransition is returned when the resource lifecycle does not allow a status change.
+var ErrInvalidTransition = errors.New("invalid resource status transition")
+
 // TerraformResourceDAO provides terraform resource data access operations.
 type TerraformResourceDAO struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cipher *StateCipher // 为空时状态以明文存储
 }
 
 // NewTerraformResourceDAO creates a new terraform resource DAO.
 func NewTerraformResourceDAO(db *gorm.DB) *TerraformResourceDAO {
-	db.AutoMigrate(&models.TerraformResource{})
+	db.AutoMigrate(&models.TerraformResource{}, &models.ResourceTransition{})
 	return &TerraformResourceDAO{db: db}
 }
 
+// SetStateCipher enables transparent encryption of tf_state and tf_plan.
+func (d *TerraformResourceDAO) SetStateCipher(cipher *StateCipher) {
+	d.cipher = cipher
+}
+
 // Create creates a new terraform resource.
 func (d *TerraformResourceDAO) Create(resource *models.TerraformResource) error {
+	restore, err := d.seal(d.db, resource)
+	if err != nil {
+		return err
+	}
+	defer restore()
 	return d.db.Create(resource).Error
 }
 
@@ -28,6 +45,9 @@ func (d *TerraformResourceDAO) Get(id int64) (*models.TerraformResource, error)
 	if result.Error != nil {
 		return nil, result.Error
 	}
+	if err := d.open(d.db, &resource); err != nil {
+		return nil, err
+	}
 	return &resource, nil
 }
 
@@ -35,46 +55,245 @@ func (d *TerraformResourceDAO) Get(id int64) (*models.TerraformResource, error)
 func (d *TerraformResourceDAO) ListByProvider(provider string) ([]models.TerraformResource, error) {
 	var resources []models.TerraformResource
 	result := d.db.Where("provider = ?", provider).Find(&resources)
-	return resources, result.Error
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return resources, d.openAll(d.db, resources)
 }
 
 // ListByType lists resources by type.
 func (d *TerraformResourceDAO) ListByType(resourceType string) ([]models.TerraformResource, error) {
 	var resources []models.TerraformResource
 	result := d.db.Where("resource_type = ?", resourceType).Find(&resources)
-	return resources, result.Error
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return resources, d.openAll(d.db, resources)
 }
 
 // ListByRegion lists resources by region.
 func (d *TerraformResourceDAO) ListByRegion(regionID string) ([]models.TerraformResource, error) {
 	var resources []models.TerraformResource
 	result := d.db.Where("region_id = ?", regionID).Find(&resources)
-	return resources, result.Error
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return resources, d.openAll(d.db, resources)
 }
 
 // ListByStatus lists resources by status.
 func (d *TerraformResourceDAO) ListByStatus(status string) ([]models.TerraformResource, error) {
 	var resources []models.TerraformResource
 	result := d.db.Where("status = ?", status).Find(&resources)
-	return resources, result.Error
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return resources, d.openAll(d.db, resources)
 }
 
-// Update updates resource.
+// Update updates resource. The status is left unchanged, it only moves through
+// UpdateStatus and Transition so the lifecycle cannot be bypassed.
 func (d *TerraformResourceDAO) Update(resource *models.TerraformResource) error {
-	return d.db.Save(resource).Error
+	restore, err := d.seal(d.db, resource)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return d.db.Omit("status").Save(resource).Error
 }
 
-// UpdateStatus updates resource status.
+// UpdateStatus moves a resource to status. It fails if the lifecycle does not
+// allow the transition from the current status.
 func (d *TerraformResourceDAO) UpdateStatus(id int64, status string) error {
-	return d.db.Model(&models.TerraformResource{}).Where("id = ?", id).Update("status", status).Error
+	var resource models.TerraformResource
+	if err := d.db.Select("id", "status").First(&resource, id).Error; err != nil {
+		return err
+	}
+	event, ok := models.ResourceEventFor(resource.Status, status)
+	if !ok {
+		return fmt.Errorf("resource %d: %q to %q: %w", id, resource.Status, status, ErrInvalidTransition)
+	}
+	_, err := d.Transition(id, event, "", "")
+	return err
+}
+
+// Transition applies a lifecycle event to a resource and records the transition.
+// taskID and reason are kept in the history. It returns the new status.
+func (d *TerraformResourceDAO) Transition(id int64, event, taskID, reason string) (string, error) {
+	var to string
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		var resource models.TerraformResource
+		if err := tx.Select("id", "status").First(&resource, id).Error; err != nil {
+			return err
+		}
+		var ok bool
+		if to, ok = models.ResourceTransitionTo(resource.Status, event); !ok {
+			return fmt.Errorf("resource %d: event %q in status %q: %w", id, event, resource.Status, ErrInvalidTransition)
+		}
+
+		// 以当前状态为条件更新，避免并发转换互相覆盖
+		result := tx.Model(&models.TerraformResource{}).
+			Where("id = ? AND status = ?", id, resource.Status).
+			Update("status", to)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("resource %d: status changed concurrently", id)
+		}
+		return tx.Create(&models.ResourceTransition{
+			ResourceID: id,
+			FromStatus: resource.Status,
+			ToStatus:   to,
+			Event:      event,
+			TaskID:     taskID,
+			Reason:     reason,
+		}).Error
+	})
+	return to, err
+}
+
+// ListTransitions lists the status history of a resource, oldest first.
+func (d *TerraformResourceDAO) ListTransitions(id int64) ([]models.ResourceTransition, error) {
+	var transitions []models.ResourceTransition
+	result := d.db.Where("resource_id = ?", id).Order("id ASC").Find(&transitions)
+	return transitions, result.Error
 }
 
 // UpdateTfState updates tfstate.
 func (d *TerraformResourceDAO) UpdateTfState(id int64, tfState string) error {
-	return d.db.Model(&models.TerraformResource{}).Where("id = ?", id).Update("tf_state", tfState).Error
+	return d.updateSealed(id, "tf_state", tfState)
+}
+
+// UpdateTfPlan updates the saved plan.
+func (d *TerraformResourceDAO) UpdateTfPlan(id int64, tfPlan string) error {
+	return d.updateSealed(id, "tf_plan", tfPlan)
+}
+
+// ListTenants lists distinct tenants that own resources.
+func (d *TerraformResourceDAO) ListTenants() ([]string, error) {
+	var tenants []string
+	result := d.db.Model(&models.TerraformResource{}).Distinct("tenant").Pluck("tenant", &tenants)
+	return tenants, result.Error
+}
+
+// Rekey generates a new data key for tenant and re-encrypts the state and plan
+// of all its resources, including rows stored before encryption was enabled.
+// It returns the number of resources re-encrypted.
+func (d *TerraformResourceDAO) Rekey(tenant string) (int, error) {
+	if d.cipher == nil {
+		return 0, fmt.Errorf("state encryption not configured")
+	}
+
+	rekeyed := 0
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		var resources []models.TerraformResource
+		err := tx.Select("id", "tenant", "tf_state", "tf_plan").
+			Where("tenant = ?", tenant).Find(&resources).Error
+		if err != nil {
+			return err
+		}
+		if err := d.openAll(tx, resources); err != nil {
+			return err
+		}
+		if _, err := d.cipher.newDataKey(tx, tenant); err != nil {
+			return err
+		}
+
+		for i := range resources {
+			if resources[i].TfState == "" && resources[i].TfPlan == "" {
+				continue
+			}
+			if _, err := d.seal(tx, &resources[i]); err != nil {
+				return err
+			}
+			err = tx.Model(&models.TerraformResource{}).Where("id = ?", resources[i].ID).
+				Updates(map[string]interface{}{
+					"tf_state": resources[i].TfState,
+					"tf_plan":  resources[i].TfPlan,
+				}).Error
+			if err != nil {
+				return err
+			}
+			rekeyed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rekeyed, nil
 }
 
 // Delete deletes resource.
 func (d *TerraformResourceDAO) Delete(id int64) error {
 	return d.db.Delete(&models.TerraformResource{}, id).Error
 }
+
+// updateSealed encrypts value with the resource tenant's data key and updates column.
+func (d *TerraformResourceDAO) updateSealed(id int64, column, value string) error {
+	if d.cipher != nil {
+		var resource models.TerraformResource
+		if err := d.db.Select("id", "tenant").First(&resource, id).Error; err != nil {
+			return err
+		}
+		sealed, err := d.cipher.encrypt(d.db, resource.Tenant, stateAAD(id, column), value)
+		if err != nil {
+			return err
+		}
+		value = sealed
+	}
+	return d.db.Model(&models.TerraformResource{}).Where("id = ?", id).Update(column, value).Error
+}
+
+// seal encrypts the state columns of resource in place and returns a function
+// restoring the plaintext, so callers keep working with decrypted values.
+func (d *TerraformResourceDAO) seal(db *gorm.DB, resource *models.TerraformResource) (func(), error) {
+	state, plan := resource.TfState, resource.TfPlan
+	restore := func() {
+		resource.TfState, resource.TfPlan = state, plan
+	}
+	if d.cipher == nil {
+		return restore, nil
+	}
+
+	var err error
+	if resource.TfState, err = d.cipher.encrypt(db, resource.Tenant, stateAAD(resource.ID, "tf_state"), state); err != nil {
+		restore()
+		return nil, fmt.Errorf("failed to encrypt tf_state: %w", err)
+	}
+	if resource.TfPlan, err = d.cipher.encrypt(db, resource.Tenant, stateAAD(resource.ID, "tf_plan"), plan); err != nil {
+		restore()
+		return nil, fmt.Errorf("failed to encrypt tf_plan: %w", err)
+	}
+	return restore, nil
+}
+
+// open decrypts the state columns of resource in place.
+func (d *TerraformResourceDAO) open(db *gorm.DB, resource *models.TerraformResource) error {
+	if d.cipher == nil {
+		return nil
+	}
+	var err error
+	if resource.TfState, err = d.cipher.decrypt(db, stateAAD(resource.ID, "tf_state"), resource.TfState); err != nil {
+		return err
+	}
+	if resource.TfPlan, err = d.cipher.decrypt(db, stateAAD(resource.ID, "tf_plan"), resource.TfPlan); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *TerraformResourceDAO) openAll(db *gorm.DB, resources []models.TerraformResource) error {
+	for i := range resources {
+		if err := d.open(db, &resources[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stateAAD 密文绑定资源 ID 与列名，防止在记录间挪用
+func stateAAD(id int64, column string) string {
+	return fmt.Sprintf("terraform_resource/%d/%s", id, column)
+}
@@ -81,3 +81,39 @@ func (d *TerraformResourceAttributeDAO) Delete(id int64) error {
 func (d *TerraformResourceAttributeDAO) DeleteByResourceID(resourceID int64) error {
 	return d.db.Where("resource_id = ?", resourceID).Delete(&models.TerraformResourceAttribute{}).Error
 }
+
+// Replace replaces all attributes of a resource.
+func (d *TerraformResourceAttributeDAO) Replace(resourceID int64, attrs []models.TerraformResourceAttribute) error {
+	return d.replace(resourceID, attrs, "resource_id = ?", resourceID)
+}
+
+// ReplaceResourceLevel replaces the resource level attributes of a resource, such as
+// the outputs of its last apply, and keeps its instance attributes.
+func (d *TerraformResourceAttributeDAO) ReplaceResourceLevel(resourceID int64, attrs []models.TerraformResourceAttribute) error {
+	for i := range attrs {
+		attrs[i].ResourceIndex = models.ResourceLevelIndex
+	}
+	return d.replace(resourceID, attrs, "resource_id = ? AND resource_index = ?", resourceID, models.ResourceLevelIndex)
+}
+
+// ReplaceMapped replaces the instance attributes that have a mapped name, such as
+// those indexed by mapping rules, and keeps the other attributes of the resource.
+func (d *TerraformResourceAttributeDAO) ReplaceMapped(resourceID int64, attrs []models.TerraformResourceAttribute) error {
+	return d.replace(resourceID, attrs, "resource_id = ? AND resource_index >= 0 AND mapped_name <> ''", resourceID)
+}
+
+// replace deletes the attributes matched by query and creates attrs in one transaction.
+func (d *TerraformResourceAttributeDAO) replace(resourceID int64, attrs []models.TerraformResourceAttribute, query string, args ...interface{}) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(query, args...).Delete(&models.TerraformResourceAttribute{}).Error; err != nil {
+			return err
+		}
+		if len(attrs) == 0 {
+			return nil
+		}
+		for i := range attrs {
+			attrs[i].ResourceId = resourceID
+		}
+		return tx.CreateInBatches(attrs, 100).Error
+	})
+}
@@ -0,0 +1,50 @@
+package dao
+
+import (
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// TerraformResourceParamDAO provides resource param data access operations.
+type TerraformResourceParamDAO struct {
+	db *gorm.DB
+}
+
+// NewTerraformResourceParamDAO creates a new resource param DAO.
+func NewTerraformResourceParamDAO(db *gorm.DB) *TerraformResourceParamDAO {
+	db.AutoMigrate(&models.TerraformResourceParam{})
+	return &TerraformResourceParamDAO{db: db}
+}
+
+// ListByResourceID lists params of a resource.
+func (d *TerraformResourceParamDAO) ListByResourceID(resourceID int64) ([]models.TerraformResourceParam, error) {
+	var params []models.TerraformResourceParam
+	result := d.db.Where("resource_id = ?", resourceID).Order("param_name ASC").Find(&params)
+	return params, result.Error
+}
+
+// Replace replaces all params of a resource.
+func (d *TerraformResourceParamDAO) Replace(resourceID int64, params []models.TerraformResourceParam) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		return replaceParams(tx, resourceID, params)
+	})
+}
+
+// DeleteByResourceID deletes all params of a resource.
+func (d *TerraformResourceParamDAO) DeleteByResourceID(resourceID int64) error {
+	return d.db.Where("resource_id = ?", resourceID).Delete(&models.TerraformResourceParam{}).Error
+}
+
+func replaceParams(tx *gorm.DB, resourceID int64, params []models.TerraformResourceParam) error {
+	if err := tx.Where("resource_id = ?", resourceID).Delete(&models.TerraformResourceParam{}).Error; err != nil {
+		return err
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	for i := range params {
+		params[i].ID = 0
+		params[i].ResourceID = resourceID
+	}
+	return tx.Create(&params).Error
+}
@@ -0,0 +1,34 @@
+package dao
+
+import (
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerraformResourceParamDAO(t *testing.T) {
+	dao := NewTerraformResourceParamDAO(setupSQLiteDB(t))
+
+	assert.NoError(t, dao.Replace(1, []models.TerraformResourceParam{
+		{ParamName: "region", ParamValue: "us-east-1"},
+		{ParamName: "cidr", ParamValue: "10.0.0.0/16"},
+	}))
+	assert.NoError(t, dao.Replace(2, []models.TerraformResourceParam{{ParamName: "cidr", ParamValue: "10.1.0.0/16"}}))
+
+	params, err := dao.ListByResourceID(1)
+	assert.NoError(t, err)
+	assert.Len(t, params, 2)
+	assert.Equal(t, "cidr", params[0].ParamName)
+
+	assert.NoError(t, dao.Replace(1, []models.TerraformResourceParam{{ParamName: "cidr", ParamValue: "10.2.0.0/16"}}))
+	params, _ = dao.ListByResourceID(1)
+	assert.Len(t, params, 1)
+	assert.Equal(t, "10.2.0.0/16", params[0].ParamValue)
+
+	assert.NoError(t, dao.DeleteByResourceID(1))
+	params, _ = dao.ListByResourceID(1)
+	assert.Empty(t, params)
+	params, _ = dao.ListByResourceID(2)
+	assert.Len(t, params, 1)
+}
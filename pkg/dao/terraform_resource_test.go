@@ -2,6 +2,7 @@ package dao
 
 import (
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -49,19 +50,68 @@ func TestTerraformResourceDAO_Get(t *testing.T) {
 }
 
 func TestTerraformResourceDAO_UpdateStatus(t *testing.T) {
-	db, mock, cleanup := setupMockDB(t)
-	defer cleanup()
+	dao := NewTerraformResourceDAO(setupSQLiteDB(t))
+	assert.NoError(t, dao.Create(&models.TerraformResource{ID: 1, Provider: "aws", ResourceType: "ec2"}))
 
-	dao := NewTerraformResourceDAO(db)
+	err := dao.UpdateStatus(1, models.ResourceStatusProvisioning)
+	assert.NoError(t, err)
 
-	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE `terraform_resource` SET `status`=? WHERE id = ?")).
-		WithArgs("running", int64(1)).
-		WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectCommit()
+	// 生命周期之外的状态与不合法的转换被拒绝
+	assert.Error(t, dao.UpdateStatus(1, "running"))
+	assert.Error(t, dao.UpdateStatus(1, models.ResourceStatusDestroyed))
+
+	resource, _ := dao.Get(1)
+	assert.Equal(t, models.ResourceStatusProvisioning, resource.Status)
+}
+
+func TestTerraformResourceDAO_UpdateKeepsStatus(t *testing.T) {
+	dao := NewTerraformResourceDAO(setupSQLiteDB(t))
+	assert.NoError(t, dao.Create(&models.TerraformResource{ID: 1, Provider: "aws", ResourceType: "ec2"}))
+	assert.NoError(t, dao.UpdateStatus(1, models.ResourceStatusProvisioning))
+
+	// Update 不能绕过生命周期修改状态
+	resource, _ := dao.Get(1)
+	resource.TfConfig = "# updated"
+	resource.Status = models.ResourceStatusDestroyed
+	assert.NoError(t, dao.Update(resource))
 
-	err := dao.UpdateStatus(1, "running")
+	resource, _ = dao.Get(1)
+	assert.Equal(t, "# updated", resource.TfConfig)
+	assert.Equal(t, models.ResourceStatusProvisioning, resource.Status)
+}
+
+func TestTerraformResourceDAO_Transition(t *testing.T) {
+	dao := NewTerraformResourceDAO(setupSQLiteDB(t))
+	assert.NoError(t, dao.Create(&models.TerraformResource{ID: 1, Provider: "aws", ResourceType: "ec2"}))
+
+	steps := []struct {
+		event, expected string
+	}{
+		{models.ResourceEventApply, models.ResourceStatusProvisioning},
+		{models.ResourceEventComplete, models.ResourceStatusActive},
+		{models.ResourceEventDrift, models.ResourceStatusDrifted},
+		{models.ResourceEventApply, models.ResourceStatusUpdating},
+		{models.ResourceEventFail, models.ResourceStatusError},
+		{models.ResourceEventDestroy, models.ResourceStatusDestroying},
+		{models.ResourceEventComplete, models.ResourceStatusDestroyed},
+	}
+	for _, step := range steps {
+		status, err := dao.Transition(1, step.event, "task-1", "")
+		assert.NoError(t, err, step.event)
+		assert.Equal(t, step.expected, status)
+	}
+
+	_, err := dao.Transition(1, models.ResourceEventComplete, "task-2", "")
+	assert.Error(t, err)
+	_, err = dao.Transition(2, models.ResourceEventApply, "task-2", "")
+	assert.Error(t, err)
+
+	transitions, err := dao.ListTransitions(1)
 	assert.NoError(t, err)
+	assert.Len(t, transitions, len(steps))
+	assert.Equal(t, models.ResourceStatusPending, transitions[0].FromStatus)
+	assert.Equal(t, models.ResourceStatusError, transitions[5].FromStatus)
+	assert.Equal(t, "task-1", transitions[6].TaskID)
 }
 
 func TestTerraformResourceDAO_ListByProvider(t *testing.T) {
@@ -98,3 +148,71 @@ func TestTerraformResourceDAO_Delete(t *testing.T) {
 	err := dao.Delete(1)
 	assert.NoError(t, err)
 }
+
+func TestTerraformResourceDAO_EncryptedState(t *testing.T) {
+	keyring, _ := newTestKeyring(t)
+	db := setupSQLiteDB(t)
+	dao := NewTerraformResourceDAO(db)
+	dao.SetStateCipher(NewStateCipher(db, keyring))
+
+	resource := &models.TerraformResource{ID: 1, Provider: "aws", ResourceType: "db", Tenant: "team-a", TfState: `{"password":"p@ss"}`}
+	assert.NoError(t, dao.Create(resource))
+	assert.Equal(t, `{"password":"p@ss"}`, resource.TfState)
+
+	var raw models.TerraformResource
+	db.First(&raw, 1)
+	assert.True(t, IsEncrypted(raw.TfState))
+	assert.False(t, strings.Contains(raw.TfState, "p@ss"))
+
+	got, err := dao.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"password":"p@ss"}`, got.TfState)
+
+	assert.NoError(t, dao.UpdateTfPlan(1, "plan-bytes"))
+	list, err := dao.ListByProvider("aws")
+	assert.NoError(t, err)
+	assert.Equal(t, "plan-bytes", list[0].TfPlan)
+
+	// 密文挪用到其他记录无法解密
+	db.Create(&models.TerraformResource{ID: 2, Provider: "aws", ResourceType: "db", TfState: raw.TfState})
+	_, err = dao.Get(2)
+	assert.Error(t, err)
+}
+
+func TestTerraformResourceDAO_Rekey(t *testing.T) {
+	keyring, _ := newTestKeyring(t)
+	db := setupSQLiteDB(t)
+	dao := NewTerraformResourceDAO(db)
+
+	// 启用加密前写入的明文状态
+	assert.NoError(t, dao.Create(&models.TerraformResource{ID: 1, Provider: "aws", ResourceType: "vpc", Tenant: "team-a", TfState: "legacy"}))
+
+	_, err := dao.Rekey("team-a")
+	assert.Error(t, err)
+
+	dao.SetStateCipher(NewStateCipher(db, keyring))
+	got, err := dao.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy", got.TfState)
+
+	rekeyed, err := dao.Rekey("team-a")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rekeyed)
+
+	var raw models.TerraformResource
+	db.First(&raw, 1)
+	assert.True(t, IsEncrypted(raw.TfState))
+
+	first := raw.TfState
+	dao.Rekey("team-a")
+	db.First(&raw, 1)
+	assert.NotEqual(t, first, raw.TfState)
+
+	got, err = dao.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy", got.TfState)
+
+	tenants, err := dao.ListTenants()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a"}, tenants)
+}
@@ -0,0 +1,145 @@
+package encrypt
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MasterKeyEnv 主密钥环境变量，多个密钥以逗号分隔，第一个为当前主密钥
+const MasterKeyEnv = "PRISM_MASTER_KEY"
+
+// Envelope 信封加密结果：数据由随机数据密钥加密，数据密钥由主密钥加密
+type Envelope struct {
+	KeyID      string // 加密数据密钥所用的主密钥 ID
+	WrappedKey []byte // 被主密钥加密的数据密钥
+	Ciphertext []byte // 被数据密钥加密的数据
+}
+
+// Keyring 主密钥环：primary 用于加密，其余密钥仅用于解密轮换前的数据
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring 创建密钥环，第一个密钥为当前主密钥
+func NewKeyring(primary []byte, previous ...[]byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+	for i, key := range append([][]byte{primary}, previous...) {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("master key %d: invalid key size %d", i, len(key))
+		}
+		id := KeyID(key)
+		if i == 0 {
+			k.primary = id
+		}
+		k.keys[id] = key
+	}
+	return k, nil
+}
+
+// LoadKeyring 从文件加载密钥环，path 为空时读取 PRISM_MASTER_KEY 环境变量。
+// 每行（或逗号分隔）一个 base64 编码的 32 字节密钥，第一个为当前主密钥，# 开头为注释。
+func LoadKeyring(path string) (*Keyring, error) {
+	var content string
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read master key file: %w", err)
+		}
+		content = string(data)
+	} else {
+		content = os.Getenv(MasterKeyEnv)
+	}
+
+	var keys [][]byte
+	for _, line := range strings.FieldsFunc(content, func(r rune) bool { return r == '\n' || r == ',' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid master key encoding: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no master key configured (set --master-key-file or %s)", MasterKeyEnv)
+	}
+	return NewKeyring(keys[0], keys[1:]...)
+}
+
+// KeyID 根据密钥内容生成稳定的 ID，避免单独维护密钥编号
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// PrimaryID 返回当前主密钥 ID
+func (k *Keyring) PrimaryID() string {
+	return k.primary
+}
+
+// WrapKey 使用当前主密钥加密数据密钥
+func (k *Keyring) WrapKey(dataKey []byte) (string, []byte, error) {
+	wrapped, err := Seal(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		return "", nil, err
+	}
+	return k.primary, wrapped, nil
+}
+
+// UnwrapKey 使用指定主密钥解密数据密钥
+func (k *Keyring) UnwrapKey(keyID string, wrapped []byte) ([]byte, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("master key %s not found in keyring", keyID)
+	}
+	return Open(master, wrapped, []byte(keyID))
+}
+
+// SealEnvelope 生成随机数据密钥加密 plaintext，aad 用于绑定上下文（如记录名）
+func (k *Keyring) SealEnvelope(plaintext, aad []byte) (*Envelope, error) {
+	dataKey, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := Seal(dataKey, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := k.WrapKey(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{KeyID: keyID, WrappedKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+// OpenEnvelope 解密信封
+func (k *Keyring) OpenEnvelope(env *Envelope, aad []byte) ([]byte, error) {
+	dataKey, err := k.UnwrapKey(env.KeyID, env.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return Open(dataKey, env.Ciphertext, aad)
+}
+
+// Rewrap 使用当前主密钥重新加密数据密钥，密文本身不变
+func (k *Keyring) Rewrap(env *Envelope) (*Envelope, error) {
+	if env.KeyID == k.primary {
+		return env, nil
+	}
+	dataKey, err := k.UnwrapKey(env.KeyID, env.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := k.WrapKey(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{KeyID: keyID, WrappedKey: wrapped, Ciphertext: env.Ciphertext}, nil
+}
@@ -0,0 +1,128 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestKeyring(t *testing.T, previous ...[]byte) (*Keyring, []byte) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey should succeed: %v", err)
+	}
+	k, err := NewKeyring(key, previous...)
+	if err != nil {
+		t.Fatalf("NewKeyring should succeed: %v", err)
+	}
+	return k, key
+}
+
+func TestSealOpen(t *testing.T) {
+	key, _ := GenerateKey()
+
+	data, err := Seal(key, []byte("secret"), []byte("aad"))
+	if err != nil {
+		t.Fatalf("Seal should succeed: %v", err)
+	}
+	plaintext, err := Open(key, data, []byte("aad"))
+	if err != nil {
+		t.Fatalf("Open should succeed: %v", err)
+	}
+	if string(plaintext) != "secret" {
+		t.Errorf("plaintext should be 'secret', got %q", plaintext)
+	}
+
+	if _, err := Open(key, data, []byte("other")); err == nil {
+		t.Error("Open with wrong aad should fail")
+	}
+}
+
+func TestKeyring_Envelope(t *testing.T) {
+	k, _ := newTestKeyring(t)
+
+	env, err := k.SealEnvelope([]byte("AKIA/secret"), []byte("aws/prod"))
+	if err != nil {
+		t.Fatalf("SealEnvelope should succeed: %v", err)
+	}
+	if env.KeyID != k.PrimaryID() {
+		t.Errorf("envelope should use primary key, got %s", env.KeyID)
+	}
+
+	plaintext, err := k.OpenEnvelope(env, []byte("aws/prod"))
+	if err != nil {
+		t.Fatalf("OpenEnvelope should succeed: %v", err)
+	}
+	if string(plaintext) != "AKIA/secret" {
+		t.Errorf("plaintext wrong: %q", plaintext)
+	}
+}
+
+func TestKeyring_Rewrap(t *testing.T) {
+	old, oldKey := newTestKeyring(t)
+	env, _ := old.SealEnvelope([]byte("data"), nil)
+
+	rotated, _ := newTestKeyring(t, oldKey)
+	if _, err := rotated.OpenEnvelope(env, nil); err != nil {
+		t.Fatalf("rotated keyring should still open old envelope: %v", err)
+	}
+
+	rewrapped, err := rotated.Rewrap(env)
+	if err != nil {
+		t.Fatalf("Rewrap should succeed: %v", err)
+	}
+	if rewrapped.KeyID != rotated.PrimaryID() {
+		t.Errorf("rewrapped envelope should use new primary key")
+	}
+
+	// 移除旧密钥后仍可解密
+	newOnly, _ := NewKeyring(rotated.keys[rotated.PrimaryID()])
+	plaintext, err := newOnly.OpenEnvelope(rewrapped, nil)
+	if err != nil {
+		t.Fatalf("new key should open rewrapped envelope: %v", err)
+	}
+	if string(plaintext) != "data" {
+		t.Errorf("plaintext wrong: %q", plaintext)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	primary, _ := GenerateKey()
+	previous, _ := GenerateKey()
+
+	path := filepath.Join(t.TempDir(), "master.key")
+	content := "# prism master keys\n" +
+		base64.StdEncoding.EncodeToString(primary) + "\n" +
+		base64.StdEncoding.EncodeToString(previous) + "\n"
+	os.WriteFile(path, []byte(content), 0600)
+
+	k, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatalf("LoadKeyring should succeed: %v", err)
+	}
+	if k.PrimaryID() != KeyID(primary) {
+		t.Error("first key should be primary")
+	}
+	if len(k.keys) != 2 {
+		t.Errorf("keyring should contain 2 keys, got %d", len(k.keys))
+	}
+}
+
+func TestLoadKeyring_Env(t *testing.T) {
+	key, _ := GenerateKey()
+	t.Setenv(MasterKeyEnv, base64.StdEncoding.EncodeToString(key))
+
+	k, err := LoadKeyring("")
+	if err != nil {
+		t.Fatalf("LoadKeyring should succeed: %v", err)
+	}
+	if k.PrimaryID() != KeyID(key) {
+		t.Error("env key should be primary")
+	}
+
+	t.Setenv(MasterKeyEnv, "")
+	if _, err := LoadKeyring(""); err == nil {
+		t.Error("missing master key should return error")
+	}
+}
@@ -3,6 +3,7 @@ package executor
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/looplab/fsm"
 )
@@ -12,8 +13,12 @@ type BaseExecutor struct {
 	mu       sync.RWMutex
 	taskID   string
 	progress *Progress
+	started  time.Time // 进入 running 状态的时间
+	finished time.Time // 离开 running 状态的时间
 	fsm      *fsm.FSM
 	cancel   context.CancelFunc
+
+	onTransition func(taskID, from, to string) // 状态转换回调
 }
 
 // NewBaseExecutor 创建基础执行器
@@ -35,20 +40,72 @@ func (b *BaseExecutor) initFSM() {
 			{Name: "fail", Src: []string{string(StatusRunning)}, Dst: string(StatusFailed)},
 			{Name: "cancel", Src: []string{string(StatusPending), string(StatusRunning)}, Dst: string(StatusCancelled)},
 		},
-		fsm.Callbacks{},
+		fsm.Callbacks{
+			"enter_state": func(_ context.Context, e *fsm.Event) {
+				b.mu.Lock()
+				if e.Dst == string(StatusRunning) {
+					b.started, b.finished = time.Now(), time.Time{}
+				} else if !b.started.IsZero() && b.finished.IsZero() {
+					b.finished = time.Now()
+				}
+				taskID, hook := b.taskID, b.onTransition
+				b.mu.Unlock()
+				if hook != nil {
+					hook(taskID, e.Src, e.Dst)
+				}
+			},
+		},
 	)
 }
 
+// Reset 重置状态机与进度，执行器可在任务结束后执行下一个任务
+func (b *BaseExecutor) Reset() {
+	b.mu.Lock()
+	b.progress = &Progress{}
+	b.started, b.finished = time.Time{}, time.Time{}
+	b.cancel = nil
+	b.mu.Unlock()
+	b.fsm.SetState(string(StatusPending))
+}
+
+// SetTaskID 设置当前任务 ID
+func (b *BaseExecutor) SetTaskID(taskID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.taskID = taskID
+}
+
+// TaskID 返回当前任务 ID
+func (b *BaseExecutor) TaskID() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.taskID
+}
+
+// OnTransition 设置状态转换回调，用于持久化状态历史。Reset 不触发回调
+func (b *BaseExecutor) OnTransition(fn func(taskID, from, to string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onTransition = fn
+}
+
 // GetProgress 获取进度
 func (b *BaseExecutor) GetProgress() *Progress {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return &Progress{
-		Phase:   b.progress.Phase,
-		Percent: b.progress.Percent,
-		Elapsed: b.progress.Elapsed,
-		Message: b.progress.Message,
+	p := *b.progress
+	p.Resources = append([]ResourceProgress(nil), b.progress.Resources...)
+	if !b.started.IsZero() {
+		p.Elapsed = b.elapsed().Milliseconds()
 	}
+	return &p
+}
+
+// SetProgress 更新完整进度信息，Elapsed 由开始时间计算，无需设置
+func (b *BaseExecutor) SetProgress(p *Progress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	*b.progress = *p
 }
 
 // UpdateProgress 更新进度
@@ -60,6 +117,23 @@ func (b *BaseExecutor) UpdateProgress(phase string, percent int, message string)
 	b.progress.Message = message
 }
 
+// Elapsed 返回执行耗时：运行中为进入 running 以来的时间，结束后固定为运行时长
+func (b *BaseExecutor) Elapsed() time.Duration {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.elapsed()
+}
+
+func (b *BaseExecutor) elapsed() time.Duration {
+	if b.started.IsZero() {
+		return 0
+	}
+	if b.finished.IsZero() {
+		return time.Since(b.started)
+	}
+	return b.finished.Sub(b.started)
+}
+
 // Status 获取当前状态
 func (b *BaseExecutor) Status() Status {
 	return Status(b.fsm.Current())
@@ -3,6 +3,7 @@ package executor
 import (
 	"context"
 	"testing"
+	"time"
 )
 
 func TestBaseExecutor_New(t *testing.T) {
@@ -167,3 +168,90 @@ func TestBaseExecutor_CancelWithoutCancelFunc(t *testing.T) {
 		t.Fatalf("cancel without cancel func should succeed: %v", err)
 	}
 }
+
+func TestBaseExecutor_Elapsed(t *testing.T) {
+	b := NewBaseExecutor()
+	if b.Elapsed() != 0 {
+		t.Errorf("elapsed should be 0 before start, got %v", b.Elapsed())
+	}
+
+	b.Transition("start")
+	time.Sleep(20 * time.Millisecond)
+	if p := b.GetProgress(); p.Elapsed < 20 {
+		t.Errorf("elapsed should be at least 20ms while running, got %d", p.Elapsed)
+	}
+
+	b.Transition("success")
+	elapsed := b.Elapsed()
+	time.Sleep(20 * time.Millisecond)
+	if b.Elapsed() != elapsed {
+		t.Errorf("elapsed should be frozen after completion, got %v then %v", elapsed, b.Elapsed())
+	}
+}
+
+func TestBaseExecutor_SetProgress(t *testing.T) {
+	b := NewBaseExecutor()
+	b.SetProgress(&Progress{
+		Phase:     "apply",
+		Percent:   60,
+		Planned:   2,
+		Completed: 1,
+		Resources: []ResourceProgress{{Address: "null_resource.a", Status: "complete"}},
+	})
+
+	p := b.GetProgress()
+	if p.Planned != 2 || p.Completed != 1 || len(p.Resources) != 1 {
+		t.Fatalf("unexpected progress: %+v", p)
+	}
+
+	// 返回的资源列表应为副本
+	p.Resources[0].Status = "errored"
+	if b.GetProgress().Resources[0].Status != "complete" {
+		t.Error("GetProgress should copy resources")
+	}
+}
+
+func TestBaseExecutor_Reset(t *testing.T) {
+	b := NewBaseExecutor()
+	b.Transition("start")
+	b.UpdateProgress("apply", 50, "applying")
+	b.Transition("fail")
+
+	// 结束状态不能再次开始
+	if err := b.Transition("start"); err == nil {
+		t.Fatal("start from failed should fail")
+	}
+
+	b.Reset()
+	if b.Status() != StatusPending || b.Elapsed() != 0 || b.GetProgress().Phase != "" {
+		t.Fatalf("reset should restore initial state, got %s %v %+v", b.Status(), b.Elapsed(), b.GetProgress())
+	}
+	if err := b.Transition("start"); err != nil {
+		t.Fatalf("start after reset should succeed: %v", err)
+	}
+}
+
+func TestBaseExecutor_OnTransition(t *testing.T) {
+	b := NewBaseExecutor()
+	var got []string
+	b.OnTransition(func(taskID, from, to string) {
+		got = append(got, taskID+":"+from+"->"+to)
+	})
+	b.SetTaskID("task-1")
+
+	b.Transition("start")
+	b.Transition("success")
+	b.Reset()
+	b.SetTaskID("task-2")
+	b.Transition("cancel")
+
+	expected := []string{"task-1:pending->running", "task-1:running->success", "task-2:pending->cancelled"}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("transition %d: expected %s, got %s", i, expected[i], got[i])
+		}
+	}
+}
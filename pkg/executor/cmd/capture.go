@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Stream 输出流类型
+type Stream string
+
+const (
+	StreamStdout Stream = "stdout"
+	StreamStderr Stream = "stderr"
+)
+
+// LineHandler 按行处理输出，stream 标记该行来自哪个输出流
+type LineHandler func(stream Stream, line string)
+
+// capture 有界输出捕获：内存中最多保留 limit 字节，超出部分连同已缓存内容溢出到文件
+type capture struct {
+	stream   Stream
+	limit    int64
+	spillDir string
+
+	buffer    bytes.Buffer
+	size      int64
+	file      *os.File
+	truncated bool
+	err       error
+}
+
+func newCapture(stream Stream, limit int64, spillDir string) *capture {
+	return &capture{stream: stream, limit: limit, spillDir: spillDir}
+}
+
+func (c *capture) Write(p []byte) (int, error) {
+	n := len(p)
+	c.size += int64(n)
+
+	if c.file != nil {
+		c.writeFile(p)
+		return n, nil
+	}
+
+	if c.limit <= 0 || int64(c.buffer.Len()+len(p)) <= c.limit {
+		c.buffer.Write(p)
+		return n, nil
+	}
+
+	// 超出内存上限：内存保留前 limit 字节，完整内容写入溢出文件
+	c.truncated = true
+	if c.spillDir != "" && c.err == nil {
+		f, err := os.CreateTemp(c.spillDir, fmt.Sprintf("%s-*.log", c.stream))
+		if err != nil {
+			c.err = fmt.Errorf("failed to create spill file: %w", err)
+		} else {
+			c.file = f
+			c.writeFile(c.buffer.Bytes())
+			c.writeFile(p)
+		}
+	}
+
+	if remain := c.limit - int64(c.buffer.Len()); remain > 0 {
+		c.buffer.Write(p[:remain])
+	}
+	return n, nil
+}
+
+func (c *capture) writeFile(p []byte) {
+	if c.err != nil {
+		return
+	}
+	if _, err := c.file.Write(p); err != nil {
+		c.err = fmt.Errorf("failed to write spill file: %w", err)
+	}
+}
+
+// close 关闭溢出文件并返回其路径，写入失败的溢出文件内容不完整，删除后返回空
+func (c *capture) close() string {
+	if c.file == nil {
+		return ""
+	}
+	c.file.Close()
+	if c.err != nil {
+		os.Remove(c.file.Name())
+		return ""
+	}
+	return c.file.Name()
+}
+
+// lineSplitter 将字节流切分为行并回调，单行超过 maxLen 时截断，剩余部分丢弃直到换行
+type lineSplitter struct {
+	mu      *sync.Mutex
+	stream  Stream
+	handler LineHandler
+	maxLen  int
+	line    bytes.Buffer
+	dropped bool
+}
+
+func (s *lineSplitter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b == '\n' {
+			s.flush()
+			continue
+		}
+		if s.maxLen > 0 && s.line.Len() >= s.maxLen {
+			s.dropped = true
+			continue
+		}
+		s.line.WriteByte(b)
+	}
+	return len(p), nil
+}
+
+// flush 输出当前缓存的行
+func (s *lineSplitter) flush() {
+	line := s.line.String()
+	if s.dropped {
+		line += "...(truncated)"
+	}
+	s.line.Reset()
+	s.dropped = false
+
+	if s.handler == nil {
+		return
+	}
+	// stdout 与 stderr 由不同 goroutine 写入，回调需要串行化
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler(s.stream, line)
+}
+
+// flushPartial 进程结束时输出未以换行结尾的最后一行
+func (s *lineSplitter) flushPartial() {
+	if s.line.Len() > 0 || s.dropped {
+		s.flush()
+	}
+}
+
+// streamWriter 同时写入捕获缓冲与行处理器
+type streamWriter struct {
+	capture  *capture
+	splitter *lineSplitter
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.capture.Write(p)
+	if w.splitter != nil {
+		w.splitter.Write(p)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCapture_WithinLimit(t *testing.T) {
+	c := newCapture(StreamStdout, 16, t.TempDir())
+	c.Write([]byte("hello"))
+
+	if c.buffer.String() != "hello" {
+		t.Errorf("buffer should be 'hello', got %q", c.buffer.String())
+	}
+	if c.truncated {
+		t.Error("capture should not be truncated")
+	}
+	if path := c.close(); path != "" {
+		t.Errorf("no spill file expected, got %s", path)
+	}
+}
+
+func TestCapture_SpillToFile(t *testing.T) {
+	dir := t.TempDir()
+	c := newCapture(StreamStdout, 8, dir)
+	c.Write([]byte("0123456"))
+	c.Write([]byte("789abc"))
+	c.Write([]byte("def"))
+
+	if c.buffer.String() != "01234567" {
+		t.Errorf("buffer should keep first 8 bytes, got %q", c.buffer.String())
+	}
+	if !c.truncated {
+		t.Error("capture should be truncated")
+	}
+
+	path := c.close()
+	if path == "" {
+		t.Fatal("spill file should be created")
+	}
+	if !strings.HasPrefix(path, dir) {
+		t.Errorf("spill file should be in %s, got %s", dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("should read spill file: %v", err)
+	}
+	if string(data) != "0123456789abcdef" {
+		t.Errorf("spill file should contain full output, got %q", string(data))
+	}
+}
+
+func TestCapture_NoSpillDir(t *testing.T) {
+	c := newCapture(StreamStderr, 4, "")
+	c.Write([]byte("abcdefgh"))
+
+	if c.buffer.String() != "abcd" {
+		t.Errorf("buffer should be 'abcd', got %q", c.buffer.String())
+	}
+	if !c.truncated {
+		t.Error("capture should be truncated")
+	}
+	if path := c.close(); path != "" {
+		t.Errorf("no spill file expected, got %s", path)
+	}
+}
+
+func TestLineSplitter_MaxLength(t *testing.T) {
+	var lines []string
+	s := &lineSplitter{
+		mu:     &sync.Mutex{},
+		stream: StreamStdout,
+		maxLen: 4,
+		handler: func(_ Stream, line string) {
+			lines = append(lines, line)
+		},
+	}
+
+	s.Write([]byte("ab\nabcdefgh\nxy"))
+	s.flushPartial()
+
+	expected := []string{"ab", "abcd...(truncated)", "xy"}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d: %v", len(expected), len(lines), lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d = %q, want %q", i, lines[i], expected[i])
+		}
+	}
+}
+
+func TestCapture_SpillError(t *testing.T) {
+	c := newCapture(StreamStdout, 4, "/nonexistent/spill")
+	c.Write([]byte("abcdefgh"))
+
+	if c.err == nil {
+		t.Fatal("spill error should be recorded")
+	}
+	if c.buffer.String() != "abcd" {
+		t.Errorf("buffer should keep first 4 bytes, got %q", c.buffer.String())
+	}
+	if path := c.close(); path != "" {
+		t.Errorf("no spill file expected, got %s", path)
+	}
+}
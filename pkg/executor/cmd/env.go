@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultAllowList 默认允许从 Prism 进程继承的环境变量
+var DefaultAllowList = []string{
+	"PATH", "HOME", "USER", "TMPDIR", "LANG", "LC_ALL", "TZ",
+	"SSL_CERT_FILE", "SSL_CERT_DIR",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"http_proxy", "https_proxy", "no_proxy",
+}
+
+// credentialEnv 各 provider 凭证字段到环境变量的映射
+var credentialEnv = map[string]map[string]string{
+	"aws": {
+		"access_key": "AWS_ACCESS_KEY_ID",
+		"secret_key": "AWS_SECRET_ACCESS_KEY",
+		"token":      "AWS_SESSION_TOKEN",
+		"region":     "AWS_REGION",
+		"profile":    "AWS_PROFILE",
+	},
+	"tencentcloud": {
+		"secret_id":      "TENCENTCLOUD_SECRET_ID",
+		"secret_key":     "TENCENTCLOUD_SECRET_KEY",
+		"security_token": "TENCENTCLOUD_SECURITY_TOKEN",
+		"region":         "TENCENTCLOUD_REGION",
+	},
+	"alicloud": {
+		"access_key":     "ALICLOUD_ACCESS_KEY",
+		"secret_key":     "ALICLOUD_SECRET_KEY",
+		"security_token": "ALICLOUD_SECURITY_TOKEN",
+		"region":         "ALICLOUD_REGION",
+	},
+	"google": {
+		"credentials": "GOOGLE_CREDENTIALS",
+		"project":     "GOOGLE_PROJECT",
+		"region":      "GOOGLE_REGION",
+	},
+	"azurerm": {
+		"client_id":       "ARM_CLIENT_ID",
+		"client_secret":   "ARM_CLIENT_SECRET",
+		"tenant_id":       "ARM_TENANT_ID",
+		"subscription_id": "ARM_SUBSCRIPTION_ID",
+	},
+	"kubernetes": {
+		"config_path": "KUBE_CONFIG_PATH",
+		"host":        "KUBE_HOST",
+		"token":       "KUBE_TOKEN",
+	},
+}
+
+// publicCredentialFields 不属于敏感信息的凭证字段
+var publicCredentialFields = map[string]bool{
+	"region":      true,
+	"profile":     true,
+	"project":     true,
+	"config_path": true,
+	"host":        true,
+}
+
+// Env 进程环境变量构建器，仅包含白名单继承项与显式注入项
+type Env struct {
+	vars    map[string]string
+	secrets map[string]bool
+}
+
+// NewEnv 创建环境变量构建器，从当前进程继承 allow 中列出的变量，未指定时使用 DefaultAllowList
+func NewEnv(allow ...string) *Env {
+	if len(allow) == 0 {
+		allow = DefaultAllowList
+	}
+	e := &Env{
+		vars:    make(map[string]string),
+		secrets: make(map[string]bool),
+	}
+	for _, key := range allow {
+		if value, ok := os.LookupEnv(key); ok {
+			e.vars[key] = value
+		}
+	}
+	return e
+}
+
+// Set 设置普通变量
+func (e *Env) Set(key, value string) *Env {
+	e.vars[key] = value
+	delete(e.secrets, key)
+	return e
+}
+
+// SetSecret 设置敏感变量，其值不会出现在 String 输出中
+func (e *Env) SetSecret(key, value string) *Env {
+	e.vars[key] = value
+	e.secrets[key] = true
+	return e
+}
+
+// SetVar 设置 Terraform 输入变量 TF_VAR_<name>
+func (e *Env) SetVar(name, value string, sensitive bool) *Env {
+	key := "TF_VAR_" + name
+	if sensitive {
+		return e.SetSecret(key, value)
+	}
+	return e.Set(key, value)
+}
+
+// SetCredentials 按 provider 将凭证字段注入为对应的环境变量
+func (e *Env) SetCredentials(provider string, creds map[string]string) error {
+	mapping, ok := credentialEnv[provider]
+	if !ok {
+		return fmt.Errorf("unsupported credential provider: %s", provider)
+	}
+	for field, value := range creds {
+		key, ok := mapping[field]
+		if !ok {
+			return fmt.Errorf("unknown credential field %q for provider %s", field, provider)
+		}
+		if publicCredentialFields[field] {
+			e.Set(key, value)
+		} else {
+			e.SetSecret(key, value)
+		}
+	}
+	return nil
+}
+
+// Get 获取变量值
+func (e *Env) Get(key string) (string, bool) {
+	value, ok := e.vars[key]
+	return value, ok
+}
+
+// Environ 返回 KEY=VALUE 形式的变量列表，按 key 排序
+func (e *Env) Environ() []string {
+	keys := e.keys()
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+e.vars[key])
+	}
+	return env
+}
+
+// Secrets 返回所有敏感变量的值
+func (e *Env) Secrets() []string {
+	var values []string
+	for _, key := range e.keys() {
+		if e.secrets[key] && e.vars[key] != "" {
+			values = append(values, e.vars[key])
+		}
+	}
+	return values
+}
+
+// String 返回脱敏后的变量列表，可安全写入日志
+func (e *Env) String() string {
+	keys := e.keys()
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := e.vars[key]
+		if e.secrets[key] {
+			value = "******"
+		}
+		parts = append(parts, key+"="+value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// GoString 与 String 相同，避免 %#v 输出敏感值
+func (e *Env) GoString() string {
+	return e.String()
+}
+
+func (e *Env) keys() []string {
+	keys := make([]string, 0, len(e.vars))
+	for key := range e.vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
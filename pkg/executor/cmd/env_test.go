@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEnv_AllowList(t *testing.T) {
+	t.Setenv("PRISM_TEST_ALLOWED", "yes")
+	t.Setenv("PRISM_TEST_DENIED", "no")
+
+	env := NewEnv("PRISM_TEST_ALLOWED")
+
+	if v, ok := env.Get("PRISM_TEST_ALLOWED"); !ok || v != "yes" {
+		t.Errorf("allowed variable should be inherited, got %q", v)
+	}
+	if _, ok := env.Get("PRISM_TEST_DENIED"); ok {
+		t.Error("variable outside allow list should not be inherited")
+	}
+}
+
+func TestEnv_DefaultAllowList(t *testing.T) {
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "leaked")
+
+	env := NewEnv()
+	if _, ok := env.Get("AWS_SECRET_ACCESS_KEY"); ok {
+		t.Error("credentials from prism environment should not be inherited")
+	}
+}
+
+func TestEnv_SetVar(t *testing.T) {
+	env := NewEnv("NONE")
+	env.SetVar("region", "us-east-1", false)
+	env.SetVar("password", "p@ss", true)
+
+	environ := env.Environ()
+	expected := []string{"TF_VAR_password=p@ss", "TF_VAR_region=us-east-1"}
+	if len(environ) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, environ)
+	}
+	for i := range expected {
+		if environ[i] != expected[i] {
+			t.Errorf("environ[%d] = %q, want %q", i, environ[i], expected[i])
+		}
+	}
+
+	secrets := env.Secrets()
+	if len(secrets) != 1 || secrets[0] != "p@ss" {
+		t.Errorf("secrets should be [p@ss], got %v", secrets)
+	}
+}
+
+func TestEnv_SetCredentials(t *testing.T) {
+	env := NewEnv("NONE")
+	err := env.SetCredentials("aws", map[string]string{
+		"access_key": "AKIA123",
+		"secret_key": "s3cr3t",
+		"region":     "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("SetCredentials should succeed: %v", err)
+	}
+
+	if v, _ := env.Get("AWS_ACCESS_KEY_ID"); v != "AKIA123" {
+		t.Errorf("AWS_ACCESS_KEY_ID wrong: %s", v)
+	}
+	if v, _ := env.Get("AWS_REGION"); v != "us-east-1" {
+		t.Errorf("AWS_REGION wrong: %s", v)
+	}
+	if len(env.Secrets()) != 2 {
+		t.Errorf("region should not be secret, got secrets %d", len(env.Secrets()))
+	}
+
+	if err := env.SetCredentials("aws", map[string]string{"unknown": "x"}); err == nil {
+		t.Error("unknown field should return error")
+	}
+	if err := env.SetCredentials("nonexistent", nil); err == nil {
+		t.Error("unknown provider should return error")
+	}
+}
+
+func TestEnv_StringRedacted(t *testing.T) {
+	env := NewEnv("NONE")
+	env.Set("TF_IN_AUTOMATION", "1")
+	env.SetSecret("TENCENTCLOUD_SECRET_KEY", "topsecret")
+
+	for _, s := range []string{env.String(), fmt.Sprintf("%v", env), fmt.Sprintf("%#v", env)} {
+		if strings.Contains(s, "topsecret") {
+			t.Errorf("secret leaked: %s", s)
+		}
+	}
+	if !strings.Contains(env.String(), "TF_IN_AUTOMATION=1") {
+		t.Errorf("plain variable should be visible: %s", env.String())
+	}
+}
+
+func TestRunner_Run_Env(t *testing.T) {
+	env := NewEnv()
+	env.Set("PRISM_TEST_VAR", "injected")
+
+	r := NewRunner(0)
+	result := r.Run(t.Context(), []string{"sh", "-c", "echo $PRISM_TEST_VAR"}, &Options{Env: env.Environ()})
+	if result.Error != nil {
+		t.Fatalf("command should succeed: %v", result.Error)
+	}
+	if result.Stdout != "injected\n" {
+		t.Errorf("stdout should be 'injected\\n', got %q", result.Stdout)
+	}
+}
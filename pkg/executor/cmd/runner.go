@@ -2,26 +2,49 @@
 package cmd
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"regexp"
+	"sync"
 	"syscall"
 	"time"
 )
 
+const (
+	// DefaultCaptureLimit 单个输出流在内存中保留的最大字节数
+	DefaultCaptureLimit int64 = 8 << 20
+	// DefaultMaxLineLength 单行最大长度，超出部分截断
+	DefaultMaxLineLength = 1 << 20
+)
+
 // Result 命令执行结果
 type Result struct {
-	Output   string
-	ExitCode int
-	Duration time.Duration
-	Error    error
+	Output     string // stdout + stderr
+	Stdout     string // 标准输出（内存捕获部分）
+	Stderr     string // 标准错误（内存捕获部分）
+	StdoutFile string // stdout 溢出文件（完整内容），未溢出时为空
+	StderrFile string // stderr 溢出文件（完整内容），未溢出时为空
+	Truncated  bool   // 内存捕获是否被截断
+	ExitCode   int
+	Duration   time.Duration
+	Error      error
+	SpillError error // 溢出文件创建或写入失败，此时超出内存上限的输出已丢失
+}
+
+// Options 单次执行选项
+type Options struct {
+	SpillDir string      // 输出超出内存上限时的溢出目录，为空则直接丢弃超出部分
+	Env      []string    // 进程环境变量，为 nil 时继承当前进程环境
+	Handler  LineHandler // 按行实时处理输出
 }
 
 // Runner 命令执行器
 type Runner struct {
-	timeout time.Duration
+	timeout       time.Duration
+	captureLimit  int64
+	maxLineLength int
 }
 
 // NewRunner 创建命令执行器
@@ -29,14 +52,50 @@ func NewRunner(timeout time.Duration) *Runner {
 	if timeout == 0 {
 		timeout = 30 * time.Minute
 	}
-	return &Runner{timeout: timeout}
+	return &Runner{
+		timeout:       timeout,
+		captureLimit:  DefaultCaptureLimit,
+		maxLineLength: DefaultMaxLineLength,
+	}
+}
+
+// SetCaptureLimit 设置单个输出流的内存捕获上限，<=0 表示不限制
+func (r *Runner) SetCaptureLimit(limit int64) {
+	r.captureLimit = limit
+}
+
+// SetMaxLineLength 设置单行最大长度，<=0 表示不限制
+func (r *Runner) SetMaxLineLength(n int) {
+	r.maxLineLength = n
 }
 
 // Exec 执行命令
 func (r *Runner) Exec(ctx context.Context, args []string) *Result {
+	return r.Run(ctx, args, nil)
+}
+
+// ExecWithHandler 执行命令并实时处理输出
+func (r *Runner) ExecWithHandler(ctx context.Context, args []string, handler func(line string)) *Result {
+	opts := &Options{}
+	if handler != nil {
+		opts.Handler = func(_ Stream, line string) {
+			handler(line)
+		}
+	}
+	return r.Run(ctx, args, opts)
+}
+
+// ErrTimeout 命令执行超时
+var ErrTimeout = errors.New("command timed out")
+
+// Run 执行命令，分别捕获 stdout/stderr 并按行回调
+func (r *Runner) Run(ctx context.Context, args []string, opts *Options) *Result {
 	if len(args) == 0 {
 		return &Result{Error: fmt.Errorf("empty command")}
 	}
+	if opts == nil {
+		opts = &Options{}
+	}
 
 	start := time.Now()
 	result := &Result{}
@@ -48,17 +107,38 @@ func (r *Runner) Exec(ctx context.Context, args []string) *Result {
 	cmd := exec.CommandContext(timeoutCtx, args[0], args[1:]...)
 	// 设置进程组，便于杀死子进程
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if opts.Env != nil {
+		cmd.Env = opts.Env
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	stdout := newCapture(StreamStdout, r.captureLimit, opts.SpillDir)
+	stderr := newCapture(StreamStderr, r.captureLimit, opts.SpillDir)
+	stdoutWriter := &streamWriter{capture: stdout}
+	stderrWriter := &streamWriter{capture: stderr}
+	if opts.Handler != nil {
+		mu := &sync.Mutex{}
+		stdoutWriter.splitter = &lineSplitter{mu: mu, stream: StreamStdout, handler: opts.Handler, maxLen: r.maxLineLength}
+		stderrWriter.splitter = &lineSplitter{mu: mu, stream: StreamStderr, handler: opts.Handler, maxLen: r.maxLineLength}
+	}
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
 
 	// 执行命令
 	err := cmd.Run()
 	result.Duration = time.Since(start)
 
-	// 合并输出
-	result.Output = stdout.String() + stderr.String()
+	if opts.Handler != nil {
+		stdoutWriter.splitter.flushPartial()
+		stderrWriter.splitter.flushPartial()
+	}
+
+	result.Stdout = stdout.buffer.String()
+	result.Stderr = stderr.buffer.String()
+	result.Output = result.Stdout + result.Stderr
+	result.StdoutFile = stdout.close()
+	result.StderrFile = stderr.close()
+	result.Truncated = stdout.truncated || stderr.truncated
+	result.SpillError = errors.Join(stdout.err, stderr.err)
 
 	if err != nil {
 		// 检查是否超时
@@ -67,7 +147,7 @@ func (r *Runner) Exec(ctx context.Context, args []string) *Result {
 			if cmd.Process != nil {
 				syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 			}
-			result.Error = fmt.Errorf("command timed out after %v", r.timeout)
+			result.Error = fmt.Errorf("%w after %v", ErrTimeout, r.timeout)
 			result.ExitCode = -1
 			return result
 		}
@@ -84,78 +164,6 @@ func (r *Runner) Exec(ctx context.Context, args []string) *Result {
 	return result
 }
 
-// ExecWithHandler 执行命令并实时处理输出
-func (r *Runner) ExecWithHandler(ctx context.Context, args []string, handler func(line string)) *Result {
-	if len(args) == 0 {
-		return &Result{Error: fmt.Errorf("empty command")}
-	}
-
-	start := time.Now()
-	result := &Result{}
-
-	timeoutCtx, cancel := context.WithTimeout(ctx, r.timeout)
-	defer cancel()
-
-	cmd := exec.CommandContext(timeoutCtx, args[0], args[1:]...)
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-
-	// 使用自定义 writer 处理输出
-	output := &outputWriter{
-		handler: handler,
-		buffer:  &bytes.Buffer{},
-	}
-	cmd.Stdout = output
-	cmd.Stderr = output
-
-	err := cmd.Run()
-	result.Duration = time.Since(start)
-	result.Output = output.buffer.String()
-
-	if err != nil {
-		if timeoutCtx.Err() == context.DeadlineExceeded {
-			if cmd.Process != nil {
-				syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-			}
-			result.Error = fmt.Errorf("command timed out after %v", r.timeout)
-			result.ExitCode = -1
-			return result
-		}
-
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			result.ExitCode = exitErr.ExitCode()
-		} else {
-			result.ExitCode = -1
-		}
-		result.Error = err
-	}
-
-	return result
-}
-
-// outputWriter 输出处理器
-type outputWriter struct {
-	handler func(line string)
-	buffer  *bytes.Buffer
-	line    bytes.Buffer
-}
-
-func (w *outputWriter) Write(p []byte) (n int, err error) {
-	n = len(p)
-	w.buffer.Write(p)
-
-	for _, b := range p {
-		if b == '\n' {
-			if w.handler != nil {
-				w.handler(w.line.String())
-			}
-			w.line.Reset()
-		} else {
-			w.line.WriteByte(b)
-		}
-	}
-	return n, nil
-}
-
 // StripANSI 移除 ANSI 转义序列
 func StripANSI(s string) string {
 	re := regexp.MustCompile(`\x1b\[[0-9;]*m`)
@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -137,3 +140,78 @@ func TestStripANSI(t *testing.T) {
 		}
 	}
 }
+
+func TestRunner_Run_SeparateStreams(t *testing.T) {
+	r := NewRunner(5 * time.Second)
+
+	var mu sync.Mutex
+	streams := map[Stream][]string{}
+	result := r.Run(context.Background(), []string{"sh", "-c", "echo out; echo err >&2"}, &Options{
+		Handler: func(stream Stream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			streams[stream] = append(streams[stream], line)
+		},
+	})
+
+	if result.Error != nil {
+		t.Fatalf("command should succeed: %v", result.Error)
+	}
+	if result.Stdout != "out\n" {
+		t.Errorf("stdout should be 'out\\n', got %q", result.Stdout)
+	}
+	if result.Stderr != "err\n" {
+		t.Errorf("stderr should be 'err\\n', got %q", result.Stderr)
+	}
+	if len(streams[StreamStdout]) != 1 || streams[StreamStdout][0] != "out" {
+		t.Errorf("stdout lines wrong: %v", streams[StreamStdout])
+	}
+	if len(streams[StreamStderr]) != 1 || streams[StreamStderr][0] != "err" {
+		t.Errorf("stderr lines wrong: %v", streams[StreamStderr])
+	}
+}
+
+func TestRunner_Run_CaptureLimit(t *testing.T) {
+	r := NewRunner(5 * time.Second)
+	r.SetCaptureLimit(10)
+
+	dir := t.TempDir()
+	result := r.Run(context.Background(), []string{"sh", "-c", "printf '0123456789abcdefghij'"}, &Options{SpillDir: dir})
+
+	if result.Error != nil {
+		t.Fatalf("command should succeed: %v", result.Error)
+	}
+	if !result.Truncated {
+		t.Error("result should be truncated")
+	}
+	if result.Stdout != "0123456789" {
+		t.Errorf("stdout should keep first 10 bytes, got %q", result.Stdout)
+	}
+	if result.StdoutFile == "" {
+		t.Fatal("stdout should spill to file")
+	}
+	data, _ := os.ReadFile(result.StdoutFile)
+	if string(data) != "0123456789abcdefghij" {
+		t.Errorf("spill file should contain full output, got %q", string(data))
+	}
+	if result.StderrFile != "" {
+		t.Errorf("stderr should not spill, got %s", result.StderrFile)
+	}
+}
+
+func TestRunner_Run_SpillError(t *testing.T) {
+	r := NewRunner(5 * time.Second)
+	r.SetCaptureLimit(10)
+
+	result := r.Run(context.Background(), []string{"sh", "-c", "printf '0123456789abcdefghij'"}, &Options{SpillDir: filepath.Join(t.TempDir(), "missing")})
+
+	if result.Error != nil {
+		t.Fatalf("command should succeed: %v", result.Error)
+	}
+	if result.SpillError == nil {
+		t.Error("spill error should be reported")
+	}
+	if !result.Truncated || result.Stdout != "0123456789" || result.StdoutFile != "" {
+		t.Errorf("stdout should be truncated in memory without spill file: %+v", result)
+	}
+}
@@ -0,0 +1,122 @@
+// Package dag orchestrates execution across resources that depend on each other.
+package dag
+
+import (
+	"fmt"
+	"sort"
+
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// Graph 资源依赖图，边由下游资源指向其依赖的上游资源
+type Graph struct {
+	upstream   map[int64][]models.ResourceDependency
+	downstream map[int64][]int64
+}
+
+// NewGraph 由依赖关系构建依赖图
+func NewGraph(deps []models.ResourceDependency) *Graph {
+	g := &Graph{
+		upstream:   make(map[int64][]models.ResourceDependency),
+		downstream: make(map[int64][]int64),
+	}
+	for _, dep := range deps {
+		g.upstream[dep.ResourceID] = append(g.upstream[dep.ResourceID], dep)
+		g.downstream[dep.DependsOnID] = append(g.downstream[dep.DependsOnID], dep.ResourceID)
+	}
+	return g
+}
+
+// Dependencies 返回资源对上游资源的依赖
+func (g *Graph) Dependencies(id int64) []models.ResourceDependency {
+	return g.upstream[id]
+}
+
+// Upstream 返回资源及其直接、间接依赖的全部上游资源
+func (g *Graph) Upstream(ids []int64) []int64 {
+	return g.closure(ids, func(id int64) []int64 {
+		var next []int64
+		for _, dep := range g.upstream[id] {
+			next = append(next, dep.DependsOnID)
+		}
+		return next
+	})
+}
+
+// Downstream 返回资源及其直接、间接依赖它们的全部下游资源
+func (g *Graph) Downstream(ids []int64) []int64 {
+	return g.closure(ids, func(id int64) []int64 {
+		return g.downstream[id]
+	})
+}
+
+func (g *Graph) closure(ids []int64, next func(int64) []int64) []int64 {
+	seen := make(map[int64]bool)
+	stack := append([]int64(nil), ids...)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		stack = append(stack, next(id)...)
+	}
+	return sortedKeys(seen)
+}
+
+// Levels 将资源按拓扑顺序分层，每层只依赖之前的层，同层资源可并行执行。
+// 只考虑资源之间的依赖，存在环时返回错误
+func (g *Graph) Levels(ids []int64) ([][]int64, error) {
+	in := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		in[id] = true
+	}
+	pending := make(map[int64]int, len(in))
+	for id := range in {
+		pending[id] = 0
+		for _, dep := range g.upstream[id] {
+			if in[dep.DependsOnID] {
+				pending[id]++
+			}
+		}
+	}
+
+	var levels [][]int64
+	for len(pending) > 0 {
+		var level []int64
+		for id, n := range pending {
+			if n == 0 {
+				level = append(level, id)
+			}
+		}
+		if len(level) == 0 {
+			var cyclic []int64
+			for id := range pending {
+				cyclic = append(cyclic, id)
+			}
+			sort.Slice(cyclic, func(i, j int) bool { return cyclic[i] < cyclic[j] })
+			return nil, fmt.Errorf("dependency cycle among resources %v", cyclic)
+		}
+		sort.Slice(level, func(i, j int) bool { return level[i] < level[j] })
+		for _, id := range level {
+			delete(pending, id)
+			for _, down := range g.downstream[id] {
+				if _, ok := pending[down]; ok {
+					pending[down]--
+				}
+			}
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+func sortedKeys(set map[int64]bool) []int64 {
+	ids := make([]int64, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
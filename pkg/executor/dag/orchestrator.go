@@ -0,0 +1,415 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/logger"
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// StatusSkipped 上游资源失败或编排被取消，资源未执行
+const StatusSkipped executor.Status = "skipped"
+
+// DefaultParallelism 默认同时执行的资源数
+const DefaultParallelism = 4
+
+// ExecutorFactory 为每个资源任务创建执行器。执行器一次只执行一个任务，并行执行需要独立的实例
+type ExecutorFactory func() executor.Executor
+
+// RequestBuilder 根据资源构建执行请求，如 terraform.ParamsRequestBuilder
+type RequestBuilder func(taskID string, action executor.Action, resource *models.TerraformResource) (*executor.ExecuteRequest, error)
+
+// AttributeSource 提供已记录的资源属性，如 dao.TerraformResourceAttributeDAO
+type AttributeSource interface {
+	ListByResourceID(resourceID int64) ([]models.TerraformResourceAttribute, error)
+}
+
+// AttributeStore 记录 apply 产生的资源属性，供之后的编排 (如 destroy) 注入下游，如 dao.TerraformResourceAttributeDAO
+type AttributeStore interface {
+	AttributeSource
+	ReplaceResourceLevel(resourceID int64, attrs []models.TerraformResourceAttribute) error
+}
+
+// IDGenerator 生成 TerraformResourceAttribute 的 ID，如雪花算法
+type IDGenerator func() int64
+
+// NodeResult 单个资源的执行结果
+type NodeResult struct {
+	ResourceID int64
+	TaskID     string
+	Status     executor.Status
+	Error      string
+	Result     *executor.ExecuteResult
+}
+
+// RunResult 一次编排的结果
+type RunResult struct {
+	RunID  string
+	Action executor.Action
+	Order  [][]int64 // 执行层次，同层资源可并行
+	Nodes  map[int64]*NodeResult
+}
+
+// Success 判断全部资源是否执行成功
+func (r *RunResult) Success() bool {
+	for _, node := range r.Nodes {
+		if node.Status != executor.StatusSuccess {
+			return false
+		}
+	}
+	return true
+}
+
+// TaskID 返回编排中资源任务的 ID
+func TaskID(runID string, resourceID int64, action executor.Action) string {
+	return fmt.Sprintf("%s-%d-%s", runID, resourceID, action)
+}
+
+// Orchestrator 按依赖顺序编排多个资源的执行：apply 先上游后下游，destroy 反之。
+// 依赖都已完成的资源立即执行，互不依赖的资源并行；失败的资源不影响无关分支，
+// 但其下游 (destroy 时为上游) 资源不再执行。上游资源的属性作为参数注入下游配置
+type Orchestrator struct {
+	newExec     ExecutorFactory
+	resources   *dao.TerraformResourceDAO
+	deps        *dao.ResourceDependencyDAO
+	build       RequestBuilder
+	attrs       AttributeSource
+	store       AttributeStore
+	ids         IDGenerator
+	taskDAO     *dao.ExecutionTaskDAO
+	parallelism int
+}
+
+// NewOrchestrator 创建编排器
+func NewOrchestrator(newExec ExecutorFactory, resources *dao.TerraformResourceDAO, deps *dao.ResourceDependencyDAO, build RequestBuilder) *Orchestrator {
+	return &Orchestrator{
+		newExec:     newExec,
+		resources:   resources,
+		deps:        deps,
+		build:       build,
+		parallelism: DefaultParallelism,
+	}
+}
+
+// SetAttributeSource 设置已记录属性的来源，未设置时只能注入本次编排中 apply 产生的属性
+func (o *Orchestrator) SetAttributeSource(attrs AttributeSource) {
+	o.attrs = attrs
+}
+
+// SetAttributeStore 设置属性存储，apply 成功后保存资源的属性，destroy 成功后清除，同时作为已记录属性的来源
+func (o *Orchestrator) SetAttributeStore(store AttributeStore, ids IDGenerator) {
+	o.store, o.ids = store, ids
+	o.attrs = store
+}
+
+// SetTaskDAO 设置后资源任务作为 runID 的子任务记录
+func (o *Orchestrator) SetTaskDAO(taskDAO *dao.ExecutionTaskDAO) {
+	o.taskDAO = taskDAO
+}
+
+// SetParallelism 设置同时执行的资源数
+func (o *Orchestrator) SetParallelism(n int) {
+	if n > 0 {
+		o.parallelism = n
+	}
+}
+
+// Plan 返回 Apply 或 Destroy 将执行的资源层次
+func (o *Orchestrator) Plan(action executor.Action, resourceIDs ...int64) ([][]int64, error) {
+	graph, err := o.graph(action)
+	if err != nil {
+		return nil, err
+	}
+	return order(graph, action, resourceIDs)
+}
+
+// Apply 按依赖顺序 apply 资源及其全部上游资源
+func (o *Orchestrator) Apply(ctx context.Context, runID string, resourceIDs ...int64) (*RunResult, error) {
+	return o.run(ctx, runID, executor.ActionApply, resourceIDs)
+}
+
+// Destroy 按依赖的逆序销毁资源及其全部下游资源
+func (o *Orchestrator) Destroy(ctx context.Context, runID string, resourceIDs ...int64) (*RunResult, error) {
+	return o.run(ctx, runID, executor.ActionDestroy, resourceIDs)
+}
+
+// graph 加载依赖图
+func (o *Orchestrator) graph(action executor.Action) (*Graph, error) {
+	if action != executor.ActionApply && action != executor.ActionDestroy {
+		return nil, fmt.Errorf("unsupported orchestration action: %s", action)
+	}
+	deps, err := o.deps.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dependencies: %w", err)
+	}
+	return NewGraph(deps), nil
+}
+
+// order 返回动作涉及的资源层次，destroy 涉及全部下游资源并逆序执行
+func order(graph *Graph, action executor.Action, resourceIDs []int64) ([][]int64, error) {
+	if action == executor.ActionApply {
+		return graph.Levels(graph.Upstream(resourceIDs))
+	}
+	levels, err := graph.Levels(graph.Downstream(resourceIDs))
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(levels)-1; i < j; i, j = i+1, j-1 {
+		levels[i], levels[j] = levels[j], levels[i]
+	}
+	return levels, nil
+}
+
+func (o *Orchestrator) run(ctx context.Context, runID string, action executor.Action, resourceIDs []int64) (*RunResult, error) {
+	graph, err := o.graph(action)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := order(graph, action, resourceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RunResult{RunID: runID, Action: action, Order: levels, Nodes: make(map[int64]*NodeResult)}
+
+	// waiting 为资源执行前尚未完成的资源数，successors 为完成后可能就绪的资源，destroy 时依赖方向反转
+	in := make(map[int64]bool)
+	var nodes []int64
+	for _, level := range levels {
+		for _, id := range level {
+			in[id] = true
+			nodes = append(nodes, id)
+		}
+	}
+	waiting := make(map[int64]int)
+	successors := make(map[int64][]int64)
+	for _, id := range nodes {
+		seen := make(map[int64]bool)
+		for _, dep := range graph.Dependencies(id) {
+			up := dep.DependsOnID
+			if !in[up] || seen[up] {
+				continue
+			}
+			seen[up] = true
+			if action == executor.ActionDestroy {
+				waiting[up]++
+				successors[id] = append(successors[id], up)
+			} else {
+				waiting[id]++
+				successors[up] = append(successors[up], id)
+			}
+		}
+	}
+
+	var ready []int64
+	for _, id := range nodes {
+		if waiting[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	values := make(map[int64]map[string]string)
+	blocked := make(map[int64]string)
+	var resolve func(node *NodeResult)
+	resolve = func(node *NodeResult) {
+		result.Nodes[node.ResourceID] = node
+		if node.Status == executor.StatusSuccess && node.Result != nil && len(node.Result.Attributes) > 0 {
+			if _, err := o.values(values, node.ResourceID); err == nil {
+				for name, value := range node.Result.Attributes {
+					values[node.ResourceID][name] = value
+				}
+			}
+		}
+		for _, next := range successors[node.ResourceID] {
+			if node.Status != executor.StatusSuccess {
+				if _, ok := blocked[next]; !ok {
+					blocked[next] = fmt.Sprintf("resource %d was not %s", node.ResourceID, doneVerb(action))
+				}
+			}
+			waiting[next]--
+			if waiting[next] > 0 {
+				continue
+			}
+			if reason, ok := blocked[next]; ok {
+				resolve(&NodeResult{ResourceID: next, TaskID: TaskID(runID, next, action), Status: StatusSkipped, Error: reason})
+			} else {
+				ready = append(ready, next)
+			}
+		}
+	}
+
+	done := make(chan *NodeResult)
+	running := 0
+	for len(ready) > 0 || running > 0 {
+		for len(ready) > 0 && running < o.parallelism {
+			id := ready[0]
+			ready = ready[1:]
+			taskID := TaskID(runID, id, action)
+			if err := ctx.Err(); err != nil {
+				resolve(&NodeResult{ResourceID: id, TaskID: taskID, Status: StatusSkipped, Error: err.Error()})
+				continue
+			}
+			params, err := o.params(values, graph.Dependencies(id))
+			if err != nil {
+				resolve(&NodeResult{ResourceID: id, TaskID: taskID, Status: executor.StatusFailed, Error: err.Error()})
+				continue
+			}
+			running++
+			go func() {
+				done <- o.execute(ctx, runID, taskID, action, id, params)
+			}()
+		}
+		if running == 0 {
+			break
+		}
+		node := <-done
+		running--
+		if node.Status != executor.StatusSuccess {
+			logger.Warn("Orchestrated resource failed",
+				logger.String("run_id", runID),
+				logger.Int64("resource_id", node.ResourceID),
+				logger.String("error", node.Error))
+		}
+		resolve(node)
+	}
+
+	if !result.Success() {
+		return result, fmt.Errorf("orchestration %s did not complete", runID)
+	}
+	return result, nil
+}
+
+// execute 执行单个资源任务并保存执行后的 state
+func (o *Orchestrator) execute(ctx context.Context, runID, taskID string, action executor.Action, resourceID int64, params map[string]string) *NodeResult {
+	node := &NodeResult{ResourceID: resourceID, TaskID: taskID, Status: executor.StatusFailed}
+
+	resource, err := o.resources.Get(resourceID)
+	if err != nil {
+		node.Error = fmt.Sprintf("failed to load resource %d: %v", resourceID, err)
+		return node
+	}
+	if o.taskDAO != nil {
+		exists, err := o.taskDAO.Exists(taskID)
+		if err == nil && !exists {
+			_, err = o.taskDAO.CreateChild(taskID, runID, resourceID, string(action))
+		}
+		if err != nil {
+			node.Error = fmt.Sprintf("failed to create task: %v", err)
+			return node
+		}
+	}
+
+	req, err := o.build(taskID, action, resource)
+	if err != nil {
+		node.Error = fmt.Sprintf("failed to build request: %v", err)
+		return node
+	}
+	if len(params) > 0 {
+		if req.Params == nil {
+			req.Params = make(map[string]string, len(params))
+		}
+		for name, value := range params {
+			req.Params[name] = value
+		}
+	}
+
+	res, err := o.newExec().Execute(ctx, req)
+	node.Result = res
+	if res != nil && res.State != "" {
+		if saveErr := o.resources.UpdateTfState(resourceID, res.State); saveErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to save state: %w", saveErr))
+		}
+	}
+	if err == nil {
+		if saveErr := o.saveAttributes(action, resourceID, res); saveErr != nil {
+			err = fmt.Errorf("failed to save attributes: %w", saveErr)
+		}
+	}
+	if err != nil {
+		node.Error = err.Error()
+		return node
+	}
+	node.Status = executor.StatusSuccess
+	return node
+}
+
+// saveAttributes 保存 apply 产生的属性为资源级属性，destroy 后清除
+func (o *Orchestrator) saveAttributes(action executor.Action, resourceID int64, res *executor.ExecuteResult) error {
+	if o.store == nil {
+		return nil
+	}
+	var attrs []models.TerraformResourceAttribute
+	if action == executor.ActionApply && res != nil {
+		for name, value := range res.Attributes {
+			attrs = append(attrs, models.TerraformResourceAttribute{
+				ID:             o.ids(),
+				ResourceId:     resourceID,
+				AttributeName:  name,
+				AttributeValue: value,
+			})
+		}
+	}
+	return o.store.ReplaceResourceLevel(resourceID, attrs)
+}
+
+// params 根据依赖取上游资源的属性作为下游的参数
+func (o *Orchestrator) params(values map[int64]map[string]string, deps []models.ResourceDependency) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, dep := range deps {
+		if dep.Attribute == "" {
+			continue
+		}
+		upstream, err := o.values(values, dep.DependsOnID)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := upstream[dep.Attribute]
+		if !ok {
+			return nil, fmt.Errorf("upstream resource %d has no attribute %s", dep.DependsOnID, dep.Attribute)
+		}
+		name := dep.ParamName
+		if name == "" {
+			name = dep.Attribute
+		}
+		params[name] = value
+	}
+	return params, nil
+}
+
+// values 返回资源的属性，首次使用时加载已记录的属性，
+// 资源级属性优先于实例属性，MappedName 优先于 AttributeName
+func (o *Orchestrator) values(values map[int64]map[string]string, resourceID int64) (map[string]string, error) {
+	if v, ok := values[resourceID]; ok {
+		return v, nil
+	}
+	v := make(map[string]string)
+	if o.attrs != nil {
+		attrs, err := o.attrs.ListByResourceID(resourceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load attributes of resource %d: %w", resourceID, err)
+		}
+		for _, attr := range attrs {
+			if _, ok := v[attr.AttributeName]; !ok || attr.ResourceIndex == models.ResourceLevelIndex {
+				v[attr.AttributeName] = attr.AttributeValue
+			}
+		}
+		for _, attr := range attrs {
+			if attr.MappedName != "" {
+				v[attr.MappedName] = attr.AttributeValue
+			}
+		}
+	}
+	values[resourceID] = v
+	return v, nil
+}
+
+func doneVerb(action executor.Action) string {
+	if action == executor.ActionDestroy {
+		return "destroyed"
+	}
+	return "applied"
+}
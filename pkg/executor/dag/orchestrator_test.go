@@ -0,0 +1,208 @@
+package dag
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/executor/executortest"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeExecutor 共享的假执行器，apply 时输出 outputs 中的属性，fail 中的资源执行失败
+type fakeExecutor struct {
+	*executortest.Executor
+	outputs map[int64]map[string]string
+	fail    map[int64]bool
+}
+
+func (f *fakeExecutor) outcome(req *executor.ExecuteRequest) *executor.ExecuteResult {
+	if f.fail[req.ResourceID] {
+		return executortest.Failed(executor.ErrorUnknown, "boom")
+	}
+	result := &executor.ExecuteResult{Status: executor.StatusSuccess}
+	if req.Action == executor.ActionApply {
+		result.Attributes = f.outputs[req.ResourceID]
+	}
+	return result
+}
+
+// setupOrchestrator 创建依赖 vpc(1) <- subnet(2) <- sg(3) <- instance(4)，以及独立于 subnet 的 bucket(5) <- instance(4)
+func setupOrchestrator(t *testing.T) (*Orchestrator, *fakeExecutor, *gorm.DB) {
+	db := executortest.DB(t)
+	resources := dao.NewTerraformResourceDAO(db)
+	deps := dao.NewResourceDependencyDAO(db)
+	for id := int64(1); id <= 5; id++ {
+		require.NoError(t, resources.Create(&models.TerraformResource{ID: id, Provider: "aws", ResourceType: "test"}))
+	}
+	for _, dep := range []models.ResourceDependency{
+		{ResourceID: 2, DependsOnID: 1, Attribute: "vpc_id"},
+		{ResourceID: 3, DependsOnID: 2, Attribute: "vpc_id", ParamName: "sg_vpc_id"},
+		{ResourceID: 3, DependsOnID: 1},
+		{ResourceID: 4, DependsOnID: 3, Attribute: "aws_security_group.main.id", ParamName: "sg_id"},
+		{ResourceID: 4, DependsOnID: 5},
+	} {
+		require.NoError(t, deps.Create(&dep))
+	}
+
+	exec := &fakeExecutor{
+		Executor: &executortest.Executor{Delay: 10 * time.Millisecond},
+		outputs: map[int64]map[string]string{
+			1: {"vpc_id": "vpc-1"},
+			2: {"vpc_id": "vpc-1"},
+			3: {"aws_security_group.main.id": "sg-1"},
+		},
+		fail: map[int64]bool{},
+	}
+	exec.Outcome = exec.outcome
+	o := NewOrchestrator(func() executor.Executor { return exec }, resources, deps,
+		func(taskID string, action executor.Action, resource *models.TerraformResource) (*executor.ExecuteRequest, error) {
+			return &executor.ExecuteRequest{TaskID: taskID, ResourceID: resource.ID, Action: action}, nil
+		})
+	return o, exec, db
+}
+
+func TestOrchestrator_Apply(t *testing.T) {
+	o, exec, db := setupOrchestrator(t)
+	taskDAO := dao.NewExecutionTaskDAO(db)
+	o.SetTaskDAO(taskDAO)
+
+	result, err := o.Apply(context.Background(), "run-1", 4)
+	require.NoError(t, err)
+	assert.True(t, result.Success())
+	assert.Equal(t, [][]int64{{1, 5}, {2}, {3}, {4}}, result.Order)
+	assert.Equal(t, 2, exec.Peak(), "independent resources should run in parallel")
+
+	order := exec.ResourceIDs()
+	assert.ElementsMatch(t, []int64{1, 5}, order[:2])
+	assert.Equal(t, []int64{2, 3, 4}, order[2:])
+
+	assert.Equal(t, map[string]string{"vpc_id": "vpc-1"}, exec.Request(2).Params)
+	assert.Equal(t, map[string]string{"sg_vpc_id": "vpc-1"}, exec.Request(3).Params)
+	assert.Equal(t, map[string]string{"sg_id": "sg-1"}, exec.Request(4).Params)
+
+	resource, _ := dao.NewTerraformResourceDAO(db).Get(4)
+	assert.Equal(t, executortest.State(TaskID("run-1", 4, executor.ActionApply)), resource.TfState)
+	tasks, _ := taskDAO.ListChildren("run-1")
+	assert.Len(t, tasks, 5)
+	assert.Equal(t, TaskID("run-1", 1, executor.ActionApply), result.Nodes[1].TaskID)
+}
+
+func TestOrchestrator_ApplyFailureSkipsDownstream(t *testing.T) {
+	o, exec, _ := setupOrchestrator(t)
+	exec.fail[2] = true
+
+	result, err := o.Apply(context.Background(), "run-1", 4)
+	assert.Error(t, err)
+	assert.Equal(t, executor.StatusSuccess, result.Nodes[1].Status)
+	assert.Equal(t, executor.StatusSuccess, result.Nodes[5].Status, "unrelated branch should still run")
+	assert.Equal(t, executor.StatusFailed, result.Nodes[2].Status)
+	assert.Equal(t, StatusSkipped, result.Nodes[3].Status)
+	assert.Equal(t, StatusSkipped, result.Nodes[4].Status)
+	assert.ElementsMatch(t, []int64{1, 5, 2}, exec.ResourceIDs())
+}
+
+func TestOrchestrator_BuildFailure(t *testing.T) {
+	o, exec, _ := setupOrchestrator(t)
+	o.build = func(taskID string, action executor.Action, resource *models.TerraformResource) (*executor.ExecuteRequest, error) {
+		if resource.ID == 2 {
+			return nil, fmt.Errorf("db down")
+		}
+		return &executor.ExecuteRequest{TaskID: taskID, ResourceID: resource.ID, Action: action}, nil
+	}
+
+	// 请求构建失败的资源不执行，下游跳过
+	result, err := o.Apply(context.Background(), "run-1", 3)
+	assert.Error(t, err)
+	assert.Contains(t, result.Nodes[2].Error, "failed to build request: db down")
+	assert.Equal(t, StatusSkipped, result.Nodes[3].Status)
+	assert.Equal(t, []int64{1}, exec.ResourceIDs())
+}
+
+func TestOrchestrator_RecordedAttributes(t *testing.T) {
+	o, exec, db := setupOrchestrator(t)
+	attrs := dao.NewTerraformResourceAttributeDAO(db)
+	o.SetAttributeSource(attrs)
+	require.NoError(t, attrs.Create(&models.TerraformResourceAttribute{ID: 1, ResourceId: 3, AttributeName: "id", AttributeValue: "sg-9", MappedName: "aws_security_group.main.id"}))
+	require.NoError(t, attrs.Create(&models.TerraformResourceAttribute{ID: 2, ResourceId: 2, AttributeName: "vpc_id", AttributeValue: "vpc-9"}))
+	require.NoError(t, attrs.Create(&models.TerraformResourceAttribute{ID: 3, ResourceId: 1, AttributeName: "id", AttributeValue: "vpc-9", MappedName: "vpc_id"}))
+
+	// 没有本次 apply 的结果时使用已记录的属性
+	exec.outputs = nil
+	_, err := o.Apply(context.Background(), "run-1", 4)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"sg_vpc_id": "vpc-9"}, exec.Request(3).Params)
+	assert.Equal(t, map[string]string{"sg_id": "sg-9"}, exec.Request(4).Params)
+	assert.Equal(t, map[string]string{"vpc_id": "vpc-9"}, exec.Request(2).Params)
+
+	// 缺少属性时资源失败
+	o.SetAttributeSource(nil)
+	exec.Clear()
+	result, err := o.Apply(context.Background(), "run-2", 2)
+	assert.Error(t, err)
+	assert.Contains(t, result.Nodes[2].Error, "upstream resource 1 has no attribute vpc_id")
+	assert.Equal(t, []int64{1}, exec.ResourceIDs())
+}
+
+func TestOrchestrator_Destroy(t *testing.T) {
+	o, exec, db := setupOrchestrator(t)
+	// 销毁时上游资源仍存在，参数来自 apply 时保存的属性
+	attrs := dao.NewTerraformResourceAttributeDAO(db)
+	var next int64
+	o.SetAttributeStore(attrs, func() int64 { next++; return next })
+	require.NoError(t, attrs.Create(&models.TerraformResourceAttribute{ID: 100, ResourceId: 3, AttributeName: "aws_security_group.main.id", AttributeValue: "sg-old"}))
+	exec.outputs[4] = map[string]string{"id": "i-1"}
+	_, err := o.Apply(context.Background(), "run-0", 4)
+	require.NoError(t, err)
+	saved, err := attrs.ListByResourceAndIndex(3, models.ResourceLevelIndex)
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, "sg-1", saved[0].AttributeValue)
+
+	exec.Clear()
+	exec.fail[3] = true
+	levels, err := o.Plan(executor.ActionDestroy, 2)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int64{{4}, {3}, {2}}, levels)
+
+	// 销毁 subnet 前先销毁依赖它的资源，sg 失败后 subnet 保留
+	o.SetParallelism(1)
+	result, err := o.Destroy(context.Background(), "run-1", 2)
+	assert.Error(t, err)
+	assert.Equal(t, []int64{4, 3}, exec.ResourceIDs())
+	assert.Equal(t, StatusSkipped, result.Nodes[2].Status)
+	assert.Equal(t, executor.ActionDestroy, exec.Requests()[0].Action)
+	assert.Equal(t, map[string]string{"sg_id": "sg-1"}, exec.Requests()[0].Params)
+
+	// 销毁成功后清除保存的属性，实例属性保留
+	saved, _ = attrs.ListByResourceAndIndex(4, models.ResourceLevelIndex)
+	assert.Empty(t, saved)
+	saved, _ = attrs.ListByResourceAndIndex(1, models.ResourceLevelIndex)
+	assert.Len(t, saved, 1)
+	saved, _ = attrs.ListByResourceAndIndex(3, models.ResourceLevelIndex)
+	assert.Len(t, saved, 1)
+	rest, _ := attrs.ListByResourceID(3)
+	assert.Len(t, rest, 2)
+}
+
+func TestGraph_Levels(t *testing.T) {
+	g := NewGraph([]models.ResourceDependency{
+		{ResourceID: 2, DependsOnID: 1},
+		{ResourceID: 3, DependsOnID: 2},
+		{ResourceID: 1, DependsOnID: 3},
+		{ResourceID: 4, DependsOnID: 1},
+	})
+	_, err := g.Levels([]int64{1, 2, 3, 4})
+	assert.ErrorContains(t, err, "dependency cycle among resources [1 2 3 4]")
+
+	levels, err := g.Levels([]int64{1, 2, 4})
+	require.NoError(t, err)
+	assert.Equal(t, [][]int64{{1}, {2, 4}}, levels)
+	assert.Equal(t, []int64{1, 2, 3, 4}, g.Downstream([]int64{2}))
+}
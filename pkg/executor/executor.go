@@ -3,17 +3,29 @@ package executor
 
 import (
 	"context"
+	"encoding/json"
 )
 
 // Action 执行动作
 type Action string
 
 const (
-	ActionInit    Action = "init"
-	ActionPlan    Action = "plan"
-	ActionApply   Action = "apply"
-	ActionDestroy Action = "destroy"
-	ActionImport  Action = "import"
+	ActionInit     Action = "init"
+	ActionValidate Action = "validate"
+	ActionPlan     Action = "plan"
+	ActionApply    Action = "apply"
+	ActionDestroy  Action = "destroy"
+	ActionImport   Action = "import"
+	ActionOutput   Action = "output"
+)
+
+// RollbackMode apply 失败后的回滚方式
+type RollbackMode string
+
+const (
+	RollbackNone    RollbackMode = ""        // 不回滚
+	RollbackDestroy RollbackMode = "destroy" // 以 -target 销毁本次任务创建的资源
+	RollbackReapply RollbackMode = "reapply" // 重新 apply 上一版本的配置
 )
 
 // Status 执行状态
@@ -27,32 +39,134 @@ const (
 	StatusCancelled Status = "cancelled"
 )
 
+// ErrorCode 失败分类，取值稳定，供调用方按类型处理
+type ErrorCode string
+
+const (
+	ErrorAuth          ErrorCode = "auth_failed"    // 凭证无效或权限不足
+	ErrorQuota         ErrorCode = "quota_exceeded" // 配额或库存不足
+	ErrorNotFound      ErrorCode = "not_found"      // 依赖的资源不存在
+	ErrorConflict      ErrorCode = "conflict"       // 资源已存在或被占用
+	ErrorRateLimited   ErrorCode = "rate_limited"   // 接口限流
+	ErrorTimeout       ErrorCode = "timeout"        // 执行超时
+	ErrorProviderCrash ErrorCode = "provider_crash" // provider 插件崩溃
+	ErrorConfigInvalid ErrorCode = "config_invalid" // 配置错误
+	ErrorCancelled     ErrorCode = "cancelled"      // 被取消
+	ErrorUnknown       ErrorCode = "unknown"        // 未能分类
+)
+
+// Retryable 判断该类失败是否为暂时性的，默认自动重试。
+// 取消由用户发起不重试，未分类的失败是否重试由重试策略决定
+func (c ErrorCode) Retryable() bool {
+	switch c {
+	case ErrorRateLimited, ErrorTimeout, ErrorProviderCrash:
+		return true
+	}
+	return false
+}
+
+// Permanent 判断该类失败重试是否不可能成功，凭证、配额、配置等问题需要人工处理
+func (c ErrorCode) Permanent() bool {
+	switch c {
+	case ErrorAuth, ErrorQuota, ErrorNotFound, ErrorConflict, ErrorConfigInvalid:
+		return true
+	}
+	return false
+}
+
 // ExecuteRequest 执行请求
 type ExecuteRequest struct {
-	TaskID     string            // 任务ID
-	ResourceID int64             // 资源ID
-	Action     Action            // 执行动作
-	WorkDir    string            // 工作目录
-	Config     string            // 配置内容
-	Params     map[string]string // 额外参数
+	TaskID      string            // 任务ID
+	ResourceID  int64             // 资源ID
+	Action      Action            // 执行动作
+	WorkDir     string            // 工作目录
+	Config      string            // 配置内容
+	Params      map[string]string // 额外参数，以 TF_VAR_<name> 注入
+	Sensitive   []string          // 敏感参数名
+	Provider    string            // 云厂商 (aws, tencentcloud, alicloud)
+	Tenant      string            // 租户
+	Credential  string            // 云账号凭证名称，由凭证存储解析
+	Credentials map[string]string // provider 凭证字段，覆盖凭证存储中的同名字段
+
+	Initialized    bool         // 工作目录已由前序步骤 init，不再重复 init
+	Locked         bool         // 资源锁已由调用方持有，如流水线从 plan 到 apply 持有锁，执行器不再加锁
+	PlanFile       string       // plan 保存的计划文件，apply 时直接应用，相对于工作目录
+	State          string       // 执行前的 tfstate，为空表示新资源
+	Rollback       RollbackMode // apply 失败后的回滚方式
+	PreviousConfig string       // 上一次成功 apply 的配置，RollbackReapply 时使用
 }
 
 // ExecuteResult 执行结果
 type ExecuteResult struct {
-	TaskID     string
-	Status     Status
-	Output     string            // 完整输出
-	Error      string            // 错误信息
-	Duration   int64             // 执行时长(ms)
-	Attributes map[string]string // 提取的属性
+	TaskID      string
+	Status      Status
+	Output      string            // 完整输出
+	Error       string            // 错误信息
+	ErrorCode   ErrorCode         // 失败分类，成功时为空
+	Retryable   bool              // 失败是否默认自动重试
+	Duration    int64             // 执行时长(ms)
+	Attributes  map[string]string // 提取的属性
+	Resources   []ResourceOutcome // 各资源的执行结果，apply/destroy 失败时据此判断哪些资源已变更
+	Diagnostics []Diagnostic      // 执行过程中的错误与警告
+	State       string            `json:"-"` // 执行后的 tfstate，由调用方保存，不随完成消息发出
+	Rollback    *ExecuteResult    // 回滚子任务的结果，未回滚时为空
+}
+
+// Diagnostic 诊断信息，如 Terraform 的错误与弃用警告
+type Diagnostic struct {
+	Severity string          // error, warning
+	Summary  string          // 摘要
+	Detail   string          // 详细信息
+	Address  string          // 相关资源地址
+	Filename string          // 配置文件
+	Line     int             // 起始行
+	Raw      json.RawMessage // 执行器原始的结构化诊断，如 Terraform 的 range 与 snippet
+}
+
+// Outcome 资源执行结果
+type Outcome string
+
+const (
+	OutcomeCreated     Outcome = "created"
+	OutcomeUpdated     Outcome = "updated"
+	OutcomeDestroyed   Outcome = "destroyed"
+	OutcomeReplaced    Outcome = "replaced"
+	OutcomeRead        Outcome = "read"
+	OutcomeFailed      Outcome = "failed"
+	OutcomeInterrupted Outcome = "interrupted" // 执行中被中断，实际状态未知
+	OutcomeSkipped     Outcome = "skipped"     // 计划变更但未执行
+)
+
+// ResourceOutcome 单个资源在任务中的执行结果
+type ResourceOutcome struct {
+	Address  string  // 资源地址
+	Type     string  // 资源类型
+	Action   string  // 计划动作 create, update, delete, replace, read
+	Outcome  Outcome // 执行结果
+	ID       string  // 云上资源 ID，完成时由 Terraform 返回
+	Duration int64   // 执行时长(ms)
+	Error    string  // 失败原因
 }
 
 // Progress 进度信息
 type Progress struct {
-	Phase   string // 当前阶段
-	Percent int    // 百分比 0-100
+	Phase     string             // 当前阶段
+	Percent   int                // 百分比 0-100
+	Elapsed   int64              // 已用时间(ms)
+	Message   string             // 当前消息
+	Planned   int                // 计划变更的资源数
+	Completed int                // 已完成变更的资源数
+	ETA       int64              // 预计剩余时间(ms)，0 表示未知
+	Resources []ResourceProgress // 各资源的执行进度
+}
+
+// ResourceProgress 单个资源的执行进度
+type ResourceProgress struct {
+	Address string // 资源地址，如 aws_instance.web
+	Type    string // 资源类型
+	Action  string // create, update, delete, replace
+	Status  string // pending, running, complete, errored
 	Elapsed int64  // 已用时间(ms)
-	Message string // 当前消息
 }
 
 // Executor 执行器接口
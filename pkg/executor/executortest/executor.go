@@ -0,0 +1,167 @@
+// Package executortest provides a fake executor for tests of packages that drive executors.
+package executortest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Executor 记录收到的请求与并发数，执行结果由 Outcome 决定，默认成功。
+// 结果未设置 State 时返回 State(taskID)，便于断言调用方保存了执行后的 state
+type Executor struct {
+	TaskDAO *dao.ExecutionTaskDAO // 设置后像真实执行器一样记录任务状态与尝试
+	Delay   time.Duration         // 每次执行的耗时，用于观察并发
+	Outcome func(req *executor.ExecuteRequest) *executor.ExecuteResult
+
+	mu       sync.Mutex
+	requests []executor.ExecuteRequest
+	running  int
+	peak     int
+}
+
+// State 返回任务执行后的 tfstate
+func State(taskID string) string {
+	return fmt.Sprintf(`{"version":4,"serial":1,"lineage":%q}`, taskID)
+}
+
+// Failed 返回失败的执行结果
+func Failed(code executor.ErrorCode, message string) *executor.ExecuteResult {
+	return &executor.ExecuteResult{
+		Status:    executor.StatusFailed,
+		Error:     message,
+		ErrorCode: code,
+		Retryable: code.Retryable(),
+	}
+}
+
+// DB 打开测试用的内存数据库
+func DB(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	return db
+}
+
+func (e *Executor) Type() string                    { return "fake" }
+func (e *Executor) Validate(config string) error    { return nil }
+func (e *Executor) GetProgress() *executor.Progress { return &executor.Progress{} }
+func (e *Executor) Cancel() error                   { return nil }
+
+// Execute 记录请求并返回 Outcome 决定的结果，失败时同时返回错误
+func (e *Executor) Execute(ctx context.Context, req *executor.ExecuteRequest) (*executor.ExecuteResult, error) {
+	e.mu.Lock()
+	e.requests = append(e.requests, *req)
+	e.running++
+	e.peak = max(e.peak, e.running)
+	e.mu.Unlock()
+
+	time.Sleep(e.Delay)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.running--
+
+	var result *executor.ExecuteResult
+	if e.Outcome != nil {
+		result = e.Outcome(req)
+	}
+	if result == nil {
+		result = &executor.ExecuteResult{Status: executor.StatusSuccess}
+	}
+	result.TaskID = req.TaskID
+	if result.State == "" {
+		result.State = State(req.TaskID)
+	}
+
+	success := result.Status == executor.StatusSuccess
+	if e.TaskDAO != nil {
+		e.TaskDAO.Start(req.TaskID)
+		e.TaskDAO.Complete(req.TaskID, success, result.Output, result.Error)
+		if !success {
+			e.TaskDAO.SetErrorCode(req.TaskID, string(result.ErrorCode), !result.ErrorCode.Permanent())
+		}
+		e.TaskDAO.RecordAttempt(req.TaskID)
+	}
+	if !success {
+		message := result.Error
+		if message == "" {
+			message = "failed"
+		}
+		return result, errors.New(message)
+	}
+	return result, nil
+}
+
+// Requests 返回收到的请求
+func (e *Executor) Requests() []executor.ExecuteRequest {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]executor.ExecuteRequest(nil), e.requests...)
+}
+
+// Request 返回资源收到的第一个请求，没有时返回 nil
+func (e *Executor) Request(resourceID int64) *executor.ExecuteRequest {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := range e.requests {
+		if e.requests[i].ResourceID == resourceID {
+			req := e.requests[i]
+			return &req
+		}
+	}
+	return nil
+}
+
+// Actions 按执行顺序返回请求的动作
+func (e *Executor) Actions() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	actions := make([]string, 0, len(e.requests))
+	for _, req := range e.requests {
+		actions = append(actions, string(req.Action))
+	}
+	return actions
+}
+
+// ResourceIDs 按执行顺序返回请求的资源
+func (e *Executor) ResourceIDs() []int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ids := make([]int64, 0, len(e.requests))
+	for _, req := range e.requests {
+		ids = append(ids, req.ResourceID)
+	}
+	return ids
+}
+
+// Calls 返回执行次数
+func (e *Executor) Calls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.requests)
+}
+
+// Peak 返回最大并发执行数
+func (e *Executor) Peak() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.peak
+}
+
+// Clear 清空已记录的请求
+func (e *Executor) Clear() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests = nil
+}
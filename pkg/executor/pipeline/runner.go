@@ -0,0 +1,490 @@
+// Package pipeline runs multi-step pipelines whose steps are execution tasks.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/executor/lock"
+	"github.com/cylonchau/prism/pkg/executor/workspace"
+	"github.com/cylonchau/prism/pkg/logger"
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// planFile 流水线中 plan 步骤保存的计划文件，apply 步骤直接应用
+const planFile = "tfplan"
+
+// DefaultSteps validate → plan → policy → approval → apply → output
+var DefaultSteps = []string{
+	models.StepValidate,
+	models.StepPlan,
+	models.StepPolicy,
+	models.StepApproval,
+	models.StepApply,
+	models.StepOutput,
+}
+
+// RequestBuilder 根据步骤任务构建执行请求，如加载资源配置与凭证
+type RequestBuilder func(task *models.ExecutionTask) (*executor.ExecuteRequest, error)
+
+// PolicyChecker 检查 plan 步骤保存的计划，返回错误表示不符合策略
+type PolicyChecker interface {
+	Check(ctx context.Context, pipeline *models.Pipeline, workDir, planFile string) error
+}
+
+// Runner 按顺序执行流水线步骤。步骤共享工作目录，只在第一个执行器步骤 init；
+// 遇到审批步骤时暂停，失败的步骤可单独重试，已成功的步骤不再执行。
+// apply 与 destroy 后的 state 保存到资源，工作目录清理后不会丢失
+type Runner struct {
+	exec      executor.Executor
+	taskDAO   *dao.ExecutionTaskDAO
+	pipelines *dao.PipelineDAO
+	resources *dao.TerraformResourceDAO
+	workspace *workspace.Manager
+	build     RequestBuilder
+	policy    PolicyChecker
+	locker    lock.LockManager
+
+	mu     sync.Mutex
+	active map[string]bool // 本实例正在执行的流水线
+}
+
+// NewRunner 创建流水线执行器，工作目录创建在 basePath 下
+func NewRunner(exec executor.Executor, taskDAO *dao.ExecutionTaskDAO, pipelines *dao.PipelineDAO, resources *dao.TerraformResourceDAO, basePath string, build RequestBuilder) *Runner {
+	return &Runner{
+		exec:      exec,
+		taskDAO:   taskDAO,
+		pipelines: pipelines,
+		resources: resources,
+		workspace: workspace.NewManager(basePath),
+		build:     build,
+		active:    make(map[string]bool),
+	}
+}
+
+// SetPolicyChecker 设置策略检查，未设置时策略步骤直接通过
+func (r *Runner) SetPolicyChecker(policy PolicyChecker) {
+	r.policy = policy
+}
+
+// SetLocker 设置资源锁。流水线从开始执行到结束（包括等待审批）以流水线 ID 持有资源锁，
+// 期间其他任务无法修改状态。锁的过期时间需覆盖等待审批的时间，锁过期后计划可能已过期，
+// 流水线从 plan 重新执行并再次审批
+func (r *Runner) SetLocker(locker lock.LockManager) {
+	r.locker = locker
+}
+
+// Create 创建流水线，steps 为空时使用 DefaultSteps
+func (r *Runner) Create(pipelineID string, resourceID int64, steps []string) (*models.Pipeline, error) {
+	if len(steps) == 0 {
+		steps = DefaultSteps
+	}
+	for _, kind := range steps {
+		if !validStep(kind) {
+			return nil, fmt.Errorf("unsupported pipeline step: %s", kind)
+		}
+	}
+	pipeline := &models.Pipeline{PipelineID: pipelineID, ResourceID: resourceID}
+	if _, err := r.pipelines.Create(pipeline, steps); err != nil {
+		return nil, fmt.Errorf("failed to create pipeline: %w", err)
+	}
+	return pipeline, nil
+}
+
+// Run 从第一个未成功的步骤开始执行，直到全部成功、某一步失败或等待审批
+func (r *Runner) Run(ctx context.Context, pipelineID string) (*models.Pipeline, error) {
+	if !r.begin(pipelineID) {
+		return nil, fmt.Errorf("pipeline %s is already running", pipelineID)
+	}
+	defer r.end(pipelineID)
+
+	pipeline, err := r.pipelines.Get(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	switch pipeline.Status {
+	case models.PipelineStatusRunning:
+		// 持有锁说明仍在其他实例执行，否则上次执行已中断（如实例重启）
+		if r.holds(pipeline) {
+			return pipeline, fmt.Errorf("pipeline %s is already running", pipelineID)
+		}
+	case models.PipelineStatusSuccess, models.PipelineStatusRejected:
+		return pipeline, fmt.Errorf("pipeline %s is %s", pipelineID, pipeline.Status)
+	}
+
+	steps, err := r.taskDAO.ListChildren(pipelineID)
+	if err != nil {
+		return pipeline, err
+	}
+	fresh, err := r.prepareWorkDir(pipeline)
+	if err != nil {
+		return pipeline, err
+	}
+	relocked, err := r.lock(ctx, pipeline)
+	if err != nil {
+		return r.finish(pipeline, models.PipelineStatusFailed, pipeline.Step, err)
+	}
+	if fresh || relocked {
+		// 计划文件已丢失，或锁曾被释放、状态可能已被其他任务修改
+		if err := r.replan(pipeline, steps); err != nil {
+			return pipeline, err
+		}
+	}
+
+	initialized, planned := false, false
+	for i := range steps {
+		step := &steps[i]
+		if step.Status == models.TaskStatusSuccess {
+			// 重建的工作目录中尚未 init
+			initialized = initialized || (executes(step.Action) && !fresh)
+			planned = planned || step.Action == models.StepPlan
+			continue
+		}
+		if step.Status == models.TaskStatusRunning {
+			// 上次执行中断，步骤结果未知，标记为失败后由 Retry 重新执行
+			r.taskDAO.Complete(step.TaskID, false, "", "interrupted")
+			return r.finish(pipeline, models.PipelineStatusFailed, step.Step, fmt.Errorf("step %d (%s) was interrupted", step.Step, step.Action))
+		}
+		if step.Status != models.TaskStatusPending {
+			// 失败的步骤需通过 Retry 重新执行
+			return r.finish(pipeline, models.PipelineStatusFailed, step.Step, fmt.Errorf("step %d (%s) has failed", step.Step, step.Action))
+		}
+
+		if step.Action == models.StepApproval {
+			logger.Info("Pipeline waiting for approval", logger.String("pipeline_id", pipelineID), logger.Int("step", step.Step))
+			return r.finish(pipeline, models.PipelineStatusWaiting, step.Step, nil)
+		}
+		if err := r.pipelines.UpdateStatus(pipelineID, models.PipelineStatusRunning, step.Step, ""); err != nil {
+			return pipeline, err
+		}
+
+		if step.Action == models.StepPolicy {
+			err = r.checkPolicy(ctx, pipeline, step, planned)
+		} else {
+			err = r.execute(ctx, pipeline, step, initialized, planned)
+			initialized = true
+		}
+		if err != nil {
+			return r.finish(pipeline, models.PipelineStatusFailed, step.Step, err)
+		}
+		planned = planned || step.Action == models.StepPlan
+	}
+
+	last := 0
+	if len(steps) > 0 {
+		last = steps[len(steps)-1].Step
+	}
+	r.workspace.Clean(pipeline.WorkDir)
+	return r.finish(pipeline, models.PipelineStatusSuccess, last, nil)
+}
+
+// Approve 通过当前等待的审批步骤并继续执行
+func (r *Runner) Approve(ctx context.Context, pipelineID, approver string) (*models.Pipeline, error) {
+	step, err := r.waitingStep(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	r.taskDAO.Start(step.TaskID)
+	if err := r.taskDAO.Complete(step.TaskID, true, "Approved by "+approver, ""); err != nil {
+		return nil, err
+	}
+	if err := r.pipelines.SetApprover(pipelineID, approver); err != nil {
+		return nil, err
+	}
+	if err := r.pipelines.UpdateStatus(pipelineID, models.PipelineStatusPending, step.Step, ""); err != nil {
+		return nil, err
+	}
+	return r.Run(ctx, pipelineID)
+}
+
+// Reject 拒绝当前等待的审批步骤，流水线结束且不可重试
+func (r *Runner) Reject(pipelineID, approver, reason string) (*models.Pipeline, error) {
+	step, err := r.waitingStep(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := r.pipelines.Get(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	msg := "Rejected by " + approver
+	if reason != "" {
+		msg += ": " + reason
+	}
+	r.taskDAO.Start(step.TaskID)
+	if err := r.taskDAO.Complete(step.TaskID, false, "", msg); err != nil {
+		return nil, err
+	}
+	if err := r.pipelines.SetApprover(pipelineID, approver); err != nil {
+		return nil, err
+	}
+	r.workspace.Clean(pipeline.WorkDir)
+	pipeline, _ = r.finish(pipeline, models.PipelineStatusRejected, step.Step, fmt.Errorf("%s", msg))
+	return pipeline, nil
+}
+
+// Retry 重置失败或中断的步骤并从该步骤继续执行，之前成功的步骤不再执行。
+// 重试 apply 时从 plan 重新执行，新的计划需再次通过策略检查与审批
+func (r *Runner) Retry(ctx context.Context, pipelineID string) (*models.Pipeline, error) {
+	pipeline, err := r.pipelines.Get(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	if pipeline.Status != models.PipelineStatusFailed {
+		return pipeline, fmt.Errorf("pipeline %s is not failed", pipelineID)
+	}
+	steps, err := r.taskDAO.ListChildren(pipelineID)
+	if err != nil {
+		return pipeline, err
+	}
+	for _, step := range steps {
+		switch step.Status {
+		case models.TaskStatusFailed, models.TaskStatusCancelled, models.TaskStatusRunning:
+		default:
+			continue
+		}
+		if err := r.taskDAO.Reset(step.TaskID); err != nil {
+			return pipeline, err
+		}
+		if step.Action == models.StepApply {
+			if err := r.replan(pipeline, steps); err != nil {
+				return pipeline, err
+			}
+		}
+		break
+	}
+	if err := r.pipelines.UpdateStatus(pipelineID, models.PipelineStatusPending, pipeline.Step, ""); err != nil {
+		return pipeline, err
+	}
+	return r.Run(ctx, pipelineID)
+}
+
+// execute 以执行器运行步骤
+func (r *Runner) execute(ctx context.Context, pipeline *models.Pipeline, step *models.ExecutionTask, initialized, planned bool) error {
+	req, err := r.build(step)
+	if err != nil {
+		r.taskDAO.Start(step.TaskID)
+		r.taskDAO.Complete(step.TaskID, false, "", err.Error())
+		return fmt.Errorf("failed to build request for step %d (%s): %w", step.Step, step.Action, err)
+	}
+	req.TaskID = step.TaskID
+	req.ResourceID = pipeline.ResourceID
+	req.Action = executor.Action(step.Action)
+	req.WorkDir = pipeline.WorkDir
+	req.Initialized = initialized
+	req.Locked = r.locker != nil
+	if initialized {
+		// 状态已在工作目录中，由前序步骤更新
+		req.State = ""
+	}
+	if step.Action == models.StepPlan || (step.Action == models.StepApply && planned) {
+		// apply 只应用已通过策略检查与审批的计划
+		req.PlanFile = planFile
+	}
+
+	result, err := r.exec.Execute(ctx, req)
+	if err == nil && result != nil && result.Status != executor.StatusSuccess {
+		err = fmt.Errorf("%s", result.Error)
+	}
+	// 失败的 apply 也可能已变更部分资源，state 同样需要保存
+	if (req.Action == executor.ActionApply || req.Action == executor.ActionDestroy) && result != nil && result.State != "" {
+		if saveErr := r.resources.UpdateTfState(pipeline.ResourceID, result.State); saveErr != nil {
+			saveErr = fmt.Errorf("failed to save state: %w", saveErr)
+			if err == nil {
+				// 步骤标记为失败，重试时从工作目录中的 state 重新执行并保存
+				r.taskDAO.Complete(step.TaskID, false, "", saveErr.Error())
+			}
+			err = errors.Join(err, saveErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("step %d (%s) failed: %w", step.Step, step.Action, err)
+	}
+	return nil
+}
+
+// replan 重置 plan 及其后直到 apply 的步骤，使 apply 应用重新生成、检查并审批的计划。
+// apply 已成功时计划不再使用，不重置
+func (r *Runner) replan(pipeline *models.Pipeline, steps []models.ExecutionTask) error {
+	from := -1
+	for i := range steps {
+		switch {
+		case steps[i].Action == models.StepPlan && steps[i].Status != models.TaskStatusPending:
+			from = i
+		case steps[i].Action == models.StepApply && from >= 0:
+			if steps[i].Status == models.TaskStatusSuccess {
+				from = -1
+				continue
+			}
+			for j := from; j <= i; j++ {
+				if err := r.taskDAO.Reset(steps[j].TaskID); err != nil {
+					return err
+				}
+				steps[j].Status = models.TaskStatusPending
+			}
+			logger.Info("Pipeline plan reset",
+				logger.String("pipeline_id", pipeline.PipelineID),
+				logger.Int("step", steps[from].Step))
+			return r.pipelines.SetApprover(pipeline.PipelineID, "")
+		}
+	}
+	return nil
+}
+
+// prepareWorkDir 创建流水线的工作目录。工作目录位于本机，在其他实例或重启后可能不存在，
+// 此时重新创建并返回 true，之前步骤的 init 与计划文件需重新生成
+func (r *Runner) prepareWorkDir(pipeline *models.Pipeline) (bool, error) {
+	missing := false
+	if pipeline.WorkDir != "" {
+		if _, err := os.Stat(pipeline.WorkDir); err == nil {
+			return false, nil
+		}
+		logger.Warn("Pipeline work dir missing, recreating",
+			logger.String("pipeline_id", pipeline.PipelineID),
+			logger.String("work_dir", pipeline.WorkDir))
+		missing = true
+	}
+	workDir, err := r.workspace.Create("pipeline", "default", strconv.FormatInt(pipeline.ResourceID, 10), pipeline.PipelineID)
+	if err != nil {
+		return false, err
+	}
+	if err := r.pipelines.SetWorkDir(pipeline.PipelineID, workDir); err != nil {
+		return false, err
+	}
+	pipeline.WorkDir = workDir
+	return missing, nil
+}
+
+// lock 获取资源锁，已持有时直接返回。返回 true 表示重新获取了锁
+func (r *Runner) lock(ctx context.Context, pipeline *models.Pipeline) (bool, error) {
+	if r.locker == nil || r.holds(pipeline) {
+		return false, nil
+	}
+	if err := r.locker.Acquire(ctx, pipeline.ResourceID, pipeline.PipelineID); err != nil {
+		return false, fmt.Errorf("failed to lock resource %d: %w", pipeline.ResourceID, err)
+	}
+	return true, nil
+}
+
+// unlock 释放流水线持有的资源锁
+func (r *Runner) unlock(pipeline *models.Pipeline) {
+	if !r.holds(pipeline) {
+		return
+	}
+	if err := r.locker.Release(pipeline.ResourceID); err != nil {
+		logger.Warn("Failed to release pipeline lock",
+			logger.String("pipeline_id", pipeline.PipelineID),
+			logger.Err(err))
+	}
+}
+
+// holds 判断流水线是否持有未过期的资源锁
+func (r *Runner) holds(pipeline *models.Pipeline) bool {
+	if r.locker == nil {
+		return false
+	}
+	status, err := r.locker.GetStatus(pipeline.ResourceID)
+	return err == nil && status != nil && status.TaskID == pipeline.PipelineID && time.Now().Before(status.ExpiresAt)
+}
+
+// begin 标记流水线在本实例执行，已在执行时返回 false
+func (r *Runner) begin(pipelineID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active[pipelineID] {
+		return false
+	}
+	r.active[pipelineID] = true
+	return true
+}
+
+func (r *Runner) end(pipelineID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.active, pipelineID)
+}
+
+// checkPolicy 对保存的计划执行策略检查
+func (r *Runner) checkPolicy(ctx context.Context, pipeline *models.Pipeline, step *models.ExecutionTask, planned bool) error {
+	r.taskDAO.Start(step.TaskID)
+	var err error
+	output := "No policy configured"
+	switch {
+	case !planned:
+		err = fmt.Errorf("policy step requires a preceding plan step")
+	case r.policy != nil:
+		output = "Policy check passed"
+		err = r.policy.Check(ctx, pipeline, pipeline.WorkDir, planFile)
+	}
+	if err != nil {
+		r.taskDAO.Complete(step.TaskID, false, "", err.Error())
+		r.taskDAO.RecordAttempt(step.TaskID)
+		return fmt.Errorf("step %d (%s) failed: %w", step.Step, step.Action, err)
+	}
+	r.taskDAO.Complete(step.TaskID, true, output, "")
+	r.taskDAO.RecordAttempt(step.TaskID)
+	return nil
+}
+
+// waitingStep 返回等待审批的步骤
+func (r *Runner) waitingStep(pipelineID string) (*models.ExecutionTask, error) {
+	pipeline, err := r.pipelines.Get(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	if pipeline.Status != models.PipelineStatusWaiting {
+		return nil, fmt.Errorf("pipeline %s is not waiting for approval", pipelineID)
+	}
+	steps, err := r.taskDAO.ListChildren(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range steps {
+		if steps[i].Step == pipeline.Step && steps[i].Action == models.StepApproval {
+			return &steps[i], nil
+		}
+	}
+	return nil, fmt.Errorf("pipeline %s has no approval step %d", pipelineID, pipeline.Step)
+}
+
+// finish 更新流水线的汇总状态
+func (r *Runner) finish(pipeline *models.Pipeline, status string, step int, err error) (*models.Pipeline, error) {
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	if updateErr := r.pipelines.UpdateStatus(pipeline.PipelineID, status, step, errMsg); updateErr != nil {
+		return pipeline, updateErr
+	}
+	pipeline.Status, pipeline.Step, pipeline.Error = status, step, errMsg
+	if pipeline.IsFinished() {
+		r.unlock(pipeline)
+	}
+	if status == models.PipelineStatusFailed {
+		logger.Warn("Pipeline failed",
+			logger.String("pipeline_id", pipeline.PipelineID),
+			logger.Int("step", step),
+			logger.Err(err))
+	}
+	return pipeline, err
+}
+
+// executes 判断步骤是否由执行器运行
+func executes(kind string) bool {
+	switch kind {
+	case models.StepValidate, models.StepPlan, models.StepApply, models.StepDestroy, models.StepOutput:
+		return true
+	}
+	return false
+}
+
+func validStep(kind string) bool {
+	return executes(kind) || kind == models.StepPolicy || kind == models.StepApproval
+}
@@ -0,0 +1,313 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/executor/executortest"
+	"github.com/cylonchau/prism/pkg/executor/lock"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failOnce 使动作的下一次执行失败
+func failOnce(exec *executortest.Executor, action executor.Action) {
+	failed := false
+	exec.Outcome = func(req *executor.ExecuteRequest) *executor.ExecuteResult {
+		if req.Action != action || failed {
+			return nil
+		}
+		failed = true
+		return executortest.Failed(executor.ErrorUnknown, "boom")
+	}
+}
+
+type policyFunc func() error
+
+func (p policyFunc) Check(ctx context.Context, pipeline *models.Pipeline, workDir, planFile string) error {
+	return p()
+}
+
+func setupRunner(t *testing.T) (*Runner, *executortest.Executor, *dao.ExecutionTaskDAO) {
+	db := executortest.DB(t)
+	taskDAO := dao.NewExecutionTaskDAO(db)
+	resources := dao.NewTerraformResourceDAO(db)
+	require.NoError(t, resources.Create(&models.TerraformResource{ID: 1, Provider: "aws", ResourceType: "test", TfState: "{}"}))
+	exec := &executortest.Executor{TaskDAO: taskDAO}
+	runner := NewRunner(exec, taskDAO, dao.NewPipelineDAO(db), resources, t.TempDir(), func(task *models.ExecutionTask) (*executor.ExecuteRequest, error) {
+		return &executor.ExecuteRequest{Config: "# config", State: "{}"}, nil
+	})
+	return runner, exec, taskDAO
+}
+
+func TestRunner_Approval(t *testing.T) {
+	runner, exec, taskDAO := setupRunner(t)
+	ctx := context.Background()
+
+	_, err := runner.Create("pl-1", 1, nil)
+	require.NoError(t, err)
+
+	pipeline, err := runner.Run(ctx, "pl-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusWaiting, pipeline.Status)
+	assert.Equal(t, 4, pipeline.Step)
+	assert.Equal(t, []string{"validate", "plan"}, exec.Actions())
+
+	_, err = runner.Approve(ctx, "pl-2", "alice")
+	assert.Error(t, err)
+
+	pipeline, err = runner.Approve(ctx, "pl-1", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusSuccess, pipeline.Status)
+	assert.Equal(t, []string{"validate", "plan", "apply", "output"}, exec.Actions())
+
+	// 只有第一个执行器步骤 init 并写入状态，plan 保存的计划由 apply 应用
+	validate, plan, apply := exec.Requests()[0], exec.Requests()[1], exec.Requests()[2]
+	assert.False(t, validate.Initialized)
+	assert.Equal(t, "{}", validate.State)
+	assert.True(t, plan.Initialized)
+	assert.Empty(t, plan.State)
+	assert.Equal(t, planFile, plan.PlanFile)
+	assert.Equal(t, planFile, apply.PlanFile)
+	assert.Equal(t, validate.WorkDir, apply.WorkDir)
+	assert.Equal(t, dao.StepTaskID("pl-1", 5, "apply"), apply.TaskID)
+	_, err = os.Stat(validate.WorkDir)
+	assert.True(t, os.IsNotExist(err), "work dir should be cleaned after success")
+
+	steps, _ := taskDAO.ListChildren("pl-1")
+	require.Len(t, steps, len(DefaultSteps))
+	for _, step := range steps {
+		assert.Equal(t, models.TaskStatusSuccess, step.Status, step.Action)
+	}
+	assert.Equal(t, "Approved by alice", steps[3].Output)
+	// apply 后的 state 保存到资源，不随工作目录清理丢失
+	resource, _ := runner.resources.Get(1)
+	assert.Equal(t, executortest.State(apply.TaskID), resource.TfState)
+
+	_, err = runner.Run(ctx, "pl-1")
+	assert.Error(t, err, "finished pipeline should not run again")
+}
+
+func TestRunner_RetryFailedStep(t *testing.T) {
+	runner, exec, taskDAO := setupRunner(t)
+	ctx := context.Background()
+	failOnce(exec, executor.ActionApply)
+
+	_, err := runner.Create("pl-1", 1, []string{models.StepPlan, models.StepApply, models.StepOutput})
+	require.NoError(t, err)
+
+	pipeline, err := runner.Run(ctx, "pl-1")
+	assert.Error(t, err)
+	assert.Equal(t, models.PipelineStatusFailed, pipeline.Status)
+	assert.Equal(t, 2, pipeline.Step)
+	// 失败的 apply 可能已变更部分资源，state 同样保存
+	resource, _ := runner.resources.Get(1)
+	assert.Equal(t, executortest.State(dao.StepTaskID("pl-1", 2, "apply")), resource.TfState)
+
+	// 未重试时不会跳过失败的步骤
+	_, err = runner.Run(ctx, "pl-1")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"plan", "apply"}, exec.Actions())
+
+	pipeline, err = runner.Retry(ctx, "pl-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusSuccess, pipeline.Status)
+	// 重试 apply 时重新 plan，apply 只应用新的计划
+	assert.Equal(t, []string{"plan", "apply", "plan", "apply", "output"}, exec.Actions())
+	assert.Equal(t, planFile, exec.Requests()[3].PlanFile)
+
+	apply, _ := taskDAO.Get(dao.StepTaskID("pl-1", 2, "apply"))
+	assert.Equal(t, 2, apply.Attempt)
+}
+
+func TestRunner_RetryApplyRequiresApproval(t *testing.T) {
+	runner, exec, taskDAO := setupRunner(t)
+	ctx := context.Background()
+	failOnce(exec, executor.ActionApply)
+	checks := 0
+	runner.SetPolicyChecker(policyFunc(func() error {
+		checks++
+		return nil
+	}))
+
+	_, err := runner.Create("pl-1", 1, nil)
+	require.NoError(t, err)
+	_, err = runner.Run(ctx, "pl-1")
+	require.NoError(t, err)
+	pipeline, err := runner.Approve(ctx, "pl-1", "alice")
+	assert.Error(t, err)
+	assert.Equal(t, 5, pipeline.Step)
+
+	// 重试的 apply 不能绕过策略检查与审批
+	pipeline, err = runner.Retry(ctx, "pl-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusWaiting, pipeline.Status)
+	assert.Equal(t, 4, pipeline.Step)
+	assert.Equal(t, []string{"validate", "plan", "apply", "plan"}, exec.Actions())
+	assert.Equal(t, 2, checks)
+	approval, _ := taskDAO.Get(dao.StepTaskID("pl-1", 4, "approval"))
+	assert.Equal(t, models.TaskStatusPending, approval.Status)
+
+	pipeline, err = runner.Approve(ctx, "pl-1", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusSuccess, pipeline.Status)
+	assert.Equal(t, planFile, exec.Requests()[4].PlanFile)
+}
+
+func TestRunner_HoldsLockUntilApply(t *testing.T) {
+	runner, exec, _ := setupRunner(t)
+	ctx := context.Background()
+	locker := lock.NewMemoryLocker(nil)
+	runner.SetLocker(locker)
+
+	_, err := runner.Create("pl-1", 1, nil)
+	require.NoError(t, err)
+	pipeline, err := runner.Run(ctx, "pl-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusWaiting, pipeline.Status)
+
+	// 等待审批期间其他任务无法获取资源锁
+	assert.Error(t, locker.Acquire(ctx, 1, "other-task"))
+	for _, req := range exec.Requests() {
+		assert.True(t, req.Locked, req.Action)
+	}
+
+	pipeline, err = runner.Approve(ctx, "pl-1", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusSuccess, pipeline.Status)
+	assert.Equal(t, []string{"validate", "plan", "apply", "output"}, exec.Actions())
+	assert.False(t, locker.IsLocked(1), "lock should be released after the pipeline finishes")
+}
+
+func TestRunner_ReplanAfterLockLost(t *testing.T) {
+	runner, exec, _ := setupRunner(t)
+	ctx := context.Background()
+	locker := lock.NewMemoryLocker(nil)
+	runner.SetLocker(locker)
+
+	_, err := runner.Create("pl-1", 1, nil)
+	require.NoError(t, err)
+	_, err = runner.Run(ctx, "pl-1")
+	require.NoError(t, err)
+
+	// 锁过期后状态可能已被修改，重新 plan 并再次审批
+	require.NoError(t, locker.Release(1))
+	pipeline, err := runner.Approve(ctx, "pl-1", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusWaiting, pipeline.Status)
+	assert.Equal(t, []string{"validate", "plan", "plan"}, exec.Actions())
+	assert.True(t, locker.IsLocked(1))
+}
+
+func TestRunner_MissingWorkDir(t *testing.T) {
+	runner, exec, _ := setupRunner(t)
+	ctx := context.Background()
+
+	_, err := runner.Create("pl-1", 1, nil)
+	require.NoError(t, err)
+	_, err = runner.Run(ctx, "pl-1")
+	require.NoError(t, err)
+
+	// 换实例或重启后工作目录不存在，重新 init 并 plan
+	require.NoError(t, os.RemoveAll(exec.Requests()[0].WorkDir))
+	pipeline, err := runner.Approve(ctx, "pl-1", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusWaiting, pipeline.Status)
+	require.Equal(t, []string{"validate", "plan", "plan"}, exec.Actions())
+	replan := exec.Requests()[2]
+	assert.False(t, replan.Initialized)
+	assert.Equal(t, "{}", replan.State)
+	_, err = os.Stat(replan.WorkDir)
+	assert.NoError(t, err)
+}
+
+func TestRunner_RecoverInterruptedStep(t *testing.T) {
+	runner, exec, taskDAO := setupRunner(t)
+	ctx := context.Background()
+
+	_, err := runner.Create("pl-1", 1, []string{models.StepPlan, models.StepOutput})
+	require.NoError(t, err)
+	// 模拟执行 plan 时实例崩溃
+	plan := dao.StepTaskID("pl-1", 1, "plan")
+	require.NoError(t, taskDAO.Start(plan))
+	require.NoError(t, runner.pipelines.UpdateStatus("pl-1", models.PipelineStatusRunning, 1, ""))
+
+	pipeline, err := runner.Run(ctx, "pl-1")
+	assert.ErrorContains(t, err, "interrupted")
+	assert.Equal(t, models.PipelineStatusFailed, pipeline.Status)
+
+	pipeline, err = runner.Retry(ctx, "pl-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusSuccess, pipeline.Status)
+	assert.Equal(t, []string{"plan", "output"}, exec.Actions())
+}
+
+func TestRunner_DestroySavesState(t *testing.T) {
+	runner, exec, _ := setupRunner(t)
+	ctx := context.Background()
+	exec.Outcome = func(req *executor.ExecuteRequest) *executor.ExecuteResult {
+		if req.Action == executor.ActionPlan {
+			return &executor.ExecuteResult{Status: executor.StatusSuccess, State: `{"plan":true}`}
+		}
+		return nil
+	}
+
+	// 只保存 apply 与 destroy 后的 state
+	_, err := runner.Create("pl-0", 1, []string{models.StepPlan})
+	require.NoError(t, err)
+	_, err = runner.Run(ctx, "pl-0")
+	require.NoError(t, err)
+	resource, _ := runner.resources.Get(1)
+	assert.Equal(t, "{}", resource.TfState)
+
+	_, err = runner.Create("pl-1", 1, []string{models.StepPlan, models.StepDestroy})
+	require.NoError(t, err)
+	pipeline, err := runner.Run(ctx, "pl-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusSuccess, pipeline.Status)
+	resource, _ = runner.resources.Get(1)
+	assert.Equal(t, executortest.State(dao.StepTaskID("pl-1", 2, "destroy")), resource.TfState)
+}
+
+func TestRunner_PolicyAndReject(t *testing.T) {
+	runner, exec, taskDAO := setupRunner(t)
+	ctx := context.Background()
+
+	_, err := runner.Create("pl-1", 1, []string{"unknown"})
+	assert.Error(t, err)
+
+	denied := true
+	runner.SetPolicyChecker(policyFunc(func() error {
+		if denied {
+			return fmt.Errorf("instance type not allowed")
+		}
+		return nil
+	}))
+
+	_, err = runner.Create("pl-1", 1, nil)
+	require.NoError(t, err)
+	pipeline, err := runner.Run(ctx, "pl-1")
+	assert.ErrorContains(t, err, "instance type not allowed")
+	assert.Equal(t, 3, pipeline.Step)
+
+	denied = false
+	pipeline, err = runner.Retry(ctx, "pl-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusWaiting, pipeline.Status)
+	assert.Equal(t, []string{"validate", "plan"}, exec.Actions())
+
+	pipeline, err = runner.Reject("pl-1", "bob", "too expensive")
+	require.NoError(t, err)
+	assert.Equal(t, models.PipelineStatusRejected, pipeline.Status)
+	approval, _ := taskDAO.Get(dao.StepTaskID("pl-1", 4, "approval"))
+	assert.Equal(t, models.TaskStatusFailed, approval.Status)
+	assert.Equal(t, "Rejected by bob: too expensive", approval.Error)
+
+	_, err = runner.Retry(ctx, "pl-1")
+	assert.Error(t, err, "rejected pipeline should not be retried")
+}
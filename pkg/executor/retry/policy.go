@@ -0,0 +1,98 @@
+// Package retry provides automatic retry of failed tasks.
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/executor"
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// Policy 重试策略
+type Policy struct {
+	MaxAttempts  int                  // 最大执行次数，含首次执行
+	InitialDelay time.Duration        // 首次重试间隔
+	MaxDelay     time.Duration        // 最大重试间隔
+	Multiplier   float64              // 间隔倍数
+	Jitter       float64              // 随机抖动比例 0-1
+	RetryOn      []executor.ErrorCode // 重试的错误分类，为空表示全部可重试分类；未分类的失败需显式列出
+}
+
+// DefaultPolicy 默认策略：最多执行 3 次，间隔 30s 起按 2 倍增长，不超过 10m
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MaxAttempts:  3,
+		InitialDelay: 30 * time.Second,
+		MaxDelay:     10 * time.Minute,
+		Multiplier:   2,
+		Jitter:       0.2,
+	}
+}
+
+// FromModel 从数据库记录构建策略，为 0 的字段使用默认值
+func FromModel(m *models.RetryPolicy) *Policy {
+	p := DefaultPolicy()
+	if m.MaxAttempts > 0 {
+		p.MaxAttempts = m.MaxAttempts
+	}
+	if m.InitialDelay > 0 {
+		p.InitialDelay = time.Duration(m.InitialDelay) * time.Millisecond
+	}
+	if m.MaxDelay > 0 {
+		p.MaxDelay = time.Duration(m.MaxDelay) * time.Millisecond
+	}
+	if m.Multiplier > 0 {
+		p.Multiplier = m.Multiplier
+	}
+	p.Jitter = m.Jitter
+	for _, code := range strings.Split(m.RetryOn, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			p.RetryOn = append(p.RetryOn, executor.ErrorCode(code))
+		}
+	}
+	return p
+}
+
+// Allows 判断失败分类是否重试。未分类的失败仅在 RetryOn 中列出时重试，
+// 其余不可重试的分类（如凭证、配额、取消）始终不重试
+func (p *Policy) Allows(code executor.ErrorCode) bool {
+	if code == executor.ErrorUnknown {
+		return p.lists(code)
+	}
+	if !code.Retryable() {
+		return false
+	}
+	return len(p.RetryOn) == 0 || p.lists(code)
+}
+
+// lists 判断 RetryOn 是否显式列出该分类
+func (p *Policy) lists(code executor.ErrorCode) bool {
+	for _, c := range p.RetryOn {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff 返回第 attempt 次执行失败后的重试间隔：
+// InitialDelay * Multiplier^(attempt-1)，不超过 MaxDelay，并加上 ±Jitter 比例的随机抖动
+func (p *Policy) Backoff(attempt int, random func() float64) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if max := float64(p.MaxDelay); p.MaxDelay > 0 && delay > max {
+		delay = max
+	}
+	if p.Jitter > 0 {
+		if random == nil {
+			random = rand.Float64
+		}
+		delay += delay * p.Jitter * (2*random() - 1)
+	}
+	return time.Duration(delay)
+}
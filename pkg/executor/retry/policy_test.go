@@ -0,0 +1,62 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/executor"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := &Policy{InitialDelay: time.Second, MaxDelay: 10 * time.Second, Multiplier: 2}
+
+	assert.Equal(t, time.Second, p.Backoff(0, nil))
+	assert.Equal(t, time.Second, p.Backoff(1, nil))
+	assert.Equal(t, 2*time.Second, p.Backoff(2, nil))
+	assert.Equal(t, 8*time.Second, p.Backoff(4, nil))
+	assert.Equal(t, 10*time.Second, p.Backoff(5, nil))
+
+	p.Jitter = 0.5
+	assert.Equal(t, 2*time.Second, p.Backoff(2, func() float64 { return 0.5 }))
+	assert.Equal(t, time.Second, p.Backoff(2, func() float64 { return 0 }))
+	assert.Equal(t, 3*time.Second, p.Backoff(2, func() float64 { return 1 }))
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(3, nil)
+		assert.True(t, d >= 2*time.Second && d <= 6*time.Second, d)
+	}
+}
+
+func TestPolicy_Allows(t *testing.T) {
+	p := DefaultPolicy()
+	assert.True(t, p.Allows(executor.ErrorTimeout))
+	assert.True(t, p.Allows(executor.ErrorRateLimited))
+	assert.False(t, p.Allows(executor.ErrorUnknown))
+	assert.False(t, p.Allows(executor.ErrorCancelled))
+	assert.False(t, p.Allows(executor.ErrorAuth))
+	assert.False(t, p.Allows(executor.ErrorQuota))
+	assert.False(t, p.Allows(executor.ErrorConfigInvalid))
+
+	p.RetryOn = []executor.ErrorCode{executor.ErrorTimeout, executor.ErrorAuth}
+	assert.True(t, p.Allows(executor.ErrorTimeout))
+	assert.False(t, p.Allows(executor.ErrorRateLimited))
+	// 不可重试的分类不受 RetryOn 影响
+	assert.False(t, p.Allows(executor.ErrorAuth))
+
+	// 未分类的失败需显式列出
+	assert.False(t, p.Allows(executor.ErrorUnknown))
+	p.RetryOn = append(p.RetryOn, executor.ErrorUnknown, executor.ErrorCancelled)
+	assert.True(t, p.Allows(executor.ErrorUnknown))
+	assert.False(t, p.Allows(executor.ErrorCancelled))
+}
+
+func TestFromModel(t *testing.T) {
+	p := FromModel(&models.RetryPolicy{MaxAttempts: 5, InitialDelay: 1500, RetryOn: "timeout, rate_limited"})
+	assert.Equal(t, 5, p.MaxAttempts)
+	assert.Equal(t, 1500*time.Millisecond, p.InitialDelay)
+	assert.Equal(t, DefaultPolicy().MaxDelay, p.MaxDelay)
+	assert.Equal(t, DefaultPolicy().Multiplier, p.Multiplier)
+	assert.Equal(t, 0.0, p.Jitter)
+	assert.Equal(t, []executor.ErrorCode{executor.ErrorTimeout, executor.ErrorRateLimited}, p.RetryOn)
+}
@@ -0,0 +1,175 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/logger"
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// defaultBatchSize 每轮最多重试的任务数
+const defaultBatchSize = 20
+
+// PolicySource 按云厂商与动作查找重试策略，由 dao.RetryPolicyDAO 实现
+type PolicySource interface {
+	Match(provider, action string) (*models.RetryPolicy, error)
+}
+
+// RequestBuilder 根据任务记录重建执行请求，如重新加载资源配置与凭证
+type RequestBuilder func(task *models.ExecutionTask) (*executor.ExecuteRequest, error)
+
+// Scheduler 执行任务并保存执行后的 state，在失败可重试时按策略安排自动重试。
+// 不可重试的失败（如凭证、配额、配置错误、取消）不会重试
+type Scheduler struct {
+	exec      executor.Executor
+	taskDAO   *dao.ExecutionTaskDAO
+	resources *dao.TerraformResourceDAO
+	build     RequestBuilder
+	policies  PolicySource
+	interval  time.Duration
+	now       func() time.Time
+	random    func() float64
+}
+
+// NewScheduler 创建重试调度器，未设置策略来源时使用 DefaultPolicy
+func NewScheduler(exec executor.Executor, taskDAO *dao.ExecutionTaskDAO, resources *dao.TerraformResourceDAO, build RequestBuilder) *Scheduler {
+	return &Scheduler{
+		exec:      exec,
+		taskDAO:   taskDAO,
+		resources: resources,
+		build:     build,
+		interval:  10 * time.Second,
+		now:       time.Now,
+	}
+}
+
+// SetPolicySource 设置重试策略来源
+func (s *Scheduler) SetPolicySource(policies PolicySource) {
+	s.policies = policies
+}
+
+// SetInterval 设置检查到期重试的间隔
+func (s *Scheduler) SetInterval(interval time.Duration) {
+	s.interval = interval
+}
+
+// Execute 执行任务并保存执行后的 state，失败且策略允许时安排下一次重试。
+// 重试请求由已保存的 state 重建，state 保存失败时不安排重试，避免重复创建资源
+func (s *Scheduler) Execute(ctx context.Context, req *executor.ExecuteRequest) (*executor.ExecuteResult, error) {
+	result, err := s.exec.Execute(ctx, req)
+	if result != nil && result.State != "" {
+		if saveErr := s.resources.UpdateTfState(req.ResourceID, result.State); saveErr != nil {
+			logger.Error("Failed to save state", logger.String("task_id", req.TaskID), logger.Err(saveErr))
+			return result, errors.Join(err, fmt.Errorf("failed to save state: %w", saveErr))
+		}
+	}
+	// 未分类的失败 Retryable 为 false，仍交由策略判断
+	if result == nil || result.Status != executor.StatusFailed {
+		return result, err
+	}
+	if scheduleErr := s.schedule(req, result.ErrorCode); scheduleErr != nil {
+		logger.Warn("Failed to schedule retry", logger.String("task_id", req.TaskID), logger.Err(scheduleErr))
+	}
+	return result, err
+}
+
+// schedule 按策略计算下一次重试时间
+func (s *Scheduler) schedule(req *executor.ExecuteRequest, code executor.ErrorCode) error {
+	policy, err := s.policy(req.Provider, string(req.Action))
+	if err != nil {
+		return err
+	}
+	if !policy.Allows(code) {
+		return nil
+	}
+	task, err := s.taskDAO.Get(req.TaskID)
+	if err != nil {
+		return err
+	}
+	if task.Attempt >= policy.MaxAttempts {
+		logger.Info("Retry attempts exhausted",
+			logger.String("task_id", req.TaskID),
+			logger.Int("attempt", task.Attempt))
+		return nil
+	}
+
+	at := s.now().Add(policy.Backoff(task.Attempt, s.random))
+	logger.Info("Retry scheduled",
+		logger.String("task_id", req.TaskID),
+		logger.String("error_code", string(code)),
+		logger.Int("attempt", task.Attempt),
+		logger.String("next_retry_at", at.Format(time.RFC3339)))
+	return s.taskDAO.ScheduleRetry(req.TaskID, at)
+}
+
+// policy 返回匹配的重试策略
+func (s *Scheduler) policy(provider, action string) (*Policy, error) {
+	if s.policies == nil {
+		return DefaultPolicy(), nil
+	}
+	m, err := s.policies.Match(provider, action)
+	if err != nil {
+		return nil, fmt.Errorf("match retry policy: %w", err)
+	}
+	if m == nil {
+		return DefaultPolicy(), nil
+	}
+	return FromModel(m), nil
+}
+
+// RunOnce 重新执行所有到期的重试，返回重试的任务数
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	tasks, err := s.taskDAO.ListDueRetries(s.now(), defaultBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list due retries: %w", err)
+	}
+
+	retried := 0
+	for i := range tasks {
+		if ctx.Err() != nil {
+			return retried, ctx.Err()
+		}
+		task := &tasks[i]
+		claimed, err := s.taskDAO.ClaimRetry(task.TaskID, s.now())
+		if err != nil {
+			return retried, fmt.Errorf("claim retry %s: %w", task.TaskID, err)
+		}
+		if !claimed {
+			continue
+		}
+
+		req, err := s.build(task)
+		if err != nil {
+			logger.Error("Failed to build retry request", logger.String("task_id", task.TaskID), logger.Err(err))
+			s.taskDAO.Complete(task.TaskID, false, "", err.Error())
+			continue
+		}
+		retried++
+		if _, err := s.Execute(ctx, req); err != nil {
+			logger.Warn("Retry failed", logger.String("task_id", task.TaskID), logger.Err(err))
+		}
+	}
+	return retried, nil
+}
+
+// Run 周期性执行到期的重试，直到 ctx 取消
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			logger.Error("Retry scheduler failed", logger.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/executor/executortest"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupScheduler 创建资源 1，执行器按顺序返回 codes 中的失败分类，空分类表示成功。
+// 重试请求由资源已保存的 state 重建
+func setupScheduler(t *testing.T, codes ...executor.ErrorCode) (*Scheduler, *executortest.Executor, *gorm.DB) {
+	db := executortest.DB(t)
+	taskDAO := dao.NewExecutionTaskDAO(db)
+	resources := dao.NewTerraformResourceDAO(db)
+	require.NoError(t, resources.Create(&models.TerraformResource{ID: 1, Provider: "aws", ResourceType: "test"}))
+	calls := 0
+	exec := &executortest.Executor{TaskDAO: taskDAO, Outcome: func(req *executor.ExecuteRequest) *executor.ExecuteResult {
+		code := codes[calls]
+		calls++
+		if code == "" {
+			return nil
+		}
+		return executortest.Failed(code, string(code))
+	}}
+	s := NewScheduler(exec, taskDAO, resources, func(task *models.ExecutionTask) (*executor.ExecuteRequest, error) {
+		resource, err := resources.Get(task.ResourceID)
+		if err != nil {
+			return nil, err
+		}
+		return &executor.ExecuteRequest{TaskID: task.TaskID, ResourceID: task.ResourceID, Action: executor.Action(task.Action), Provider: "aws", State: resource.TfState}, nil
+	})
+	return s, exec, db
+}
+
+func TestScheduler_RetriesTransientFailures(t *testing.T) {
+	s, exec, db := setupScheduler(t, executor.ErrorTimeout, executor.ErrorRateLimited, "")
+	taskDAO := dao.NewExecutionTaskDAO(db)
+	policies := dao.NewRetryPolicyDAO(db)
+	require.NoError(t, policies.Create(&models.RetryPolicy{Provider: "aws", MaxAttempts: 3, InitialDelay: 1000}))
+	s.SetPolicySource(policies)
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	_, err := taskDAO.Create("task-1", 1, "apply")
+	require.NoError(t, err)
+	result, err := s.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-1", Action: executor.ActionApply, Provider: "aws"})
+	require.Error(t, err)
+	assert.Equal(t, executor.StatusFailed, result.Status)
+
+	task, err := taskDAO.Get("task-1")
+	require.NoError(t, err)
+	require.NotNil(t, task.NextRetryAt)
+	assert.WithinDuration(t, now.Add(time.Second), *task.NextRetryAt, time.Millisecond)
+
+	// 未到期不重试
+	retried, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, retried)
+
+	// 第二次失败间隔翻倍，第三次成功
+	now = now.Add(time.Second)
+	retried, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, retried)
+	task, _ = taskDAO.Get("task-1")
+	assert.WithinDuration(t, now.Add(2*time.Second), *task.NextRetryAt, time.Millisecond)
+
+	now = now.Add(2 * time.Second)
+	retried, err = s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, retried)
+	assert.Equal(t, 3, exec.Calls())
+
+	task, _ = taskDAO.Get("task-1")
+	assert.Equal(t, models.TaskStatusSuccess, task.Status)
+	assert.Equal(t, 3, task.Attempt)
+	assert.Nil(t, task.NextRetryAt)
+}
+
+func TestScheduler_LeavesPermanentFailures(t *testing.T) {
+	s, _, db := setupScheduler(t, executor.ErrorAuth)
+	taskDAO := dao.NewExecutionTaskDAO(db)
+
+	_, err := taskDAO.Create("task-1", 1, "apply")
+	require.NoError(t, err)
+	_, err = s.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-1", Action: executor.ActionApply})
+	require.Error(t, err)
+
+	task, _ := taskDAO.Get("task-1")
+	assert.Nil(t, task.NextRetryAt)
+	assert.True(t, task.Permanent)
+}
+
+func TestScheduler_LeavesCancelledTasks(t *testing.T) {
+	s, _, db := setupScheduler(t, executor.ErrorCancelled)
+	taskDAO := dao.NewExecutionTaskDAO(db)
+
+	_, err := taskDAO.Create("task-1", 1, "apply")
+	require.NoError(t, err)
+	_, err = s.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-1", Action: executor.ActionApply})
+	require.Error(t, err)
+
+	task, _ := taskDAO.Get("task-1")
+	assert.Nil(t, task.NextRetryAt)
+	assert.False(t, task.Permanent)
+}
+
+func TestScheduler_RetriesUnknownOnlyWhenListed(t *testing.T) {
+	s, _, db := setupScheduler(t, executor.ErrorUnknown, executor.ErrorUnknown)
+	taskDAO := dao.NewExecutionTaskDAO(db)
+	policies := dao.NewRetryPolicyDAO(db)
+	s.SetPolicySource(policies)
+
+	_, err := taskDAO.Create("task-1", 1, "apply")
+	require.NoError(t, err)
+	_, err = s.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-1", Action: executor.ActionApply, Provider: "aws"})
+	require.Error(t, err)
+	task, _ := taskDAO.Get("task-1")
+	assert.Nil(t, task.NextRetryAt)
+	assert.False(t, task.Permanent)
+
+	require.NoError(t, policies.Create(&models.RetryPolicy{Provider: "aws", RetryOn: "timeout,unknown"}))
+	_, err = taskDAO.Create("task-2", 1, "apply")
+	require.NoError(t, err)
+	_, err = s.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-2", Action: executor.ActionApply, Provider: "aws"})
+	require.Error(t, err)
+	task, _ = taskDAO.Get("task-2")
+	assert.NotNil(t, task.NextRetryAt)
+}
+
+func TestScheduler_StopsAfterMaxAttempts(t *testing.T) {
+	s, exec, db := setupScheduler(t, executor.ErrorTimeout, executor.ErrorTimeout, executor.ErrorTimeout, "")
+	taskDAO := dao.NewExecutionTaskDAO(db)
+	s.random = func() float64 { return 0.5 }
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	_, err := taskDAO.Create("task-1", 1, "apply")
+	require.NoError(t, err)
+	_, err = s.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-1", Action: executor.ActionApply})
+	require.Error(t, err)
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Hour)
+		_, err = s.RunOnce(context.Background())
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, DefaultPolicy().MaxAttempts, exec.Calls())
+	task, _ := taskDAO.Get("task-1")
+	assert.Equal(t, models.TaskStatusFailed, task.Status)
+	assert.Nil(t, task.NextRetryAt)
+
+	attempts, err := taskDAO.ListAttempts("task-1")
+	require.NoError(t, err)
+	require.Len(t, attempts, 3)
+	for i, attempt := range attempts {
+		assert.Equal(t, i+1, attempt.Attempt)
+		assert.Equal(t, string(executor.ErrorTimeout), attempt.ErrorCode)
+	}
+}
+
+func TestScheduler_SavesState(t *testing.T) {
+	s, exec, db := setupScheduler(t, executor.ErrorTimeout, "")
+	taskDAO := dao.NewExecutionTaskDAO(db)
+	resources := dao.NewTerraformResourceDAO(db)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	partial := `{"version":4,"serial":7}`
+	outcome := exec.Outcome
+	exec.Outcome = func(req *executor.ExecuteRequest) *executor.ExecuteResult {
+		result := outcome(req)
+		if result != nil {
+			result.State = partial // 失败的 apply 已创建部分资源
+		}
+		return result
+	}
+
+	_, err := taskDAO.Create("task-1", 1, "apply")
+	require.NoError(t, err)
+	_, err = s.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-1", ResourceID: 1, Action: executor.ActionApply, Provider: "aws"})
+	require.Error(t, err)
+	resource, _ := resources.Get(1)
+	assert.Equal(t, partial, resource.TfState)
+
+	// 重试以失败时保存的 state 执行，成功后保存新的 state
+	now = now.Add(time.Hour)
+	retried, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, retried)
+	requests := exec.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, partial, requests[1].State)
+	resource, _ = resources.Get(1)
+	assert.Equal(t, executortest.State("task-1"), resource.TfState)
+}
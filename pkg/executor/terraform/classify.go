@@ -0,0 +1,122 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/executor/cmd"
+	"github.com/cylonchau/prism/pkg/logger"
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// PatternSource 数据库中的分类规则，由 dao.ErrorPatternDAO 实现
+type PatternSource interface {
+	ListByProvider(provider string) ([]models.ErrorPattern, error)
+	Seed(patterns []models.ErrorPattern) error
+}
+
+// BuiltinPatterns 内置分类规则，设置 PatternSource 时写入数据库，之后可由运维修改或停用。
+// 优先级为负，自定义规则默认先于内置规则匹配；通用规则中限流需在配额之前，如 RequestLimitExceeded
+var BuiltinPatterns = []models.ErrorPattern{
+	{Name: "aws.auth", Provider: "aws", Code: string(executor.ErrorAuth), Enabled: true, Priority: -10, Pattern: `ExpiredToken|InvalidClientTokenId|UnrecognizedClientException`},
+	{Name: "aws.quota", Provider: "aws", Code: string(executor.ErrorQuota), Enabled: true, Priority: -10, Pattern: `VcpuLimitExceeded|InsufficientInstanceCapacity|AddressLimitExceeded`},
+	{Name: "tencentcloud.auth", Provider: "tencentcloud", Code: string(executor.ErrorAuth), Enabled: true, Priority: -10, Pattern: `AuthFailure|UnauthorizedOperation`},
+	{Name: "tencentcloud.quota", Provider: "tencentcloud", Code: string(executor.ErrorQuota), Enabled: true, Priority: -10, Pattern: `ResourceInsufficient|ResourcesSoldOut|InsufficientBalance`},
+	{Name: "tencentcloud.conflict", Provider: "tencentcloud", Code: string(executor.ErrorConflict), Enabled: true, Priority: -10, Pattern: `ResourceInUse|FailedOperation\.DuplicateName`},
+	{Name: "alicloud.auth", Provider: "alicloud", Code: string(executor.ErrorAuth), Enabled: true, Priority: -10, Pattern: `InvalidAccessKeyId|SignatureDoesNotMatch|Forbidden\.RAM`},
+	{Name: "alicloud.quota", Provider: "alicloud", Code: string(executor.ErrorQuota), Enabled: true, Priority: -10, Pattern: `QuotaExceed|OperationDenied\.NoStock|InvalidAccountStatus\.NotEnoughBalance`},
+
+	{Name: "provider_crash", Code: string(executor.ErrorProviderCrash), Enabled: true, Priority: -10, Pattern: `(?i)plugin (crashed|did not respond)|panic: |rpc error: code = Unavailable`},
+	{Name: "config_invalid", Code: string(executor.ErrorConfigInvalid), Enabled: true, Priority: -20, Pattern: `Unsupported argument|Missing required argument|Unsupported block type|Unsupported attribute|Reference to undeclared|Invalid reference|Invalid function argument|Incorrect attribute value type|Argument or block definition required|Invalid expression|Duplicate resource`},
+	{Name: "rate_limited", Code: string(executor.ErrorRateLimited), Enabled: true, Priority: -30, Pattern: `(?i)throttl|rate exceeded|too many requests|RequestLimitExceeded|StatusCode: 429\b`},
+	{Name: "auth_failed", Code: string(executor.ErrorAuth), Enabled: true, Priority: -40, Pattern: `(?i)unauthori[sz]ed|authenticat|access denied|forbidden|invalid credentials|no valid credential|StatusCode: 40[13]\b`},
+	{Name: "quota_exceeded", Code: string(executor.ErrorQuota), Enabled: true, Priority: -50, Pattern: `(?i)quota|limit ?exceeded|insufficient (capacity|balance)`},
+	{Name: "timeout", Code: string(executor.ErrorTimeout), Enabled: true, Priority: -60, Pattern: `(?i)timeout while waiting|timed out|deadline exceeded`},
+	{Name: "conflict", Code: string(executor.ErrorConflict), Enabled: true, Priority: -70, Pattern: `(?i)already exists|conflict|duplicate|\bin use\b|StatusCode: 409\b`},
+	{Name: "not_found", Code: string(executor.ErrorNotFound), Enabled: true, Priority: -80, Pattern: `(?i)not ?found|does not exist|couldn't find|no such|StatusCode: 404\b`},
+}
+
+// Classifier 将 Terraform 诊断信息与执行结果映射为稳定的错误分类。
+// 匹配顺序：取消与超时，其后按规则顺序（云厂商规则优先，再按优先级）
+type Classifier struct {
+	source PatternSource
+
+	mu       sync.Mutex
+	compiled map[string]*regexp.Regexp
+}
+
+// NewClassifier 创建分类器，source 为空时直接使用内置规则
+func NewClassifier(source PatternSource) *Classifier {
+	return &Classifier{source: source, compiled: make(map[string]*regexp.Regexp)}
+}
+
+// Classify 对失败分类，texts 为诊断信息与 stderr 等可供匹配的文本
+func (c *Classifier) Classify(provider string, err error, texts []string) executor.ErrorCode {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return executor.ErrorCancelled
+	case errors.Is(err, cmd.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return executor.ErrorTimeout
+	}
+	if err != nil {
+		texts = append(texts, err.Error())
+	}
+
+	for _, p := range c.patterns(provider) {
+		re := c.compile(p.Pattern)
+		if re != nil && matchAny(re, texts) {
+			return executor.ErrorCode(p.Code)
+		}
+	}
+	return executor.ErrorUnknown
+}
+
+// patterns 返回适用于云厂商的规则，已按匹配顺序排列
+func (c *Classifier) patterns(provider string) []models.ErrorPattern {
+	if c.source != nil {
+		patterns, err := c.source.ListByProvider(provider)
+		if err != nil {
+			logger.Warn("Failed to load error patterns", logger.String("provider", provider), logger.Err(err))
+		}
+		return patterns
+	}
+
+	var specific, generic []models.ErrorPattern
+	for _, p := range BuiltinPatterns {
+		switch p.Provider {
+		case provider:
+			specific = append(specific, p)
+		case "":
+			generic = append(generic, p)
+		}
+	}
+	return append(specific, generic...)
+}
+
+// compile 编译并缓存规则，无效的规则被忽略
+func (c *Classifier) compile(pattern string) *regexp.Regexp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	re, ok := c.compiled[pattern]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			logger.Warn("Invalid error pattern", logger.String("pattern", pattern), logger.Err(err))
+		}
+		c.compiled[pattern] = re
+	}
+	return re
+}
+
+func matchAny(re *regexp.Regexp, texts []string) bool {
+	for _, text := range texts {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
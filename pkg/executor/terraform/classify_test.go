@@ -0,0 +1,169 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/executor/cmd"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+type fakePatterns []models.ErrorPattern
+
+func (f fakePatterns) ListByProvider(provider string) ([]models.ErrorPattern, error) {
+	var patterns []models.ErrorPattern
+	for _, p := range f {
+		if p.Provider == provider || p.Provider == "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns, nil
+}
+
+func (f fakePatterns) Seed([]models.ErrorPattern) error { return nil }
+
+func TestClassifier_Builtin(t *testing.T) {
+	classifier := NewClassifier(nil)
+
+	tests := []struct {
+		provider string
+		text     string
+		expected executor.ErrorCode
+	}{
+		{"aws", "creating EC2 Instance: operation error EC2: RunInstances, https response error StatusCode: 403, api error UnauthorizedOperation", executor.ErrorAuth},
+		{"aws", "api error RequestLimitExceeded: Request limit exceeded.", executor.ErrorRateLimited},
+		{"aws", "api error VcpuLimitExceeded: You have requested more vCPU capacity", executor.ErrorQuota},
+		{"aws", "creating Security Group (web): InvalidGroup.Duplicate: The security group 'web' already exists", executor.ErrorConflict},
+		{"aws", "reading EC2 Subnet (subnet-1): couldn't find resource: InvalidSubnetID.NotFound", executor.ErrorNotFound},
+		{"aws", "timeout while waiting for state to become 'running'", executor.ErrorTimeout},
+		{"aws", "The terraform-provider-aws_v5.0.0 plugin crashed!", executor.ErrorProviderCrash},
+		{"aws", "Unsupported argument", executor.ErrorConfigInvalid},
+		{"tencentcloud", "[TencentCloudSDKError] Code=ResourceInsufficient.SpecifiedInstanceType", executor.ErrorQuota},
+		{"alicloud", "SDK.ServerError ErrorCode: Forbidden.RAM", executor.ErrorAuth},
+		{"aws", "something unexpected happened", executor.ErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		if code := classifier.Classify(tt.provider, nil, []string{tt.text}); code != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.text, tt.expected, code)
+		}
+	}
+}
+
+func TestClassifier_RunnerOutcome(t *testing.T) {
+	classifier := NewClassifier(nil)
+
+	timeout := fmt.Errorf("terraform apply failed: %w", fmt.Errorf("%w after 30m0s", cmd.ErrTimeout))
+	if code := classifier.Classify("aws", timeout, nil); code != executor.ErrorTimeout {
+		t.Errorf("runner timeout should be classified as timeout, got %s", code)
+	}
+	if code := classifier.Classify("aws", context.Canceled, nil); code != executor.ErrorCancelled {
+		t.Errorf("expected cancelled, got %s", code)
+	}
+	// 错误本身的文本同样参与匹配
+	if code := classifier.Classify("aws", errors.New("credential aws/prod not found"), nil); code != executor.ErrorNotFound {
+		t.Errorf("expected not_found, got %s", code)
+	}
+}
+
+func TestClassifier_Patterns(t *testing.T) {
+	classifier := NewClassifier(fakePatterns{
+		{Provider: "aws", Code: string(executor.ErrorRateLimited), Pattern: `SlowDown`},
+		{Provider: "", Code: string(executor.ErrorQuota), Pattern: `no capacity`},
+		{Provider: "aws", Code: string(executor.ErrorAuth), Pattern: `(`}, // 无效规则被忽略
+	})
+
+	if code := classifier.Classify("aws", nil, []string{"S3 returned SlowDown"}); code != executor.ErrorRateLimited {
+		t.Errorf("expected rate_limited from database pattern, got %s", code)
+	}
+	if code := classifier.Classify("alicloud", nil, []string{"S3 returned SlowDown"}); code != executor.ErrorUnknown {
+		t.Errorf("provider pattern should not apply to other providers, got %s", code)
+	}
+	// 设置 source 后只按数据库中的规则匹配，内置规则已写入数据库
+	if code := classifier.Classify("alicloud", nil, []string{"zone has no capacity, resource does not exist"}); code != executor.ErrorQuota {
+		t.Errorf("expected quota_exceeded, got %s", code)
+	}
+}
+
+func TestClassifier_SeededPatterns(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patterns := dao.NewErrorPatternDAO(db)
+	exec := New(&Config{}, nil, nil, nil)
+	if err := exec.SetPatternSource(patterns); err != nil {
+		t.Fatal(err)
+	}
+	// 重复设置不会重复写入内置规则
+	if err := exec.SetPatternSource(patterns); err != nil {
+		t.Fatal(err)
+	}
+	all, _ := patterns.List()
+	if len(all) != len(BuiltinPatterns) {
+		t.Fatalf("expected %d seeded patterns, got %d", len(BuiltinPatterns), len(all))
+	}
+
+	quota := "api error VcpuLimitExceeded: You have requested more vCPU capacity"
+	limited := "api error RequestLimitExceeded: Request limit exceeded."
+	if code := exec.classifier.Classify("aws", nil, []string{quota}); code != executor.ErrorQuota {
+		t.Errorf("expected quota_exceeded from seeded pattern, got %s", code)
+	}
+	if code := exec.classifier.Classify("aws", nil, []string{limited}); code != executor.ErrorRateLimited {
+		t.Errorf("rate limit should match before quota, got %s", code)
+	}
+
+	// 停用内置规则后不再匹配
+	for _, p := range all {
+		if p.Name == "aws.quota" || p.Name == "quota_exceeded" {
+			if err := patterns.SetEnabled(p.ID, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if code := exec.classifier.Classify("aws", nil, []string{quota}); code != executor.ErrorUnknown {
+		t.Errorf("disabled pattern should not match, got %s", code)
+	}
+
+	// 自定义规则默认先于内置规则匹配
+	if err := patterns.Create(&models.ErrorPattern{Code: string(executor.ErrorQuota), Pattern: `RequestLimitExceeded`, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if code := exec.classifier.Classify("aws", nil, []string{limited}); code != executor.ErrorQuota {
+		t.Errorf("custom pattern should match before builtin, got %s", code)
+	}
+}
+
+func TestErrorCode_Retryable(t *testing.T) {
+	for _, code := range []executor.ErrorCode{executor.ErrorRateLimited, executor.ErrorTimeout, executor.ErrorProviderCrash} {
+		if !code.Retryable() {
+			t.Errorf("%s should be retryable", code)
+		}
+		if code.Permanent() {
+			t.Errorf("%s should not be permanent", code)
+		}
+	}
+	for _, code := range []executor.ErrorCode{executor.ErrorAuth, executor.ErrorQuota, executor.ErrorNotFound, executor.ErrorConflict, executor.ErrorConfigInvalid} {
+		if code.Retryable() {
+			t.Errorf("%s should not be retryable", code)
+		}
+		if !code.Permanent() {
+			t.Errorf("%s should be permanent", code)
+		}
+	}
+	// 取消与未分类的失败不自动重试，但可以手动重试
+	for _, code := range []executor.ErrorCode{executor.ErrorCancelled, executor.ErrorUnknown} {
+		if code.Retryable() {
+			t.Errorf("%s should not be retryable", code)
+		}
+		if code.Permanent() {
+			t.Errorf("%s should not be permanent", code)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package terraform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// Decoder reads terraform JSON UI messages from a stream, one JSON object per line.
+// Lines that are not JSON objects are skipped. Lines are not length limited.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder creates a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Next returns the next message, or io.EOF when the stream ends.
+func (d *Decoder) Next() (*TerraformMessage, error) {
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSpace(line)
+			if len(line) > 0 && line[0] == '{' {
+				var msg TerraformMessage
+				if json.Unmarshal(line, &msg) == nil {
+					return &msg, nil
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ParseJSONStream parses terraform JSON output from r without retaining messages.
+// handler, if not nil, is called for each message. ParseResult.Messages is left empty.
+func (p *Parser) ParseJSONStream(r io.Reader, handler func(msg *TerraformMessage)) (*ParseResult, error) {
+	result := &ParseResult{
+		Messages: []TerraformMessage{},
+		Errors:   []Diagnostic{},
+		Success:  true,
+	}
+
+	decoder := NewDecoder(r)
+	for {
+		msg, err := decoder.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		result.add(msg)
+		if handler != nil {
+			handler(msg)
+		}
+	}
+}
+
+// add merges a message into the summary fields of the result.
+func (r *ParseResult) add(msg *TerraformMessage) {
+	switch msg.Type {
+	case MessageVersion:
+		r.Version = msg.Terraform
+	case MessageChangeSummary:
+		if msg.Changes != nil {
+			r.Changes = msg.Changes
+		}
+	case MessageOutputs:
+		if r.Outputs == nil {
+			r.Outputs = make(map[string]OutputInfo, len(msg.Outputs))
+		}
+		for name, output := range msg.Outputs {
+			r.Outputs[name] = output
+		}
+	case MessageResourceDrift:
+		if msg.Change != nil {
+			r.Drift = append(r.Drift, *msg.Change)
+		}
+	case MessageDiagnostic:
+		if msg.Diagnostic != nil {
+			r.Errors = append(r.Errors, *msg.Diagnostic)
+			if msg.Diagnostic.Severity == "error" {
+				r.Success = false
+			}
+		}
+	case MessageTestSummary:
+		if msg.TestSummary != nil {
+			r.Test = msg.TestSummary
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package terraform
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoder_Next(t *testing.T) {
+	// 超过 bufio.Scanner 默认上限的单行消息
+	long := strings.Repeat("x", 128*1024)
+	input := "Initializing...\n" +
+		`{"@level":"info","@message":"Terraform 1.9.0","type":"version","terraform":"1.9.0","ui":"1.2"}` + "\n" +
+		"{not json}\n" +
+		`{"@level":"info","@message":"` + long + `","type":"log"}` + "\n" +
+		`{"@level":"info","@message":"no trailing newline","type":"log"}`
+
+	decoder := NewDecoder(strings.NewReader(input))
+
+	var messages []*TerraformMessage
+	for {
+		msg, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	if messages[0].Type != MessageVersion || messages[0].UI != "1.2" {
+		t.Errorf("unexpected version message: %+v", messages[0])
+	}
+	if len(messages[1].Message) != len(long) {
+		t.Errorf("long message truncated to %d bytes", len(messages[1].Message))
+	}
+	if messages[2].Message != "no trailing newline" {
+		t.Errorf("unexpected last message: %s", messages[2].Message)
+	}
+}
+
+func TestParser_ParseJSONStream(t *testing.T) {
+	output := `{"type":"version","terraform":"1.9.0"}
+{"type":"resource_drift","change":{"resource":{"addr":"aws_instance.web","resource_type":"aws_instance"},"action":"update"}}
+{"type":"planned_change","change":{"resource":{"addr":"aws_instance.web","resource_type":"aws_instance"},"action":"replace","reason":"cannot_update"}}
+{"type":"planned_change","change":{"resource":{"addr":"aws_instance.new","resource_type":"aws_instance"},"previous_resource":{"addr":"aws_instance.old"},"action":"move"}}
+{"type":"change_summary","changes":{"add":1,"change":0,"import":0,"remove":1,"operation":"plan"}}
+{"type":"apply_start","hook":{"resource":{"addr":"aws_instance.web","resource_key":0},"action":"delete","id_key":"id","id_value":"i-1"}}
+{"type":"apply_complete","hook":{"resource":{"addr":"aws_instance.web"},"action":"create","id_key":"id","id_value":"i-2","elapsed_seconds":12.5}}
+{"type":"provision_progress","hook":{"resource":{"addr":"aws_instance.web"},"provisioner":"remote-exec","output":"installing"}}
+{"type":"refresh_complete","hook":{"resource":{"addr":"aws_vpc.main"},"id_key":"id","id_value":"vpc-1"}}
+{"type":"outputs","outputs":{"ip":{"sensitive":false,"type":"string","value":"10.0.0.1"},"password":{"sensitive":true,"type":"string"}}}
+{"type":"diagnostic","diagnostic":{"severity":"warning","summary":"deprecated","range":{"filename":"main.tf","start":{"line":1,"column":1,"byte":0},"end":{"line":1,"column":5,"byte":4}},"snippet":{"context":"resource","code":"ami = var.ami","start_line":3,"highlight_start_offset":6,"highlight_end_offset":13,"values":[{"traversal":"var.ami","statement":"is \"ami-1\""}]}}}
+{"type":"test_run","test_run":{"path":"main.tftest.hcl","run":"setup","progress":"complete","elapsed":1200,"status":"pass"}}
+{"type":"test_summary","test_summary":{"status":"pass","passed":1,"failed":0,"errored":0,"skipped":0}}`
+
+	var messages []*TerraformMessage
+	result, err := NewParser().ParseJSONStream(strings.NewReader(output), func(msg *TerraformMessage) {
+		messages = append(messages, msg)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 13 || len(result.Messages) != 0 {
+		t.Fatalf("expected 13 handled and 0 retained messages, got %d and %d", len(messages), len(result.Messages))
+	}
+
+	if len(result.Drift) != 1 || result.Drift[0].Action != "update" {
+		t.Errorf("unexpected drift: %+v", result.Drift)
+	}
+	if c := messages[2].Change; c.Action != "replace" || c.Reason != "cannot_update" {
+		t.Errorf("unexpected planned change: %+v", c)
+	}
+	if c := messages[3].Change; c.PreviousResource == nil || c.PreviousResource.Addr != "aws_instance.old" {
+		t.Errorf("moved resource should have previous_resource: %+v", c)
+	}
+	if h := messages[6].Hook; h.IDValue != "i-2" || h.ElapsedSeconds != 12.5 {
+		t.Errorf("unexpected apply_complete hook: %+v", h)
+	}
+	if h := messages[7].Hook; h.Provisioner != "remote-exec" || h.Output != "installing" {
+		t.Errorf("unexpected provision hook: %+v", h)
+	}
+	if len(result.Outputs) != 2 || string(result.Outputs["ip"].Value) != `"10.0.0.1"` || !result.Outputs["password"].Sensitive {
+		t.Errorf("unexpected outputs: %+v", result.Outputs)
+	}
+	if d := result.Errors[0]; d.Snippet == nil || d.Snippet.Values[0].Traversal != "var.ami" || d.Range.End.Column != 5 {
+		t.Errorf("unexpected diagnostic: %+v", d)
+	}
+	if !result.Success {
+		t.Error("warnings should not fail the result")
+	}
+	if r := messages[11].TestRun; r.Run != "setup" || r.Status != "pass" {
+		t.Errorf("unexpected test run: %+v", r)
+	}
+	if result.Test == nil || result.Test.Passed != 1 {
+		t.Errorf("unexpected test summary: %+v", result.Test)
+	}
+}
@@ -4,7 +4,9 @@ package terraform
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -15,13 +17,23 @@ import (
 	"github.com/cylonchau/prism/pkg/executor/lock"
 	"github.com/cylonchau/prism/pkg/executor/workspace"
 	"github.com/cylonchau/prism/pkg/executor/ws"
+	"github.com/cylonchau/prism/pkg/logger"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/cylonchau/prism/pkg/secret"
 )
 
+// stateFile is the local state file in a work directory.
+const stateFile = "terraform.tfstate"
+
+// maxStderrLines is the number of trailing stderr lines kept for error classification.
+const maxStderrLines = 100
+
 // Config holds Terraform executor configuration.
 type Config struct {
-	BinaryPath string
-	BasePath   string
-	Timeout    time.Duration
+	BinaryPath     string
+	BasePath       string
+	PluginCacheDir string // TF_PLUGIN_CACHE_DIR，为空则不启用
+	Timeout        time.Duration
 }
 
 // DefaultConfig returns the default configuration.
@@ -33,17 +45,77 @@ func DefaultConfig() *Config {
 	}
 }
 
+// CredentialResolver resolves stored cloud credentials by provider and name.
+type CredentialResolver interface {
+	Resolve(provider, name string) (map[string]string, error)
+}
+
+// AttributeIndexer maps attributes of an applied or destroyed state to canonical names.
+type AttributeIndexer interface {
+	Index(resourceID int64, state string) error
+}
+
 // Executor implements Terraform execution.
 type Executor struct {
 	*executor.BaseExecutor
 	config    *Config
 	locker    lock.LockManager
 	taskDAO   *dao.ExecutionTaskDAO
+	outcomes  *dao.TaskResourceOutcomeDAO
+	diagDAO   *dao.TaskDiagnosticDAO
+	resources *dao.TerraformResourceDAO
+	events    *dao.TaskEventDAO
 	workspace *workspace.Manager
 	runner    *cmd.Runner
 	hub       *ws.Hub
 	parser    *Parser
-	errors    []Diagnostic // Extracted errors from JSON output
+	history   DurationHistory
+
+	classifier  *Classifier
+	credentials CredentialResolver
+	indexer     AttributeIndexer
+	secrets     *secret.Resolver
+	build       RequestBuilder
+	log         logger.Logger
+}
+
+// task holds the state of a single Execute call, so that concurrent calls on one
+// Executor do not share secrets, diagnostics or progress.
+type task struct {
+	*Executor
+	errors   []Diagnostic // Extracted errors from JSON output
+	diags    []Diagnostic // All diagnostics (errors and warnings) in report order
+	stderr   []string     // Last stderr lines, for error classification
+	drifted  bool         // Terraform reported changes made outside of it
+	progress *ProgressTracker
+	env      *cmd.Env         // Process environment
+	redactor *secret.Redactor // Masks secrets injected into the task
+	log      logger.Logger
+}
+
+// newTask creates the state of a new Execute call.
+func (e *Executor) newTask() *task {
+	redactor := secret.NewRedactor()
+	return &task{
+		Executor: e,
+		errors:   []Diagnostic{},
+		progress: NewProgressTracker(e.history),
+		redactor: redactor,
+		log:      logger.WithRedaction(e.log, redactor.Redact),
+	}
+}
+
+// child creates the state of a linked task, such as a rollback, that shares the
+// process environment and secrets of t.
+func (t *task) child() *task {
+	return &task{
+		Executor: t.Executor,
+		errors:   []Diagnostic{},
+		progress: NewProgressTracker(t.history),
+		env:      t.env,
+		redactor: t.redactor,
+		log:      t.log,
+	}
 }
 
 // New creates a new Terraform executor.
@@ -60,7 +132,134 @@ func New(config *Config, locker lock.LockManager, taskDAO *dao.ExecutionTaskDAO,
 		runner:       cmd.NewRunner(config.Timeout),
 		hub:          hub,
 		parser:       NewParser(),
-		errors:       []Diagnostic{},
+		classifier:   NewClassifier(nil),
+		log:          logger.Named("terraform"),
+	}
+}
+
+// SetCredentialResolver sets the store used to resolve ExecuteRequest.Credential.
+func (e *Executor) SetCredentialResolver(resolver CredentialResolver) {
+	e.credentials = resolver
+}
+
+// SetAttributeIndexer sets the indexer run on the state after apply and destroy.
+func (e *Executor) SetAttributeIndexer(indexer AttributeIndexer) {
+	e.indexer = indexer
+}
+
+// SetOutcomeDAO sets the store of per-resource task outcomes.
+func (e *Executor) SetOutcomeDAO(outcomes *dao.TaskResourceOutcomeDAO) {
+	e.outcomes = outcomes
+}
+
+// SetPatternSource sets the store of error classification patterns and seeds
+// the builtin patterns into it.
+func (e *Executor) SetPatternSource(source PatternSource) error {
+	if err := source.Seed(BuiltinPatterns); err != nil {
+		return fmt.Errorf("failed to seed error patterns: %w", err)
+	}
+	e.classifier = NewClassifier(source)
+	return nil
+}
+
+// SetDiagnosticDAO sets the store of structured task diagnostics.
+func (e *Executor) SetDiagnosticDAO(diagDAO *dao.TaskDiagnosticDAO) {
+	e.diagDAO = diagDAO
+}
+
+// SetEventDAO sets the store of task timelines and records state transitions into it.
+func (e *Executor) SetEventDAO(events *dao.TaskEventDAO) {
+	e.events = events
+	e.OnTransition(func(taskID, from, to string) {
+		e.recordEvent(taskID, models.TaskEventState, to, from, "")
+	})
+}
+
+// SetRequestBuilder sets how Retry rebuilds the request of a stored task.
+func (e *Executor) SetRequestBuilder(build RequestBuilder) {
+	e.build = build
+}
+
+// SetDurationHistory sets the store of historical resource durations used for ETA.
+func (e *Executor) SetDurationHistory(history DurationHistory) {
+	e.history = history
+}
+
+// SetSecretResolver sets the resolver for secret:// references in request params.
+func (e *Executor) SetSecretResolver(resolver *secret.Resolver) {
+	e.secrets = resolver
+}
+
+// ConfigParams converts stored config params to request params.
+// Values may be secret:// references, which are resolved just before execution.
+func ConfigParams(params []models.TerraformConfigParam) map[string]string {
+	result := make(map[string]string, len(params))
+	for _, p := range params {
+		value := p.ParamValue
+		if value == "" {
+			value = p.DefaultValue
+		}
+		result[p.ParamName] = value
+	}
+	return result
+}
+
+// NewResourceRequest builds an execute request for a stored resource.
+func NewResourceRequest(taskID string, action executor.Action, resource *models.TerraformResource) *executor.ExecuteRequest {
+	return &executor.ExecuteRequest{
+		TaskID:     taskID,
+		ResourceID: resource.ID,
+		Action:     action,
+		Config:     resource.TfConfig,
+		Provider:   resource.Provider,
+		Tenant:     resource.Tenant,
+		Credential: resource.Credential,
+		State:      resource.TfState,
+	}
+}
+
+// ResourceParams converts stored resource params to request params.
+func ResourceParams(params []models.TerraformResourceParam) map[string]string {
+	result := make(map[string]string, len(params))
+	for _, p := range params {
+		result[p.ParamName] = p.ParamValue
+	}
+	return result
+}
+
+// ParamsRequestBuilder builds requests like NewResourceRequest with the stored
+// params of the resource, for use as a dag.RequestBuilder.
+func ParamsRequestBuilder(params *dao.TerraformResourceParamDAO) func(taskID string, action executor.Action, resource *models.TerraformResource) (*executor.ExecuteRequest, error) {
+	return func(taskID string, action executor.Action, resource *models.TerraformResource) (*executor.ExecuteRequest, error) {
+		req := NewResourceRequest(taskID, action, resource)
+		stored, err := params.ListByResourceID(resource.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load params of resource %d: %w", resource.ID, err)
+		}
+		req.Params = ResourceParams(stored)
+		return req, nil
+	}
+}
+
+// RequestBuilder rebuilds the execute request of a stored task.
+type RequestBuilder func(task *models.ExecutionTask) (*executor.ExecuteRequest, error)
+
+// ResourceRequestBuilder rebuilds the execute request of a stored task from its resource
+// and stored params, so a retry runs with the same variables as the original attempt.
+// For use as a retry.RequestBuilder and by Executor.Retry.
+func ResourceRequestBuilder(resources *dao.TerraformResourceDAO, params *dao.TerraformResourceParamDAO) RequestBuilder {
+	return func(task *models.ExecutionTask) (*executor.ExecuteRequest, error) {
+		resource, err := resources.Get(task.ResourceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load resource %d: %w", task.ResourceID, err)
+		}
+		req := NewResourceRequest(task.TaskID, executor.Action(task.Action), resource)
+		stored, err := params.ListByResourceID(resource.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load params of resource %d: %w", resource.ID, err)
+		}
+		req.Params = ResourceParams(stored)
+		return req, nil
 	}
 }
 
@@ -69,106 +268,244 @@ func (e *Executor) Type() string {
 	return "terraform"
 }
 
+// Execute runs a task with its own diagnostics, secrets and progress.
 func (e *Executor) Execute(ctx context.Context, req *executor.ExecuteRequest) (*executor.ExecuteResult, error) {
+	return e.newTask().execute(ctx, req)
+}
+
+func (t *task) execute(ctx context.Context, req *executor.ExecuteRequest) (*executor.ExecuteResult, error) {
 	start := time.Now()
-	e.errors = []Diagnostic{} // Reset errors
+	t.Reset()
+	t.SetTaskID(req.TaskID)
 
 	result := &executor.ExecuteResult{
 		TaskID: req.TaskID,
 		Status: executor.StatusRunning,
 	}
 
-	// 1. Create task record
-	if e.taskDAO != nil {
-		_, err := e.taskDAO.Create(req.TaskID, req.ResourceID, string(req.Action))
+	// 1. Create task record, a retried task reuses its record
+	if t.taskDAO != nil {
+		exists, err := t.taskDAO.Exists(req.TaskID)
+		if err == nil && !exists {
+			_, err = t.taskDAO.Create(req.TaskID, req.ResourceID, string(req.Action))
+		}
 		if err != nil {
 			result.Status = executor.StatusFailed
 			result.Error = err.Error()
 			return result, err
 		}
 	}
+	t.recordEvent(req.TaskID, models.TaskEventPhase, models.TaskPhaseQueued, "", "")
 
-	// 2. Acquire lock
-	if e.locker != nil {
-		if err := e.locker.Acquire(ctx, req.ResourceID, req.TaskID); err != nil {
-			result.Status = executor.StatusFailed
-			result.Error = err.Error()
-			e.completeTask(req.TaskID, false, err.Error())
+	// 2. Start task, counting the attempt before waiting for the lock so that
+	// a lock conflict is recorded as an attempt of its own
+	if t.taskDAO != nil {
+		t.taskDAO.Start(req.TaskID)
+	}
+
+	// 3. Acquire lock
+	if t.locker != nil && !req.Locked {
+		t.recordEvent(req.TaskID, models.TaskEventPhase, models.TaskPhaseLockWait, "", "")
+		if err := t.locker.Acquire(ctx, req.ResourceID, req.TaskID); err != nil {
+			t.failTask(ctx, req, result, err)
 			return result, err
 		}
-		defer e.locker.Release(req.ResourceID)
+		defer t.locker.Release(req.ResourceID)
 	}
-
-	// 3. Start task
-	if e.taskDAO != nil {
-		e.taskDAO.Start(req.TaskID)
+	if t.hub != nil {
+		t.hub.Bind(req.TaskID, ws.TaskMeta{ResourceID: req.ResourceID, Provider: req.Provider, Tenant: req.Tenant})
+		defer t.hub.Unbind(req.TaskID)
 	}
-	if err := e.Transition("start"); err != nil {
-		result.Status = executor.StatusFailed
-		result.Error = err.Error()
-		e.completeTask(req.TaskID, false, err.Error())
+	if err := t.Transition("start"); err != nil {
+		t.failTask(ctx, req, result, err)
 		return result, err
 	}
+	started := t.startLifecycle(req)
+	defer func() { t.finishLifecycle(req, result, started) }()
 
 	// 4. Setup cancellation
 	ctx, cancel := context.WithCancel(ctx)
-	e.SetCancel(cancel)
+	t.SetCancel(cancel)
 
 	// 5. Create work directory
+	var err error
 	workDir := req.WorkDir
 	if workDir == "" {
-		var err error
-		workDir, err = e.workspace.Create("default", "default", fmt.Sprintf("%d", req.ResourceID), req.TaskID)
+		workDir, err = t.workspace.Create("default", "default", fmt.Sprintf("%d", req.ResourceID), req.TaskID)
 		if err != nil {
-			result.Status = executor.StatusFailed
-			result.Error = err.Error()
-			e.Transition("fail")
-			e.completeTask(req.TaskID, false, err.Error())
+			t.Transition("fail")
+			t.failTask(ctx, req, result, err)
 			return result, err
 		}
-		defer e.workspace.Clean(workDir)
+		defer t.workspace.Clean(workDir)
 	}
 
-	// 6. Execute action
-	var err error
+	// 6. Write config and build process environment
+	if err := t.prepare(ctx, workDir, req); err != nil {
+		t.Transition("fail")
+		t.failTask(ctx, req, result, err)
+		t.sendError(req.TaskID, result.Error)
+		t.sendComplete(req.TaskID, false, result)
+		return result, err
+	}
+
+	// 7. Execute action
 	switch req.Action {
 	case executor.ActionInit:
-		err = e.init(ctx, workDir, req)
+		err = t.init(ctx, workDir, req)
+	case executor.ActionValidate:
+		err = t.validate(ctx, workDir, req)
+	case executor.ActionOutput:
+		result.Attributes, err = t.output(ctx, workDir, req)
 	case executor.ActionPlan:
-		err = e.plan(ctx, workDir, req)
+		err = t.plan(ctx, workDir, req)
 	case executor.ActionApply:
-		err = e.apply(ctx, workDir, req)
+		err = t.apply(ctx, workDir, req)
 	case executor.ActionDestroy:
-		err = e.destroy(ctx, workDir, req)
+		err = t.destroy(ctx, workDir, req)
 	default:
 		err = fmt.Errorf("unsupported action: %s", req.Action)
 	}
 
-	// 7. Update result
+	// 8. Update result
 	result.Duration = time.Since(start).Milliseconds()
-	result.Output = e.getErrorSummary()
+	result.Output = t.getErrorSummary()
+	if req.Action == executor.ActionApply || req.Action == executor.ActionDestroy {
+		result.Resources = t.progress.Outcomes(t.GetErrors())
+		t.saveOutcomes(req.TaskID, result.Resources)
+	}
+	result.Diagnostics = t.GetDiagnostics()
+	t.saveDiagnostics(req.TaskID, result.Diagnostics)
+	result.State = t.readState(workDir)
+	if req.Action == executor.ActionApply && err == nil {
+		result.Attributes = t.stateAttributes(result.State)
+	}
 
 	if err != nil {
-		result.Status = executor.StatusFailed
-		result.Error = err.Error()
-		e.Transition("fail")
-		e.completeTask(req.TaskID, false, err.Error())
-		e.sendComplete(req.TaskID, false, result)
+		if summary := outcomeSummary(result.Resources); summary != "" {
+			t.sendRawLog(req.TaskID, ws.LevelWarn, summary)
+		}
+		t.Transition("fail")
+		t.failTask(ctx, req, result, err)
+		t.sendError(req.TaskID, result.Error)
+		if req.Action == executor.ActionApply && req.Rollback != executor.RollbackNone {
+			if result.Rollback = t.rollback(ctx, workDir, req, result.Resources); result.Rollback != nil {
+				result.State = result.Rollback.State
+			}
+		}
+		t.indexAttributes(req, result.State)
+		t.sendComplete(req.TaskID, false, result)
+		t.log.Error("Terraform task failed",
+			logger.String("task_id", req.TaskID),
+			logger.String("action", string(req.Action)),
+			logger.String("error_code", string(result.ErrorCode)),
+			logger.Err(err))
 		return result, err
 	}
 
 	result.Status = executor.StatusSuccess
-	e.Transition("success")
-	e.completeTask(req.TaskID, true, "")
-	e.sendComplete(req.TaskID, true, result)
+	t.setPhase(req.TaskID, models.TaskPhaseCompleted, "Completed")
+	t.Transition("success")
+	t.completeTask(req.TaskID)
+	t.indexAttributes(req, result.State)
+	t.sendComplete(req.TaskID, true, result)
 	return result, nil
 }
 
-// completeTask persists task completion.
-func (e *Executor) completeTask(taskID string, success bool, errMsg string) {
-	if e.taskDAO != nil {
-		e.taskDAO.Complete(taskID, success, e.getErrorSummary(), errMsg)
+// indexAttributes re-indexes resource attributes from the state left by apply or destroy.
+func (t *task) indexAttributes(req *executor.ExecuteRequest, state string) {
+	if t.indexer == nil || state == "" {
+		return
+	}
+	if req.Action != executor.ActionApply && req.Action != executor.ActionDestroy {
+		return
+	}
+	if err := t.indexer.Index(req.ResourceID, state); err != nil {
+		t.log.Warn("Failed to index resource attributes",
+			logger.String("task_id", req.TaskID),
+			logger.Int64("resource_id", req.ResourceID),
+			logger.Err(err))
+	}
+}
+
+// failTask classifies err and records the failure on result and the task record.
+func (t *task) failTask(ctx context.Context, req *executor.ExecuteRequest, result *executor.ExecuteResult, err error) {
+	result.Status = executor.StatusFailed
+	result.Error = t.redactor.Redact(err.Error())
+	result.ErrorCode = t.classify(ctx, req.Provider, err)
+	result.Retryable = result.ErrorCode.Retryable()
+	if t.taskDAO != nil {
+		t.taskDAO.Complete(req.TaskID, false, t.getErrorSummary(), t.redactor.Redact(err.Error()))
+		t.taskDAO.SetErrorCode(req.TaskID, string(result.ErrorCode), !result.ErrorCode.Permanent())
+		t.taskDAO.RecordAttempt(req.TaskID)
+	}
+}
+
+// classify maps a failure to an error code using diagnostics and stderr of the task.
+func (t *task) classify(ctx context.Context, provider string, err error) executor.ErrorCode {
+	// 取消时 terraform 进程被杀死，err 为退出错误
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return executor.ErrorCancelled
+	}
+	texts := make([]string, 0, 2*len(t.errors)+len(t.stderr))
+	for _, d := range t.errors {
+		texts = append(texts, d.Summary, d.Detail)
+	}
+	texts = append(texts, t.stderr...)
+	return t.classifier.Classify(provider, err, texts)
+}
+
+// completeTask persists successful task completion and its attempt.
+func (t *task) completeTask(taskID string) {
+	if t.taskDAO != nil {
+		t.taskDAO.Complete(taskID, true, t.getErrorSummary(), "")
+		t.taskDAO.RecordAttempt(taskID)
+	}
+}
+
+// saveOutcomes persists per-resource outcomes of a task.
+func (t *task) saveOutcomes(taskID string, outcomes []executor.ResourceOutcome) {
+	if t.outcomes == nil {
+		return
+	}
+	records := make([]models.TaskResourceOutcome, 0, len(outcomes))
+	for _, o := range outcomes {
+		records = append(records, models.TaskResourceOutcome{
+			Address:      o.Address,
+			ResourceType: o.Type,
+			Action:       o.Action,
+			Outcome:      string(o.Outcome),
+			CloudID:      o.ID,
+			Duration:     o.Duration,
+			Error:        o.Error,
+		})
+	}
+	if err := t.outcomes.Save(taskID, records); err != nil {
+		t.log.Warn("Failed to save resource outcomes",
+			logger.String("task_id", taskID),
+			logger.Err(err))
+	}
+}
+
+// outcomeSummary summarizes resource outcomes of a failed task, e.g.
+// "Partially applied: 2 created, 1 failed, 3 skipped".
+func outcomeSummary(outcomes []executor.ResourceOutcome) string {
+	if len(outcomes) == 0 {
+		return ""
 	}
+	counts := make(map[executor.Outcome]int)
+	var order []executor.Outcome
+	for _, o := range outcomes {
+		if counts[o.Outcome] == 0 {
+			order = append(order, o.Outcome)
+		}
+		counts[o.Outcome]++
+	}
+	parts := make([]string, 0, len(order))
+	for _, outcome := range order {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[outcome], outcome))
+	}
+	return "Partially applied: " + strings.Join(parts, ", ")
 }
 
 // Retry resets and re-executes a failed task.
@@ -176,6 +513,9 @@ func (e *Executor) Retry(ctx context.Context, taskID string) (*executor.ExecuteR
 	if e.taskDAO == nil {
 		return nil, fmt.Errorf("task store not configured")
 	}
+	if e.build == nil {
+		return nil, fmt.Errorf("request builder not configured")
+	}
 
 	canRetry, err := e.taskDAO.CanRetry(taskID)
 	if err != nil {
@@ -191,20 +531,123 @@ func (e *Executor) Retry(ctx context.Context, taskID string) (*executor.ExecuteR
 		return nil, err
 	}
 
+	// Rebuild the request as the original attempt ran
+	req, err := e.build(task)
+	if err != nil {
+		return nil, err
+	}
+
 	// Reset task
 	if err := e.taskDAO.Reset(taskID); err != nil {
 		return nil, err
 	}
 
 	// Re-execute
-	req := &executor.ExecuteRequest{
-		TaskID:     taskID,
-		ResourceID: task.ResourceID,
-		Action:     executor.Action(task.Action),
-	}
 	return e.Execute(ctx, req)
 }
 
+// prepare 写入配置文件并构建进程环境
+func (t *task) prepare(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
+	if req.Config != "" {
+		if err := t.workspace.WriteFile(workDir, "main.tf", []byte(req.Config)); err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+	}
+	if req.State != "" {
+		if err := t.workspace.WriteFile(workDir, stateFile, []byte(req.State)); err != nil {
+			return fmt.Errorf("failed to write state: %w", err)
+		}
+	}
+
+	creds, err := t.resolveCredentials(workDir, req)
+	if err != nil {
+		return err
+	}
+
+	// 参数中的 secret:// 引用在此解析，解析结果仅存在于进程环境中
+	resolved := *req
+	if t.secrets == nil {
+		for name, value := range req.Params {
+			if secret.IsRef(value) {
+				return fmt.Errorf("param %s is a secret reference but no secret resolver is configured", name)
+			}
+		}
+	} else {
+		params, names, err := t.secrets.ResolveParams(ctx, req.Params)
+		if err != nil {
+			return err
+		}
+		resolved.Params = params
+		resolved.Sensitive = append(append([]string{}, req.Sensitive...), names...)
+	}
+
+	env, err := t.buildEnv(&resolved, creds)
+	if err != nil {
+		return err
+	}
+	t.env = env
+	t.redactor.Add(env.Secrets()...)
+	return nil
+}
+
+// resolveCredentials 合并凭证存储中的凭证与请求中显式传入的凭证，kubeconfig 内容写入工作目录
+func (e *Executor) resolveCredentials(workDir string, req *executor.ExecuteRequest) (map[string]string, error) {
+	creds := make(map[string]string)
+	if req.Credential != "" {
+		if e.credentials == nil {
+			return nil, fmt.Errorf("credential store not configured")
+		}
+		resolved, err := e.credentials.Resolve(req.Provider, req.Credential)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range resolved {
+			creds[k] = v
+		}
+	}
+	for k, v := range req.Credentials {
+		creds[k] = v
+	}
+
+	if kubeconfig, ok := creds["kubeconfig"]; ok {
+		path := filepath.Join(workDir, ".kubeconfig")
+		if err := os.WriteFile(path, []byte(kubeconfig), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write kubeconfig: %w", err)
+		}
+		delete(creds, "kubeconfig")
+		creds["config_path"] = path
+	}
+	return creds, nil
+}
+
+// buildEnv 构建 terraform 进程环境：白名单继承 + 自动化变量 + 凭证 + TF_VAR_*
+func (e *Executor) buildEnv(req *executor.ExecuteRequest, creds map[string]string) (*cmd.Env, error) {
+	env := cmd.NewEnv()
+	env.Set("TF_IN_AUTOMATION", "1")
+	env.Set("TF_INPUT", "0")
+	if e.config.PluginCacheDir != "" {
+		if err := os.MkdirAll(e.config.PluginCacheDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create plugin cache dir: %w", err)
+		}
+		env.Set("TF_PLUGIN_CACHE_DIR", e.config.PluginCacheDir)
+	}
+
+	if len(creds) > 0 {
+		if err := env.SetCredentials(req.Provider, creds); err != nil {
+			return nil, err
+		}
+	}
+
+	sensitive := make(map[string]bool, len(req.Sensitive))
+	for _, name := range req.Sensitive {
+		sensitive[name] = true
+	}
+	for name, value := range req.Params {
+		env.SetVar(name, value, sensitive[name])
+	}
+	return env, nil
+}
+
 // Validate 验证配置
 func (e *Executor) Validate(config string) error {
 	// TODO: 使用 terraform validate
@@ -212,21 +655,20 @@ func (e *Executor) Validate(config string) error {
 }
 
 // init 执行 terraform init
-func (e *Executor) init(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
-	e.UpdateProgress("init", 10, "Running terraform init...")
-	e.sendProgress(req.TaskID, "init", 10, "Running terraform init...")
+func (t *task) init(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
+	if req.Initialized && req.Action != executor.ActionInit {
+		return nil
+	}
+	t.setPhase(req.TaskID, "init", "Running terraform init...")
 
 	args := []string{
-		e.config.BinaryPath,
+		t.config.BinaryPath,
 		"-chdir=" + workDir,
 		"init",
 		"-no-color",
 	}
 
-	result := e.runner.ExecWithHandler(ctx, args, func(line string) {
-		cleaned := cmd.StripANSI(line)
-		e.sendLog(req.TaskID, cleaned)
-	})
+	result := t.run(ctx, workDir, req, args)
 
 	if result.Error != nil {
 		return fmt.Errorf("terraform init failed: %w", result.Error)
@@ -234,77 +676,157 @@ func (e *Executor) init(ctx context.Context, workDir string, req *executor.Execu
 	return nil
 }
 
+// validate 执行 terraform validate，诊断信息计入任务的错误与警告
+func (t *task) validate(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
+	if err := t.init(ctx, workDir, req); err != nil {
+		return err
+	}
+
+	t.setPhase(req.TaskID, "validate", "Running terraform validate...")
+
+	args := []string{
+		t.config.BinaryPath,
+		"-chdir=" + workDir,
+		"validate",
+		"-json",
+	}
+
+	result := t.runQuiet(ctx, workDir, req, args)
+
+	// validate -json 输出单个多行 JSON 对象，失败时退出码非 0
+	var report struct {
+		Valid       bool         `json:"valid"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), &report); err == nil {
+		for _, d := range report.Diagnostics {
+			t.diags = append(t.diags, d)
+			level := ws.LevelWarn
+			if d.Severity == models.SeverityError {
+				t.errors = append(t.errors, d)
+				level = ws.LevelError
+			}
+			t.sendRawLog(req.TaskID, level, d.Summary)
+		}
+		if report.Valid {
+			t.sendRawLog(req.TaskID, ws.LevelInfo, "The configuration is valid")
+		}
+	}
+
+	if result.Error != nil {
+		return fmt.Errorf("terraform validate failed: %w", result.Error)
+	}
+	return nil
+}
+
+// output 执行 terraform output，返回各输出值，敏感输出加入脱敏
+func (t *task) output(ctx context.Context, workDir string, req *executor.ExecuteRequest) (map[string]string, error) {
+	if err := t.init(ctx, workDir, req); err != nil {
+		return nil, err
+	}
+
+	t.setPhase(req.TaskID, "output", "Reading terraform outputs...")
+
+	args := []string{
+		t.config.BinaryPath,
+		"-chdir=" + workDir,
+		"output",
+		"-json",
+	}
+
+	result := t.runQuiet(ctx, workDir, req, args)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("terraform output failed: %w", result.Error)
+	}
+	outputs, err := t.parser.ParseOutputs([]byte(result.Stdout))
+	if err != nil {
+		return nil, err
+	}
+	// 敏感输出不放入结果属性，结果会随完成消息持久化与广播
+	values := make(map[string]string, len(outputs.Values))
+	for name, value := range outputs.Values {
+		if outputs.Sensitive[name] {
+			t.redactor.Add(value)
+			continue
+		}
+		values[name] = value
+	}
+	t.sendLog(req.TaskID, fmt.Sprintf("Extracted %d outputs, %d sensitive omitted", len(values), len(outputs.Values)-len(values)))
+	return values, nil
+}
+
 // plan 执行 terraform plan
-func (e *Executor) plan(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
+func (t *task) plan(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
 	// 先 init
-	if err := e.init(ctx, workDir, req); err != nil {
+	if err := t.init(ctx, workDir, req); err != nil {
 		return err
 	}
 
-	e.UpdateProgress("plan", 30, "Running terraform plan...")
-	e.sendProgress(req.TaskID, "plan", 30, "Running terraform plan...")
+	t.setPhase(req.TaskID, "plan", "Running terraform plan...")
 
 	args := []string{
-		e.config.BinaryPath,
+		t.config.BinaryPath,
 		"-chdir=" + workDir,
 		"plan",
 		"-input=false",
 		"-json",
 	}
+	if req.PlanFile != "" {
+		args = append(args, "-out="+req.PlanFile)
+	}
 
-	result := e.runner.ExecWithHandler(ctx, args, func(line string) {
-		cleaned := cmd.StripANSI(line)
-		e.sendLog(req.TaskID, cleaned)
-	})
+	result := t.run(ctx, workDir, req, args)
 
 	if result.Error != nil {
 		return fmt.Errorf("terraform plan failed: %w", result.Error)
 	}
 
 	// 解析 plan 输出
-	planInfo := e.parser.ParsePlan(result.Output)
-	e.sendLog(req.TaskID, fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy",
+	planInfo := t.parsePlan(result)
+	t.sendLog(req.TaskID, fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy",
 		planInfo.ToAdd, planInfo.ToChange, planInfo.ToDestroy))
 
 	return nil
 }
 
 // apply 执行 terraform apply
-func (e *Executor) apply(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
+func (t *task) apply(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
 	// 先 init
-	if err := e.init(ctx, workDir, req); err != nil {
+	if err := t.init(ctx, workDir, req); err != nil {
 		return err
 	}
 
-	e.UpdateProgress("apply", 50, "Running terraform apply...")
-	e.sendProgress(req.TaskID, "apply", 50, "Running terraform apply...")
+	t.setPhase(req.TaskID, "apply", "Running terraform apply...")
 
 	args := []string{
-		e.config.BinaryPath,
+		t.config.BinaryPath,
 		"-chdir=" + workDir,
 		"apply",
 		"-auto-approve",
 		"-json",
 	}
+	if req.PlanFile != "" {
+		args = append(args, req.PlanFile)
+	}
 
-	result := e.runner.ExecWithHandler(ctx, args, func(line string) {
-		cleaned := cmd.StripANSI(line)
-		e.sendLog(req.TaskID, cleaned)
-	})
+	result := t.run(ctx, workDir, req, args)
 
 	if result.Error != nil {
 		return fmt.Errorf("terraform apply failed: %w", result.Error)
 	}
+	t.recordDurations()
 
-	e.UpdateProgress("apply", 90, "Parsing tfstate...")
+	t.setPhase(req.TaskID, "parse", "Parsing tfstate...")
 
 	// 解析 tfstate
-	tfstatePath := filepath.Join(workDir, "terraform.tfstate")
-	if e.workspace.Exists(tfstatePath) {
-		data, err := e.workspace.ReadFile(workDir, "terraform.tfstate")
+	tfstatePath := filepath.Join(workDir, stateFile)
+	if t.workspace.Exists(tfstatePath) {
+		data, err := t.workspace.ReadFile(workDir, stateFile)
 		if err == nil {
-			attrs := e.parser.ParseTfstate(data)
-			e.sendLog(req.TaskID, fmt.Sprintf("Extracted %d attributes from tfstate", len(attrs)))
+			t.redactor.Add(t.parser.SensitiveValues(data)...)
+			attrs := t.stateAttributes(string(data))
+			t.sendLog(req.TaskID, fmt.Sprintf("Extracted %d attributes from tfstate", len(attrs)))
 		}
 	}
 
@@ -312,66 +834,157 @@ func (e *Executor) apply(ctx context.Context, workDir string, req *executor.Exec
 }
 
 // destroy 执行 terraform destroy
-func (e *Executor) destroy(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
+func (t *task) destroy(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
 	// 先 init
-	if err := e.init(ctx, workDir, req); err != nil {
+	if err := t.init(ctx, workDir, req); err != nil {
 		return err
 	}
 
-	e.UpdateProgress("destroy", 50, "Running terraform destroy...")
-	e.sendProgress(req.TaskID, "destroy", 50, "Running terraform destroy...")
+	t.setPhase(req.TaskID, "destroy", "Running terraform destroy...")
 
 	args := []string{
-		e.config.BinaryPath,
+		t.config.BinaryPath,
 		"-chdir=" + workDir,
 		"destroy",
 		"-auto-approve",
 		"-json",
 	}
 
-	result := e.runner.ExecWithHandler(ctx, args, func(line string) {
-		cleaned := cmd.StripANSI(line)
-		e.sendLog(req.TaskID, cleaned)
-	})
+	result := t.run(ctx, workDir, req, args)
 
 	if result.Error != nil {
 		return fmt.Errorf("terraform destroy failed: %w", result.Error)
 	}
+	t.recordDurations()
 
 	return nil
 }
 
+// run 执行 terraform 命令，stdout 按 JSON 行解析，stderr 原样转发
+func (t *task) run(ctx context.Context, workDir string, req *executor.ExecuteRequest, args []string) *cmd.Result {
+	var environ []string
+	if t.env != nil {
+		environ = t.env.Environ()
+	}
+	result := t.runner.Run(ctx, args, &cmd.Options{
+		SpillDir: workDir,
+		Env:      environ,
+		Handler: func(stream cmd.Stream, line string) {
+			cleaned := cmd.StripANSI(line)
+			if stream == cmd.StreamStderr {
+				t.sendStderr(req.TaskID, cleaned)
+				return
+			}
+			t.sendLog(req.TaskID, cleaned)
+		},
+	})
+	t.checkSpill(req.TaskID, result)
+	return result
+}
+
+// runQuiet 执行 terraform 命令，stdout 不逐行转发，用于输出单个 JSON 文档或含敏感值的命令
+func (t *task) runQuiet(ctx context.Context, workDir string, req *executor.ExecuteRequest, args []string) *cmd.Result {
+	var environ []string
+	if t.env != nil {
+		environ = t.env.Environ()
+	}
+	result := t.runner.Run(ctx, args, &cmd.Options{
+		SpillDir: workDir,
+		Env:      environ,
+		Handler: func(stream cmd.Stream, line string) {
+			if stream == cmd.StreamStderr {
+				t.sendStderr(req.TaskID, cmd.StripANSI(line))
+			}
+		},
+	})
+	t.checkSpill(req.TaskID, result)
+	return result
+}
+
+// checkSpill logs output lost because it could not be spilled to a file.
+func (t *task) checkSpill(taskID string, result *cmd.Result) {
+	if result.SpillError != nil {
+		t.log.Warn("Command output truncated",
+			logger.String("task_id", taskID),
+			logger.Err(result.SpillError))
+	}
+}
+
+// sendStderr keeps the last stderr lines for error classification and forwards the line.
+func (t *task) sendStderr(taskID, line string) {
+	if len(t.stderr) == maxStderrLines {
+		t.stderr = t.stderr[1:]
+	}
+	t.stderr = append(t.stderr, line)
+	t.sendRawLog(taskID, ws.LevelError, line)
+}
+
+// parsePlan 解析 plan 输出，内存捕获被截断时从溢出文件流式读取
+func (e *Executor) parsePlan(result *cmd.Result) *PlanInfo {
+	if result.StdoutFile != "" {
+		if f, err := os.Open(result.StdoutFile); err == nil {
+			defer f.Close()
+			parsed, err := e.parser.ParseJSONStream(f, nil)
+			if err == nil && parsed.Changes != nil {
+				return &PlanInfo{
+					ToAdd:     parsed.Changes.Add,
+					ToChange:  parsed.Changes.Change,
+					ToDestroy: parsed.Changes.Remove,
+				}
+			}
+		}
+	}
+	return e.parser.ParsePlan(result.Stdout)
+}
+
+// sendRawLog sends a non-JSON line with secrets redacted.
+func (t *task) sendRawLog(taskID, level, line string) {
+	if t.hub != nil {
+		t.hub.SendLogLevel(taskID, level, t.redactor.Redact(line))
+	}
+}
+
 // sendLog processes a JSON line and extracts errors.
-func (e *Executor) sendLog(taskID, line string) {
+func (t *task) sendLog(taskID, line string) {
 	// Parse JSON message
 	var msg TerraformMessage
 	if err := json.Unmarshal([]byte(line), &msg); err != nil {
 		// Not JSON, just send as-is
-		if e.hub != nil {
-			e.hub.SendLog(taskID, line)
-		}
+		t.sendRawLog(taskID, ws.LevelInfo, line)
 		return
 	}
 
 	// Send human-readable message to websocket
-	if e.hub != nil {
-		e.hub.SendLog(taskID, msg.Message)
+	level := msg.Level
+	if level == "" {
+		level = ws.LevelInfo
 	}
+	t.sendRawLog(taskID, level, msg.Message)
 
-	// Extract and store errors
-	if msg.Type == "diagnostic" && msg.Diagnostic != nil && msg.Diagnostic.Severity == "error" {
-		e.errors = append(e.errors, *msg.Diagnostic)
+	if msg.Type == MessageResourceDrift {
+		t.drifted = true
+	}
+	if t.progress.Observe(&msg) {
+		t.sendProgress(taskID)
+	}
+
+	// Extract and store diagnostics
+	if msg.Type == MessageDiagnostic && msg.Diagnostic != nil {
+		t.diags = append(t.diags, *msg.Diagnostic)
+		if msg.Diagnostic.Severity == models.SeverityError {
+			t.errors = append(t.errors, *msg.Diagnostic)
+		}
 	}
 }
 
 // getErrorSummary returns formatted error summary for storage.
-func (e *Executor) getErrorSummary() string {
-	if len(e.errors) == 0 {
+func (t *task) getErrorSummary() string {
+	if len(t.errors) == 0 {
 		return ""
 	}
 
 	var sb strings.Builder
-	for i, err := range e.errors {
+	for i, err := range t.errors {
 		if i > 0 {
 			sb.WriteString("\n---\n")
 		}
@@ -386,29 +999,151 @@ func (e *Executor) getErrorSummary() string {
 			sb.WriteString(fmt.Sprintf("Detail: %s\n", err.Detail))
 		}
 	}
-	return sb.String()
+	return t.redactor.Redact(sb.String())
 }
 
-// GetErrors returns extracted errors.
-func (e *Executor) GetErrors() []Diagnostic {
-	return e.errors
+// GetErrors returns extracted errors with secrets redacted.
+func (t *task) GetErrors() []Diagnostic {
+	errors := make([]Diagnostic, len(t.errors))
+	for i, d := range t.errors {
+		d.Summary = t.redactor.Redact(d.Summary)
+		d.Detail = t.redactor.Redact(d.Detail)
+		errors[i] = d
+	}
+	return errors
 }
 
-// sendProgress sends progress update.
-func (e *Executor) sendProgress(taskID, phase string, percent int, message string) {
-	if e.hub != nil {
-		e.hub.SendProgress(taskID, &ws.ProgressData{
-			Phase:   phase,
-			Percent: percent,
-			Elapsed: 0,
-			Message: message,
+// GetDiagnostics returns all diagnostics with secrets redacted.
+func (t *task) GetDiagnostics() []executor.Diagnostic {
+	diagnostics := make([]executor.Diagnostic, 0, len(t.diags))
+	for _, d := range t.diags {
+		diagnostic := executor.Diagnostic{
+			Severity: d.Severity,
+			Summary:  t.redactor.Redact(d.Summary),
+			Detail:   t.redactor.Redact(d.Detail),
+			Address:  d.Address,
+		}
+		if d.Range != nil {
+			diagnostic.Filename = d.Range.Filename
+			diagnostic.Line = d.Range.Start.Line
+		}
+		// snippet 中的代码与表达式取值同样可能包含密钥
+		if raw, err := json.Marshal(d); err == nil {
+			diagnostic.Raw = json.RawMessage(t.redactor.Redact(string(raw)))
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+// saveDiagnostics persists diagnostics of a task.
+func (t *task) saveDiagnostics(taskID string, diagnostics []executor.Diagnostic) {
+	if t.diagDAO == nil {
+		return
+	}
+	records := make([]models.TaskDiagnostic, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		records = append(records, models.TaskDiagnostic{
+			Severity: d.Severity,
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+			Address:  d.Address,
+			Filename: d.Filename,
+			Line:     d.Line,
+			Data:     string(d.Raw),
+		})
+	}
+	if err := t.diagDAO.Save(taskID, records); err != nil {
+		t.log.Warn("Failed to save task diagnostics",
+			logger.String("task_id", taskID),
+			logger.Err(err))
+	}
+}
+
+// setPhase enters a new phase and sends progress update.
+func (t *task) setPhase(taskID, phase, message string) {
+	t.progress.SetPhase(phase, message)
+	t.sendProgress(taskID)
+	t.recordEvent(taskID, models.TaskEventPhase, phase, "", t.redactor.Redact(message))
+}
+
+// sendProgress stores the tracked progress and sends it with elapsed time.
+func (t *task) sendProgress(taskID string) {
+	t.SetProgress(t.progress.Progress())
+	if t.hub == nil {
+		return
+	}
+	p := t.GetProgress()
+	data := &ws.ProgressData{
+		Phase:     p.Phase,
+		Percent:   p.Percent,
+		Elapsed:   p.Elapsed,
+		Message:   t.redactor.Redact(p.Message),
+		Planned:   p.Planned,
+		Completed: p.Completed,
+		ETA:       p.ETA,
+	}
+	for _, r := range p.Resources {
+		data.Resources = append(data.Resources, ws.ResourceTiming{
+			Address: r.Address,
+			Type:    r.Type,
+			Action:  r.Action,
+			Status:  r.Status,
+			Elapsed: r.Elapsed,
 		})
 	}
+	t.hub.SendProgress(taskID, data)
+}
+
+// recordDurations saves resource timings of the current task for ETA estimation.
+func (t *task) recordDurations() {
+	if err := t.progress.Record(); err != nil {
+		t.log.Warn("Failed to record resource durations", logger.Err(err))
+	}
+}
+
+// recordEvent appends a state or phase change to the task timeline. message must
+// already be redacted.
+func (e *Executor) recordEvent(taskID, kind, name, from, message string) {
+	if e.events == nil {
+		return
+	}
+	err := e.events.Record(&models.TaskEvent{
+		TaskID:  taskID,
+		Kind:    kind,
+		Name:    name,
+		From:    from,
+		Message: message,
+	})
+	if err != nil {
+		e.log.Warn("Failed to record task event",
+			logger.String("task_id", taskID),
+			logger.String("event", name),
+			logger.Err(err))
+	}
+}
+
+// sendError sends an error message.
+func (t *task) sendError(taskID, message string) {
+	if t.hub != nil {
+		t.hub.SendError(taskID, t.redactor.Redact(message))
+	}
 }
 
 // sendComplete sends completion message.
-func (e *Executor) sendComplete(taskID string, success bool, result interface{}) {
-	if e.hub != nil {
-		e.hub.SendComplete(taskID, success, result)
+func (t *task) sendComplete(taskID string, success bool, result *executor.ExecuteResult) {
+	if t.hub == nil {
+		return
+	}
+	// 完成消息会持久化并发往所有订阅者，整体脱敏后发出
+	var payload interface{}
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			t.log.Warn("Failed to encode task result", logger.String("task_id", taskID), logger.Err(err))
+			return
+		}
+		payload = json.RawMessage(t.redactor.Redact(string(data)))
 	}
+	t.hub.SendComplete(taskID, success, payload)
 }
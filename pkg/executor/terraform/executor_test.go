@@ -2,10 +2,22 @@ package terraform
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/cylonchau/prism/pkg/dao"
 	"github.com/cylonchau/prism/pkg/executor"
 	"github.com/cylonchau/prism/pkg/executor/lock"
+	"github.com/cylonchau/prism/pkg/executor/ws"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/cylonchau/prism/pkg/secret"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -104,12 +116,105 @@ func TestExecutor_Execute_LockFailed(t *testing.T) {
 	}
 }
 
+func TestExecutor_Execute_LockFailedCountsAttempt(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskDAO := dao.NewExecutionTaskDAO(db)
+	locker := lock.NewMemoryLocker(nil)
+	exec := New(nil, locker, taskDAO, nil)
+	locker.Acquire(context.Background(), 1, "other-task")
+
+	// 每次锁冲突都记为一次尝试，重试次数才能达到上限
+	req := &executor.ExecuteRequest{TaskID: "test-task", ResourceID: 1, Action: executor.ActionApply}
+	for i := 0; i < 2; i++ {
+		if _, err := exec.Execute(context.Background(), req); err == nil {
+			t.Fatal("locked resource should return error")
+		}
+	}
+	task, err := taskDAO.Get("test-task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Attempt != 2 || task.Status != models.TaskStatusFailed {
+		t.Errorf("unexpected task: attempt %d, status %d", task.Attempt, task.Status)
+	}
+	attempts, err := taskDAO.ListAttempts("test-task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 || attempts[1].Attempt != 2 {
+		t.Errorf("each lock conflict should be recorded: %+v", attempts)
+	}
+}
+
+func TestExecutor_Execute_LockHeldByCaller(t *testing.T) {
+	binary, _ := fakeTerraform(t)
+	locker := lock.NewMemoryLocker(nil)
+	exec := New(&Config{BinaryPath: binary, BasePath: t.TempDir()}, locker, nil, nil)
+
+	// 流水线持有锁时步骤不再加锁，结束后也不释放
+	locker.Acquire(context.Background(), 1, "pipeline-1")
+	if _, err := exec.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-1", ResourceID: 1, Action: executor.ActionPlan, Locked: true}); err != nil {
+		t.Fatalf("plan with lock held by caller failed: %v", err)
+	}
+	if status, _ := locker.GetStatus(1); status == nil || status.TaskID != "pipeline-1" {
+		t.Errorf("lock of the caller should be kept: %+v", status)
+	}
+}
+
 func TestExecutor_sendMethods(t *testing.T) {
-	exec := New(nil, nil, nil, nil)
+	tk := New(nil, nil, nil, nil).newTask()
+
+	tk.sendLog("task-1", "test")
+	tk.setPhase("task-1", "init", "testing")
+	tk.sendComplete("task-1", true, nil)
+}
+
+// completeMessage 返回持久化的完成消息
+func completeMessage(t *testing.T, store ws.LogStore, taskID string) string {
+	t.Helper()
+	messages, err := store.Since(taskID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range messages {
+		if msg.Type == ws.TypeComplete {
+			data, err := json.Marshal(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(data)
+		}
+	}
+	t.Fatalf("no complete message for %s", taskID)
+	return ""
+}
+
+func TestExecutor_CompleteMessage(t *testing.T) {
+	binary, _ := fakeTerraform(t)
+	store := ws.NewMemoryStore()
+	hub := ws.NewHub()
+	hub.SetLogStore(store)
+	exec := New(&Config{BinaryPath: binary, BasePath: t.TempDir()}, nil, nil, hub)
+
+	result, _ := exec.Execute(context.Background(), &executor.ExecuteRequest{
+		TaskID: "task-1", ResourceID: 1, Action: executor.ActionDestroy, State: `{"version":4,"serial":1}`,
+	})
+	if !strings.Contains(result.State, `"serial":3`) {
+		t.Fatalf("result should carry the state for the caller: %q", result.State)
+	}
+	if msg := completeMessage(t, store, "task-1"); strings.Contains(msg, "serial") {
+		t.Errorf("complete message should not carry the state: %s", msg)
+	}
 
-	exec.sendLog("task-1", "test")
-	exec.sendProgress("task-1", "init", 10, "testing")
-	exec.sendComplete("task-1", true, nil)
+	tk := exec.newTask()
+	tk.redactor.Add("hunter2")
+	tk.sendComplete("task-2", false, &executor.ExecuteResult{TaskID: "task-2", Error: "password hunter2 rejected"})
+	if msg := completeMessage(t, store, "task-2"); strings.Contains(msg, "hunter2") || !strings.Contains(msg, secret.Mask) {
+		t.Errorf("complete message should be redacted: %s", msg)
+	}
 }
 
 func TestExecutor_GetProgress(t *testing.T) {
@@ -135,3 +240,367 @@ func TestExecutor_Cancel(t *testing.T) {
 		t.Errorf("cancel should succeed: %v", err)
 	}
 }
+
+func TestExecutor_buildEnv(t *testing.T) {
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "from-prism")
+	cacheDir := t.TempDir()
+	exec := New(&Config{BinaryPath: "terraform", BasePath: t.TempDir(), PluginCacheDir: cacheDir}, nil, nil, nil)
+
+	env, err := exec.buildEnv(&executor.ExecuteRequest{
+		Provider:  "aws",
+		Params:    map[string]string{"instance_type": "t3.micro", "db_password": "pw"},
+		Sensitive: []string{"db_password"},
+	}, map[string]string{"access_key": "AKIA", "secret_key": "task-secret"})
+	if err != nil {
+		t.Fatalf("buildEnv should succeed: %v", err)
+	}
+
+	expected := map[string]string{
+		"TF_IN_AUTOMATION":      "1",
+		"TF_INPUT":              "0",
+		"TF_PLUGIN_CACHE_DIR":   cacheDir,
+		"AWS_SECRET_ACCESS_KEY": "task-secret",
+		"TF_VAR_instance_type":  "t3.micro",
+		"TF_VAR_db_password":    "pw",
+	}
+	for key, want := range expected {
+		if got, _ := env.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if strings.Contains(env.String(), "task-secret") || strings.Contains(env.String(), "=pw") {
+		t.Errorf("secrets should be redacted: %s", env.String())
+	}
+}
+
+func TestExecutor_buildEnv_InvalidProvider(t *testing.T) {
+	exec := New(nil, nil, nil, nil)
+	_, err := exec.buildEnv(&executor.ExecuteRequest{Provider: "unknown"}, map[string]string{"key": "value"})
+	if err == nil {
+		t.Error("unknown provider should return error")
+	}
+}
+
+type stubResolver map[string]map[string]string
+
+func (s stubResolver) Resolve(provider, name string) (map[string]string, error) {
+	creds, ok := s[provider+"/"+name]
+	if !ok {
+		return nil, fmt.Errorf("credential %s/%s not found", provider, name)
+	}
+	return creds, nil
+}
+
+func TestExecutor_resolveCredentials(t *testing.T) {
+	exec := New(nil, nil, nil, nil)
+	req := &executor.ExecuteRequest{Provider: "aws", Credential: "prod"}
+
+	if _, err := exec.resolveCredentials(t.TempDir(), req); err == nil {
+		t.Error("credential without resolver should return error")
+	}
+
+	exec.SetCredentialResolver(stubResolver{
+		"aws/prod":           {"access_key": "AKIA", "secret_key": "stored"},
+		"kubernetes/cluster": {"kubeconfig": "apiVersion: v1"},
+	})
+	req.Credentials = map[string]string{"secret_key": "override"}
+	creds, err := exec.resolveCredentials(t.TempDir(), req)
+	if err != nil {
+		t.Fatalf("resolveCredentials should succeed: %v", err)
+	}
+	if creds["access_key"] != "AKIA" || creds["secret_key"] != "override" {
+		t.Errorf("explicit credentials should override stored ones: %v", creds)
+	}
+
+	workDir := t.TempDir()
+	creds, err = exec.resolveCredentials(workDir, &executor.ExecuteRequest{Provider: "kubernetes", Credential: "cluster"})
+	if err != nil {
+		t.Fatalf("resolveCredentials should succeed: %v", err)
+	}
+	if creds["config_path"] != filepath.Join(workDir, ".kubeconfig") {
+		t.Errorf("kubeconfig should be written to workspace, got %v", creds)
+	}
+	if _, ok := creds["kubeconfig"]; ok {
+		t.Error("kubeconfig content should not be passed through env")
+	}
+}
+
+func TestNewResourceRequest(t *testing.T) {
+	req := NewResourceRequest("task-1", executor.ActionApply, &models.TerraformResource{
+		ID:         42,
+		Provider:   "aws",
+		TfConfig:   "resource {}",
+		Credential: "prod",
+		TfState:    "{}",
+	})
+	if req.ResourceID != 42 || req.Provider != "aws" || req.Credential != "prod" || req.Config != "resource {}" || req.State != "{}" {
+		t.Errorf("request fields wrong: %+v", req)
+	}
+}
+
+func TestExecutor_prepare_SecretParams(t *testing.T) {
+	t.Setenv("PRISM_SECRET_DB", "hunter2")
+	resolver := secret.NewResolver()
+	resolver.Register("env", secret.NewEnvProvider("PRISM_SECRET_"))
+
+	exec := New(nil, nil, nil, nil)
+	exec.SetSecretResolver(resolver)
+	tk := exec.newTask()
+
+	req := &executor.ExecuteRequest{
+		Params: map[string]string{
+			"db_password": "secret://env/PRISM_SECRET_DB",
+			"size":        "small",
+		},
+	}
+	if err := tk.prepare(context.Background(), t.TempDir(), req); err != nil {
+		t.Fatalf("prepare should succeed: %v", err)
+	}
+
+	if v, _ := tk.env.Get("TF_VAR_db_password"); v != "hunter2" {
+		t.Errorf("secret param should be resolved, got %q", v)
+	}
+	if strings.Contains(tk.env.String(), "hunter2") {
+		t.Error("resolved secret should be treated as sensitive")
+	}
+	if req.Params["db_password"] != "secret://env/PRISM_SECRET_DB" {
+		t.Error("request params should keep the reference")
+	}
+
+	// 未配置解析器时不能把引用原样作为变量值
+	err := New(nil, nil, nil, nil).newTask().prepare(context.Background(), t.TempDir(), req)
+	if err == nil || !strings.Contains(err.Error(), "db_password") {
+		t.Errorf("secret reference without resolver should fail, got %v", err)
+	}
+}
+
+func TestConfigParams(t *testing.T) {
+	params := ConfigParams([]models.TerraformConfigParam{
+		{ParamName: "size", ParamValue: "large", DefaultValue: "small"},
+		{ParamName: "region", DefaultValue: "us-east-1"},
+		{ParamName: "password", ParamValue: "secret://vault/db#password"},
+	})
+	if params["size"] != "large" || params["region"] != "us-east-1" || params["password"] != "secret://vault/db#password" {
+		t.Errorf("params wrong: %v", params)
+	}
+}
+
+func TestParamsRequestBuilder(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := dao.NewTerraformResourceParamDAO(db)
+	if err := params.Replace(42, []models.TerraformResourceParam{{ParamName: "cidr", ParamValue: "10.0.0.0/16"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	build := ParamsRequestBuilder(params)
+	req, err := build("task-1", executor.ActionApply, &models.TerraformResource{ID: 42, TfConfig: "resource {}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Config != "resource {}" || req.Params["cidr"] != "10.0.0.0/16" {
+		t.Errorf("request wrong: %+v", req)
+	}
+
+	// 参数加载失败时不构建缺少变量的请求
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	if _, err := build("task-2", executor.ActionApply, &models.TerraformResource{ID: 42}); err == nil {
+		t.Error("expected error when params cannot be loaded")
+	}
+}
+
+func TestResourceRequestBuilder(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources := dao.NewTerraformResourceDAO(db)
+	params := dao.NewTerraformResourceParamDAO(db)
+	resource := &models.TerraformResource{ID: 42, Provider: "aws", ResourceType: "vpc", TfConfig: "resource {}"}
+	if err := resources.Create(resource); err != nil {
+		t.Fatal(err)
+	}
+	if err := params.Replace(resource.ID, []models.TerraformResourceParam{{ParamName: "password", ParamValue: "secret://vault/db#password"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 自动重试与手动重试都以原始参数重建请求
+	build := ResourceRequestBuilder(resources, params)
+	req, err := build(&models.ExecutionTask{TaskID: "task-1", ResourceID: resource.ID, Action: "apply"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Action != executor.ActionApply || req.Config != "resource {}" || req.Params["password"] != "secret://vault/db#password" {
+		t.Errorf("request wrong: %+v", req)
+	}
+
+	exec := New(nil, nil, dao.NewExecutionTaskDAO(db), nil)
+	if _, err := exec.Retry(context.Background(), "task-1"); err == nil || !strings.Contains(err.Error(), "request builder") {
+		t.Errorf("retry without a request builder should fail: %v", err)
+	}
+}
+
+func TestExecutor_Redaction(t *testing.T) {
+	exec := New(nil, nil, nil, nil).newTask()
+	exec.redactor.Add("hunter2")
+
+	exec.sendLog("task-1", `{"@level":"error","@message":"Error: auth failed","type":"diagnostic","diagnostic":{"severity":"error","summary":"auth failed","detail":"password hunter2 rejected"}}`)
+
+	if summary := exec.getErrorSummary(); strings.Contains(summary, "hunter2") || !strings.Contains(summary, secret.Mask) {
+		t.Errorf("error summary should be redacted: %s", summary)
+	}
+	if errs := exec.GetErrors(); len(errs) != 1 || strings.Contains(errs[0].Detail, "hunter2") {
+		t.Errorf("diagnostics should be redacted: %+v", errs)
+	}
+	if exec.errors[0].Detail != "password hunter2 rejected" {
+		t.Error("stored diagnostics should not be modified")
+	}
+}
+
+func TestOutcomeSummary(t *testing.T) {
+	if s := outcomeSummary(nil); s != "" {
+		t.Errorf("empty outcomes should have no summary, got %q", s)
+	}
+
+	s := outcomeSummary([]executor.ResourceOutcome{
+		{Address: "a", Outcome: executor.OutcomeCreated},
+		{Address: "b", Outcome: executor.OutcomeFailed},
+		{Address: "c", Outcome: executor.OutcomeCreated},
+		{Address: "d", Outcome: executor.OutcomeSkipped},
+	})
+	if s != "Partially applied: 2 created, 1 failed, 1 skipped" {
+		t.Errorf("unexpected summary: %s", s)
+	}
+}
+
+func TestExecutor_Diagnostics(t *testing.T) {
+	exec := New(nil, nil, nil, nil).newTask()
+	exec.redactor.Add("hunter2")
+
+	exec.sendLog("task-1", `{"@level":"warn","type":"diagnostic","diagnostic":{"severity":"warning","summary":"Argument is deprecated","address":"aws_s3_bucket.logs","range":{"filename":"main.tf","start":{"line":7,"column":3}}}}`)
+	exec.sendLog("task-1", `{"@level":"error","type":"diagnostic","diagnostic":{"severity":"error","summary":"auth failed","snippet":{"code":"password = \"hunter2\"","start_line":3}}}`)
+
+	if len(exec.GetErrors()) != 1 {
+		t.Errorf("only errors should be in GetErrors, got %d", len(exec.GetErrors()))
+	}
+
+	diagnostics := exec.GetDiagnostics()
+	if len(diagnostics) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %d", len(diagnostics))
+	}
+	if d := diagnostics[0]; d.Severity != "warning" || d.Filename != "main.tf" || d.Line != 7 || d.Address != "aws_s3_bucket.logs" {
+		t.Errorf("unexpected warning: %+v", d)
+	}
+	if raw := string(diagnostics[1].Raw); strings.Contains(raw, "hunter2") || !strings.Contains(raw, "snippet") {
+		t.Errorf("raw diagnostic should keep snippet with secrets redacted: %s", raw)
+	}
+}
+
+func TestExecutor_PipelineActions(t *testing.T) {
+	binary, calls := fakeTerraform(t)
+	store := ws.NewMemoryStore()
+	hub := ws.NewHub()
+	hub.SetLogStore(store)
+	exec := New(&Config{BinaryPath: binary, BasePath: t.TempDir()}, nil, nil, hub)
+	workDir := t.TempDir()
+
+	result, err := exec.Execute(context.Background(), &executor.ExecuteRequest{
+		TaskID: "task-1", ResourceID: 1, Action: executor.ActionValidate, WorkDir: workDir, Config: "# invalid",
+	})
+	if err == nil || len(result.Diagnostics) != 1 || result.Diagnostics[0].Summary != "Unsupported argument" {
+		t.Fatalf("validate should fail with diagnostics: %v %+v", err, result.Diagnostics)
+	}
+
+	steps := []executor.ExecuteRequest{
+		{TaskID: "task-2", Action: executor.ActionPlan, PlanFile: "tfplan", Initialized: true},
+		{TaskID: "task-3", Action: executor.ActionApply, PlanFile: "tfplan", Initialized: true},
+		{TaskID: "task-4", Action: executor.ActionOutput, Initialized: true},
+	}
+	var tk *task
+	for i := range steps {
+		steps[i].ResourceID, steps[i].WorkDir, steps[i].Config = 1, workDir, "# previous"
+		tk = exec.newTask()
+		if result, err = tk.execute(context.Background(), &steps[i]); err != nil {
+			t.Fatalf("%s failed: %v", steps[i].Action, err)
+		}
+	}
+	if result.Attributes["ip"] != "10.0.0.1" || result.Attributes["tags"] != `{"env":"dev"}` {
+		t.Errorf("unexpected outputs: %v", result.Attributes)
+	}
+	if _, ok := result.Attributes["password"]; ok {
+		t.Errorf("sensitive outputs should be left out of attributes: %v", result.Attributes)
+	}
+	if tk.redactor.Redact("s3cret") == "s3cret" {
+		t.Error("sensitive outputs should be redacted")
+	}
+	if msg := completeMessage(t, store, "task-4"); strings.Contains(msg, "s3cret") {
+		t.Errorf("persisted complete message should have no secrets: %s", msg)
+	}
+
+	data, _ := os.ReadFile(calls)
+	expected := "init -no-color\nvalidate -json\nplan -input=false -json -out=tfplan\napply -auto-approve -json tfplan\noutput -json\n"
+	if string(data) != expected {
+		t.Errorf("init should only run once, got:\n%s", data)
+	}
+}
+
+type indexerFunc func(resourceID int64, state string) error
+
+func (f indexerFunc) Index(resourceID int64, state string) error { return f(resourceID, state) }
+
+func TestExecutor_AttributeIndexer(t *testing.T) {
+	binary, _ := fakeTerraform(t)
+	exec := New(&Config{BinaryPath: binary, BasePath: t.TempDir()}, nil, nil, nil)
+	indexed := make(map[int64]string)
+	exec.SetAttributeIndexer(indexerFunc(func(resourceID int64, state string) error {
+		indexed[resourceID] = state
+		return fmt.Errorf("no rules")
+	}))
+
+	// 失败的 apply 也会留下部分创建的资源
+	exec.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-1", ResourceID: 1, Action: executor.ActionApply})
+	if _, err := exec.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-2", ResourceID: 2, Action: executor.ActionDestroy}); err != nil {
+		t.Fatalf("destroy failed: %v", err)
+	}
+	exec.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-3", ResourceID: 3, Action: executor.ActionPlan})
+
+	if indexed[1] != `{"version":4,"serial":2}`+"\n" || indexed[2] != `{"version":4,"serial":3}`+"\n" {
+		t.Errorf("apply and destroy states should be indexed: %v", indexed)
+	}
+	if _, ok := indexed[3]; ok {
+		t.Error("plan should not be indexed")
+	}
+}
+
+func TestExecutor_ConcurrentTasks(t *testing.T) {
+	binary, _ := fakeTerraform(t)
+	exec := New(&Config{BinaryPath: binary, BasePath: t.TempDir()}, nil, nil, nil)
+
+	var wg sync.WaitGroup
+	results := make([]*executor.ExecuteResult, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = exec.Execute(context.Background(), &executor.ExecuteRequest{
+				TaskID:     fmt.Sprintf("task-%d", i),
+				ResourceID: int64(i),
+				Action:     executor.ActionValidate,
+				Config:     "# invalid",
+				Params:     map[string]string{"password": fmt.Sprintf("secret-%d", i)},
+				Sensitive:  []string{"password"},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	// 每个任务只包含自己的诊断
+	for i, result := range results {
+		if len(result.Diagnostics) != 1 {
+			t.Errorf("task %d should have its own diagnostics, got %d", i, len(result.Diagnostics))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package terraform
+
+import (
+	"errors"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/logger"
+	models "github.com/cylonchau/prism/pkg/model"
+	"gorm.io/gorm"
+)
+
+// SetResourceDAO sets the resource store whose lifecycle status is driven by task outcomes.
+func (e *Executor) SetResourceDAO(resources *dao.TerraformResourceDAO) {
+	e.resources = resources
+}
+
+// startLifecycle moves the resource into the in-progress status of the action.
+// It returns false if the resource lifecycle is not tracked for this task.
+func (t *task) startLifecycle(req *executor.ExecuteRequest) bool {
+	switch req.Action {
+	case executor.ActionApply:
+		return t.transitionResource(req, models.ResourceEventApply, "", false)
+	case executor.ActionDestroy:
+		return t.transitionResource(req, models.ResourceEventDestroy, "", false)
+	}
+	return false
+}
+
+// finishLifecycle moves the resource according to the task result. A plan marks
+// the resource drifted when terraform detected changes made outside of it.
+func (t *task) finishLifecycle(req *executor.ExecuteRequest, result *executor.ExecuteResult, started bool) {
+	switch {
+	case req.Action == executor.ActionPlan && result.Status == executor.StatusSuccess:
+		if t.drifted {
+			t.transitionResource(req, models.ResourceEventDrift, "Changes outside of terraform detected", true)
+		} else {
+			t.transitionResource(req, models.ResourceEventSync, "", true)
+		}
+	case !started:
+	case result.Status == executor.StatusSuccess:
+		t.transitionResource(req, models.ResourceEventComplete, "", false)
+	default:
+		t.transitionResource(req, models.ResourceEventFail, result.Error, false)
+		// 重新 apply 上一版本配置成功后资源恢复可用
+		if rb := result.Rollback; rb != nil && rb.Status == executor.StatusSuccess && req.Rollback == executor.RollbackReapply {
+			reason := "Rolled back to previous config in task " + rb.TaskID
+			if t.transitionResource(req, models.ResourceEventApply, reason, false) {
+				t.transitionResource(req, models.ResourceEventComplete, reason, false)
+			}
+		}
+	}
+}
+
+// transitionResource applies a lifecycle event to the resource of a task. Resources
+// that are not stored are ignored; optional events are skipped silently when the
+// current status does not allow them.
+func (t *task) transitionResource(req *executor.ExecuteRequest, event, reason string, optional bool) bool {
+	if t.resources == nil {
+		return false
+	}
+	status, err := t.resources.Transition(req.ResourceID, event, req.TaskID, t.redactor.Redact(reason))
+	switch {
+	case err == nil:
+		t.log.Info("Resource status changed",
+			logger.Int64("resource_id", req.ResourceID),
+			logger.String("task_id", req.TaskID),
+			logger.String("status", status))
+		return true
+	case errors.Is(err, gorm.ErrRecordNotFound), optional && errors.Is(err, dao.ErrInvalidTransition):
+	default:
+		t.log.Warn("Failed to change resource status",
+			logger.Int64("resource_id", req.ResourceID),
+			logger.String("task_id", req.TaskID),
+			logger.String("event", event),
+			logger.Err(err))
+	}
+	return false
+}
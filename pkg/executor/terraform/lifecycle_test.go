@@ -0,0 +1,67 @@
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestExecutor_Lifecycle(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources := dao.NewTerraformResourceDAO(db)
+	if err := resources.Create(&models.TerraformResource{ID: 1, Provider: "aws", ResourceType: "ec2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	binary, _ := fakeTerraform(t)
+	exec := New(&Config{BinaryPath: binary, BasePath: t.TempDir()}, nil, nil, nil)
+	exec.SetResourceDAO(resources)
+
+	steps := []struct {
+		req      executor.ExecuteRequest
+		expected string
+	}{
+		// apply 失败
+		{executor.ExecuteRequest{Action: executor.ActionApply, Config: "# current"}, models.ResourceStatusError},
+		// plan 只在 active 与 drifted 之间转换
+		{executor.ExecuteRequest{Action: executor.ActionPlan, Config: "# drift"}, models.ResourceStatusError},
+		// apply 失败后重新 apply 上一版本配置成功
+		{executor.ExecuteRequest{Action: executor.ActionApply, Config: "# current", Rollback: executor.RollbackReapply, PreviousConfig: "# previous"}, models.ResourceStatusActive},
+		{executor.ExecuteRequest{Action: executor.ActionPlan, Config: "# drift"}, models.ResourceStatusDrifted},
+		{executor.ExecuteRequest{Action: executor.ActionPlan, Config: "# current"}, models.ResourceStatusActive},
+		{executor.ExecuteRequest{Action: executor.ActionDestroy}, models.ResourceStatusDestroyed},
+	}
+	for i, step := range steps {
+		req := step.req
+		req.TaskID = "task-" + string(rune('1'+i))
+		req.ResourceID = 1
+		exec.Execute(context.Background(), &req)
+
+		resource, err := resources.Get(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resource.Status != step.expected {
+			t.Errorf("step %d (%s): expected %s, got %s", i, req.Action, step.expected, resource.Status)
+		}
+	}
+
+	transitions, err := resources.ListTransitions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 10 {
+		t.Fatalf("expected 10 transitions, got %d", len(transitions))
+	}
+	if tr := transitions[1]; tr.Event != models.ResourceEventFail || tr.TaskID != "task-1" || tr.Reason == "" {
+		t.Errorf("failure should be recorded with reason: %+v", tr)
+	}
+}
@@ -2,6 +2,7 @@ package terraform
 
 import (
 	"encoding/json"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -10,6 +11,7 @@ import (
 )
 
 // TerraformMessage represents a single JSON message from terraform output.
+// See https://developer.hashicorp.com/terraform/internals/machine-readable-ui
 type TerraformMessage struct {
 	Level     string `json:"@level"`
 	Message   string `json:"@message"`
@@ -27,8 +29,39 @@ type TerraformMessage struct {
 	// For diagnostic (errors)
 	Diagnostic *Diagnostic `json:"diagnostic,omitempty"`
 
-	// For hooks (refresh_start, apply_start, etc)
+	// For hooks (refresh_start, apply_start, provision_start, etc)
 	Hook *HookInfo `json:"hook,omitempty"`
+
+	// For planned_change and resource_drift
+	Change *ResourceChange `json:"change,omitempty"`
+
+	// For outputs
+	Outputs map[string]OutputInfo `json:"outputs,omitempty"`
+
+	// For terraform test
+	TestAbstract  map[string][]string `json:"test_abstract,omitempty"` // file -> run blocks
+	TestFile      *TestFile           `json:"test_file,omitempty"`
+	TestRun       *TestRun            `json:"test_run,omitempty"`
+	TestCleanup   *TestCleanup        `json:"test_cleanup,omitempty"`
+	TestSummary   *TestSummary        `json:"test_summary,omitempty"`
+	TestPlan      json.RawMessage     `json:"test_plan,omitempty"`      // plan in terraform show -json format
+	TestState     json.RawMessage     `json:"test_state,omitempty"`     // state in terraform show -json format
+	TestInterrupt json.RawMessage     `json:"test_interrupt,omitempty"` // resources left behind by an interrupt
+}
+
+// ResourceChange describes a planned change or drift of a resource.
+type ResourceChange struct {
+	Resource         *ResourceInfo  `json:"resource,omitempty"`
+	PreviousResource *ResourceInfo  `json:"previous_resource,omitempty"` // set for moved resources
+	Action           string         `json:"action"`                      // noop, create, read, update, replace, delete, move, remove, import
+	Reason           string         `json:"reason,omitempty"`            // why replace/delete/read is required
+	Importing        *ImportingInfo `json:"importing,omitempty"`
+	GeneratedConfig  string         `json:"generated_config,omitempty"`
+}
+
+// ImportingInfo describes a resource being imported.
+type ImportingInfo struct {
+	ID string `json:"id"`
 }
 
 // ChangeSummary contains plan change statistics.
@@ -37,7 +70,7 @@ type ChangeSummary struct {
 	Change    int    `json:"change"`
 	Import    int    `json:"import"`
 	Remove    int    `json:"remove"`
-	Operation string `json:"operation"`
+	Operation string `json:"operation"` // plan, apply, destroy
 }
 
 // Diagnostic contains error/warning information.
@@ -47,6 +80,7 @@ type Diagnostic struct {
 	Detail   string       `json:"detail"`
 	Address  string       `json:"address"`
 	Range    *SourceRange `json:"range,omitempty"`
+	Snippet  *Snippet     `json:"snippet,omitempty"`
 }
 
 // SourceRange points to source code location.
@@ -55,26 +89,130 @@ type SourceRange struct {
 	Start    struct {
 		Line   int `json:"line"`
 		Column int `json:"column"`
+		Byte   int `json:"byte"`
 	} `json:"start"`
+	End struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+		Byte   int `json:"byte"`
+	} `json:"end"`
+}
+
+// Snippet is the source code excerpt of a diagnostic.
+type Snippet struct {
+	Context              string              `json:"context"`
+	Code                 string              `json:"code"`
+	StartLine            int                 `json:"start_line"`
+	HighlightStartOffset int                 `json:"highlight_start_offset"`
+	HighlightEndOffset   int                 `json:"highlight_end_offset"`
+	Values               []ExpressionValue   `json:"values,omitempty"`
+	FunctionCall         *FunctionCallDetail `json:"function_call,omitempty"`
+}
+
+// ExpressionValue is a value referenced by the expression of a diagnostic.
+type ExpressionValue struct {
+	Traversal string `json:"traversal"`
+	Statement string `json:"statement"`
+}
+
+// FunctionCallDetail describes the function call that caused a diagnostic.
+type FunctionCallDetail struct {
+	CalledAs  string          `json:"called_as"`
+	Signature json.RawMessage `json:"signature,omitempty"`
 }
 
 // HookInfo contains resource operation information.
 type HookInfo struct {
-	Resource *ResourceInfo `json:"resource,omitempty"`
-	Action   string        `json:"action,omitempty"`
-	IDKey    string        `json:"id_key,omitempty"`
-	IDValue  string        `json:"id_value,omitempty"`
+	Resource       *ResourceInfo `json:"resource,omitempty"`
+	Action         string        `json:"action,omitempty"`
+	IDKey          string        `json:"id_key,omitempty"`
+	IDValue        string        `json:"id_value,omitempty"`
+	ElapsedSeconds float64       `json:"elapsed_seconds,omitempty"`
+	Provisioner    string        `json:"provisioner,omitempty"` // provision_* only
+	Output         string        `json:"output,omitempty"`      // provision_progress only
 }
 
 // ResourceInfo contains resource details.
 type ResourceInfo struct {
-	Addr         string `json:"addr"`
-	Module       string `json:"module"`
-	Resource     string `json:"resource"`
-	ResourceType string `json:"resource_type"`
-	ResourceName string `json:"resource_name"`
+	Addr            string      `json:"addr"`
+	Module          string      `json:"module"`
+	Resource        string      `json:"resource"`
+	ResourceType    string      `json:"resource_type"`
+	ResourceName    string      `json:"resource_name"`
+	ResourceKey     interface{} `json:"resource_key,omitempty"` // count index or for_each key
+	ImpliedProvider string      `json:"implied_provider,omitempty"`
+}
+
+// OutputInfo describes a root module output.
+type OutputInfo struct {
+	Sensitive bool            `json:"sensitive"`
+	Type      json.RawMessage `json:"type,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Action    string          `json:"action,omitempty"` // set in plan: noop, create, update, delete
 }
 
+// TestFile reports the status of a test file.
+type TestFile struct {
+	Path     string `json:"path"`
+	Progress string `json:"progress"` // starting, running, teardown, complete
+	Status   string `json:"status"`   // pending, skip, pass, fail, error
+}
+
+// TestRun reports the status of a run block.
+type TestRun struct {
+	Path     string  `json:"path"`
+	Run      string  `json:"run"`
+	Progress string  `json:"progress"`
+	Elapsed  float64 `json:"elapsed,omitempty"` // milliseconds
+	Status   string  `json:"status"`
+}
+
+// TestCleanup lists resources that failed to be destroyed after a test.
+type TestCleanup struct {
+	FailedResources []struct {
+		Instance   string `json:"instance"`
+		DeposedKey string `json:"deposed_key,omitempty"`
+	} `json:"failed_resources"`
+}
+
+// TestSummary reports the result of a test command.
+type TestSummary struct {
+	Status  string `json:"status"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Errored int    `json:"errored"`
+	Skipped int    `json:"skipped"`
+}
+
+// JSON UI message types.
+const (
+	MessageVersion           = "version"
+	MessageLog               = "log"
+	MessageDiagnostic        = "diagnostic"
+	MessagePlannedChange     = "planned_change"
+	MessageResourceDrift     = "resource_drift"
+	MessageChangeSummary     = "change_summary"
+	MessageOutputs           = "outputs"
+	MessageApplyStart        = "apply_start"
+	MessageApplyProgress     = "apply_progress"
+	MessageApplyComplete     = "apply_complete"
+	MessageApplyErrored      = "apply_errored"
+	MessageRefreshStart      = "refresh_start"
+	MessageRefreshComplete   = "refresh_complete"
+	MessageProvisionStart    = "provision_start"
+	MessageProvisionProgress = "provision_progress"
+	MessageProvisionComplete = "provision_complete"
+	MessageProvisionErrored  = "provision_errored"
+	MessageTestAbstract      = "test_abstract"
+	MessageTestFile          = "test_file"
+	MessageTestRun           = "test_run"
+	MessageTestCleanup       = "test_cleanup"
+	MessageTestSummary       = "test_summary"
+	MessageTestPlan          = "test_plan"
+	MessageTestState         = "test_state"
+	MessageTestInterrupt     = "test_interrupt"
+)
+
 // PlanInfo plan result summary.
 type PlanInfo struct {
 	ToAdd     int
@@ -86,7 +224,10 @@ type PlanInfo struct {
 type ParseResult struct {
 	Messages []TerraformMessage
 	Changes  *ChangeSummary
-	Errors   []Diagnostic
+	Errors   []Diagnostic // all diagnostics, including warnings
+	Outputs  map[string]OutputInfo
+	Drift    []ResourceChange // resources changed outside of terraform
+	Test     *TestSummary
 	Version  string
 	Success  bool
 }
@@ -104,44 +245,15 @@ func NewParser() *Parser {
 }
 
 // ParseJSONOutput parses terraform JSON output (one JSON per line).
+// Use ParseJSONStream for large outputs.
 func (p *Parser) ParseJSONOutput(output string) *ParseResult {
-	result := &ParseResult{
-		Messages: []TerraformMessage{},
-		Errors:   []Diagnostic{},
-		Success:  true,
-	}
-
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || !strings.HasPrefix(line, "{") {
-			continue
-		}
-
-		var msg TerraformMessage
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
-			continue
-		}
-
-		result.Messages = append(result.Messages, msg)
-
-		switch msg.Type {
-		case "version":
-			result.Version = msg.Terraform
-		case "change_summary":
-			if msg.Changes != nil {
-				result.Changes = msg.Changes
-			}
-		case "diagnostic":
-			if msg.Diagnostic != nil {
-				result.Errors = append(result.Errors, *msg.Diagnostic)
-				if msg.Diagnostic.Severity == "error" {
-					result.Success = false
-				}
-			}
-		}
+	var messages []TerraformMessage
+	result, _ := p.ParseJSONStream(strings.NewReader(output), func(msg *TerraformMessage) {
+		messages = append(messages, *msg)
+	})
+	if messages != nil {
+		result.Messages = messages
 	}
-
 	return result
 }
 
@@ -206,6 +318,56 @@ func (p *Parser) ParseTfstate(data []byte) map[string]string {
 	return attrs
 }
 
+// StateOutputs returns root module outputs recorded in tfstate by name, without
+// sensitive outputs. Values are strings as-is and other types JSON encoded.
+func (p *Parser) StateOutputs(data []byte) map[string]string {
+	outputs := make(map[string]string)
+	gjson.GetBytes(data, "outputs").ForEach(func(name, output gjson.Result) bool {
+		if output.Get("sensitive").Bool() {
+			return true
+		}
+		value := output.Get("value")
+		if value.Type == gjson.String {
+			outputs[name.String()] = value.String()
+		} else {
+			outputs[name.String()] = value.Raw
+		}
+		return true
+	})
+	return outputs
+}
+
+// Outputs holds root module outputs read by terraform output -json.
+// Values are strings as-is and other types JSON encoded.
+type Outputs struct {
+	Values    map[string]string
+	Sensitive map[string]bool
+}
+
+// ParseOutputs parses terraform output -json.
+func (p *Parser) ParseOutputs(data []byte) (*Outputs, error) {
+	var raw map[string]OutputInfo
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse outputs: %w", err)
+	}
+	outputs := &Outputs{
+		Values:    make(map[string]string, len(raw)),
+		Sensitive: make(map[string]bool),
+	}
+	for name, output := range raw {
+		var str string
+		if err := json.Unmarshal(output.Value, &str); err == nil {
+			outputs.Values[name] = str
+		} else {
+			outputs.Values[name] = string(output.Value)
+		}
+		if output.Sensitive {
+			outputs.Sensitive[name] = true
+		}
+	}
+	return outputs, nil
+}
+
 // ParseTfstateJSON parses tfstate to structured data.
 func (p *Parser) ParseTfstateJSON(data []byte) (*TfState, error) {
 	var state TfState
@@ -251,3 +413,62 @@ func (p *Parser) ExtractAttributes(data []byte, paths map[string]string) map[str
 
 	return result
 }
+
+// SensitiveValues extracts values marked sensitive in tfstate (sensitive outputs
+// and instance sensitive_attributes), used to redact task output.
+func (p *Parser) SensitiveValues(data []byte) []string {
+	var values []string
+
+	gjson.GetBytes(data, "outputs").ForEach(func(_, output gjson.Result) bool {
+		if output.Get("sensitive").Bool() {
+			values = appendLeaves(values, output.Get("value"))
+		}
+		return true
+	})
+
+	gjson.GetBytes(data, "resources").ForEach(func(_, resource gjson.Result) bool {
+		resource.Get("instances").ForEach(func(_, instance gjson.Result) bool {
+			attributes := instance.Get("attributes")
+			instance.Get("sensitive_attributes").ForEach(func(_, path gjson.Result) bool {
+				value := attributes
+				path.ForEach(func(_, step gjson.Result) bool {
+					key := step.Get("value")
+					if key.IsObject() {
+						key = key.Get("value")
+					}
+					value = value.Get(gjsonEscape(key.String()))
+					return value.Exists()
+				})
+				if value.Exists() {
+					values = appendLeaves(values, value)
+				}
+				return true
+			})
+			return true
+		})
+		return true
+	})
+
+	return values
+}
+
+// appendLeaves appends all scalar leaves of a JSON value.
+func appendLeaves(values []string, value gjson.Result) []string {
+	if value.IsObject() || value.IsArray() {
+		value.ForEach(func(_, v gjson.Result) bool {
+			values = appendLeaves(values, v)
+			return true
+		})
+		return values
+	}
+	if value.Exists() && value.Type != gjson.Null {
+		values = append(values, value.String())
+	}
+	return values
+}
+
+// gjsonEscape escapes gjson path special characters in a single key.
+func gjsonEscape(key string) string {
+	replacer := strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`, "|", `\|`, "#", `\#`, "@", `\@`)
+	return replacer.Replace(key)
+}
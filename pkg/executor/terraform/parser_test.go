@@ -325,3 +325,80 @@ func TestParser_ParsePlan_JSON(t *testing.T) {
 		t.Errorf("ToDestroy should be 1, got %d", info.ToDestroy)
 	}
 }
+
+func TestParser_SensitiveValues(t *testing.T) {
+	parser := NewParser()
+
+	tfstate := []byte(`{
+		"version": 4,
+		"outputs": {
+			"db_password": {"value": "out-secret", "type": "string", "sensitive": true},
+			"endpoint": {"value": "db.example.com", "type": "string"}
+		},
+		"resources": [
+			{
+				"type": "aws_db_instance",
+				"name": "main",
+				"instances": [
+					{
+						"attributes": {
+							"id": "db-1",
+							"password": "attr-secret",
+							"connection": {"token": "nested-secret", "host": "localhost"},
+							"keys": ["list-secret-0", "list-secret-1"]
+						},
+						"sensitive_attributes": [
+							[{"type": "get_attr", "value": "password"}],
+							[{"type": "get_attr", "value": "connection"}, {"type": "get_attr", "value": "token"}],
+							[{"type": "get_attr", "value": "keys"}, {"type": "index", "value": {"value": 1, "type": "number"}}]
+						]
+					}
+				]
+			}
+		]
+	}`)
+
+	values := parser.SensitiveValues(tfstate)
+
+	expected := map[string]bool{"out-secret": true, "attr-secret": true, "nested-secret": true, "list-secret-1": true}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %d values, got %v", len(expected), values)
+	}
+	for _, v := range values {
+		if !expected[v] {
+			t.Errorf("unexpected sensitive value %q", v)
+		}
+	}
+}
+
+func TestParser_ParseOutputs(t *testing.T) {
+	parser := NewParser()
+	outputs, err := parser.ParseOutputs([]byte(`{"ip":{"sensitive":false,"value":"10.0.0.1"},"ports":{"sensitive":false,"value":[80,443]},"password":{"sensitive":true,"value":"s3cret"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outputs.Values["ip"] != "10.0.0.1" || outputs.Values["ports"] != "[80,443]" || outputs.Values["password"] != "s3cret" {
+		t.Errorf("unexpected values: %v", outputs.Values)
+	}
+	if !outputs.Sensitive["password"] || outputs.Sensitive["ip"] {
+		t.Errorf("unexpected sensitive outputs: %v", outputs.Sensitive)
+	}
+
+	if _, err := parser.ParseOutputs([]byte("not json")); err == nil {
+		t.Error("invalid output should fail")
+	}
+}
+
+func TestParser_StateOutputs(t *testing.T) {
+	parser := NewParser()
+	outputs := parser.StateOutputs([]byte(`{"outputs":{"vpc_id":{"value":"vpc-1","type":"string"},"subnets":{"value":["a","b"]},"password":{"value":"s3cret","sensitive":true}}}`))
+	if outputs["vpc_id"] != "vpc-1" || outputs["subnets"] != `["a","b"]` {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+	if _, ok := outputs["password"]; ok {
+		t.Errorf("sensitive outputs should be skipped: %v", outputs)
+	}
+	if len(parser.StateOutputs([]byte(`{"version":4}`))) != 0 {
+		t.Error("state without outputs should have none")
+	}
+}
@@ -0,0 +1,348 @@
+package terraform
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/executor"
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// defaultParallelism terraform apply 默认的 -parallelism
+const defaultParallelism = 10
+
+// 资源执行状态
+const (
+	ResourcePending  = "pending"
+	ResourceRunning  = "running"
+	ResourceComplete = "complete"
+	ResourceErrored  = "errored"
+)
+
+// phaseBand 阶段在总进度中的区间 [start, end]
+type phaseBand struct {
+	start, end int
+}
+
+var phaseBands = map[string]phaseBand{
+	"init":                    {0, 10},
+	"plan":                    {10, 30},
+	"apply":                   {30, 95},
+	"destroy":                 {30, 95},
+	"parse":                   {95, 100},
+	models.TaskPhaseCompleted: {100, 100},
+}
+
+// DurationHistory 资源类型各操作的历史耗时，由 dao.ResourceDurationDAO 实现
+type DurationHistory interface {
+	Estimate(resourceType, action string) (time.Duration, bool)
+	Record(resourceType, action string, elapsed time.Duration) error
+}
+
+// trackedResource 跟踪中的资源
+type trackedResource struct {
+	executor.ResourceProgress
+	started time.Time     // 当前操作开始时间
+	spent   time.Duration // 已完成操作的耗时，replace 会产生 delete 与 create 两次操作
+	id      string        // apply_complete 返回的云上资源 ID
+}
+
+// historyEstimate 资源类型某操作的历史耗时，ok 为 false 表示没有历史记录
+type historyEstimate struct {
+	duration time.Duration
+	ok       bool
+}
+
+// ProgressTracker 根据 Terraform JSON UI 的 planned_change 与 apply_* 消息计算任务进度
+type ProgressTracker struct {
+	mu        sync.Mutex
+	history   DurationHistory
+	estimates map[string]historyEstimate // <type>/<action> -> 历史耗时，每个任务只查询一次
+	phase     string
+	message   string
+	order     []string
+	resources map[string]*trackedResource
+	now       func() time.Time
+}
+
+// NewProgressTracker 创建进度跟踪器，history 为空时仅使用本次执行中已完成资源的平均耗时估算
+func NewProgressTracker(history DurationHistory) *ProgressTracker {
+	return &ProgressTracker{
+		history:   history,
+		estimates: make(map[string]historyEstimate),
+		resources: make(map[string]*trackedResource),
+		now:       time.Now,
+	}
+}
+
+// Reset 清空跟踪状态
+func (t *ProgressTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phase, t.message = "", ""
+	t.order = nil
+	t.resources = make(map[string]*trackedResource)
+	t.estimates = make(map[string]historyEstimate)
+}
+
+// SetPhase 进入新阶段
+func (t *ProgressTracker) SetPhase(phase, message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phase, t.message = phase, message
+}
+
+// Observe 处理一条 Terraform 消息，返回进度是否变化
+func (t *ProgressTracker) Observe(msg *TerraformMessage) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch msg.Type {
+	case MessagePlannedChange:
+		if msg.Change == nil || msg.Change.Resource == nil || !countedAction(msg.Change.Action) {
+			return false
+		}
+		t.track(msg.Change.Resource, msg.Change.Action)
+		return true
+	case MessageApplyStart, MessageApplyProgress, MessageApplyComplete, MessageApplyErrored:
+		if msg.Hook == nil || msg.Hook.Resource == nil {
+			return false
+		}
+	default:
+		return false
+	}
+
+	r := t.track(msg.Hook.Resource, msg.Hook.Action)
+	now := t.now()
+	switch msg.Type {
+	case MessageApplyStart:
+		if r.Status != ResourceRunning {
+			r.Status = ResourceRunning
+			r.started = now
+		}
+	case MessageApplyProgress:
+		if r.Status != ResourceRunning {
+			r.Status = ResourceRunning
+			r.started = now.Add(-seconds(msg.Hook.ElapsedSeconds))
+		}
+	case MessageApplyComplete, MessageApplyErrored:
+		elapsed := seconds(msg.Hook.ElapsedSeconds)
+		if elapsed == 0 && !r.started.IsZero() {
+			elapsed = now.Sub(r.started)
+		}
+		r.spent += elapsed
+		r.started = time.Time{}
+		if msg.Hook.IDValue != "" {
+			r.id = msg.Hook.IDValue
+		}
+		r.Status = ResourceComplete
+		if msg.Type == MessageApplyErrored {
+			r.Status = ResourceErrored
+		}
+	}
+	t.message = msg.Message
+	return true
+}
+
+// Progress 返回当前进度，Elapsed 由 BaseExecutor 计算
+func (t *ProgressTracker) Progress() *executor.Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	p := &executor.Progress{
+		Phase:     t.phase,
+		Message:   t.message,
+		Planned:   len(t.order),
+		Resources: make([]executor.ResourceProgress, 0, len(t.order)),
+	}
+	finished := 0
+	for _, addr := range t.order {
+		r := t.resources[addr]
+		rp := r.ResourceProgress
+		rp.Elapsed = r.elapsed(now).Milliseconds()
+		p.Resources = append(p.Resources, rp)
+		switch r.Status {
+		case ResourceComplete:
+			p.Completed++
+			finished++
+		case ResourceErrored:
+			finished++
+		}
+	}
+
+	band := phaseBands[t.phase]
+	p.Percent = band.start
+	if (t.phase == "apply" || t.phase == "destroy") && p.Planned > 0 {
+		p.Percent += (band.end - band.start) * finished / p.Planned
+		p.ETA = t.eta(now).Milliseconds()
+	}
+	return p
+}
+
+// Record 将已完成资源的耗时写入历史记录
+func (t *ProgressTracker) Record() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.history == nil {
+		return nil
+	}
+	for _, addr := range t.order {
+		r := t.resources[addr]
+		if r.Status != ResourceComplete || r.Type == "" {
+			continue
+		}
+		if err := t.history.Record(r.Type, r.Action, r.spent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Outcomes 返回各资源的执行结果，errors 中带地址的诊断信息作为对应资源的失败原因
+func (t *ProgressTracker) Outcomes(errors []Diagnostic) []executor.ResourceOutcome {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	reasons := make(map[string][]string)
+	for _, d := range errors {
+		if d.Address != "" {
+			reasons[d.Address] = append(reasons[d.Address], d.Summary)
+		}
+	}
+
+	now := t.now()
+	outcomes := make([]executor.ResourceOutcome, 0, len(t.order))
+	for _, addr := range t.order {
+		r := t.resources[addr]
+		outcome := executor.ResourceOutcome{
+			Address:  addr,
+			Type:     r.Type,
+			Action:   r.Action,
+			ID:       r.id,
+			Duration: r.elapsed(now).Milliseconds(),
+		}
+		switch r.Status {
+		case ResourceComplete:
+			outcome.Outcome = completedOutcome(r.Action)
+		case ResourceErrored:
+			outcome.Outcome = executor.OutcomeFailed
+			outcome.Error = strings.Join(reasons[addr], "; ")
+		case ResourceRunning:
+			outcome.Outcome = executor.OutcomeInterrupted
+		default:
+			outcome.Outcome = executor.OutcomeSkipped
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+// completedOutcome 返回计划动作完成后的结果
+func completedOutcome(action string) executor.Outcome {
+	switch action {
+	case "create":
+		return executor.OutcomeCreated
+	case "update":
+		return executor.OutcomeUpdated
+	case "delete":
+		return executor.OutcomeDestroyed
+	case "replace":
+		return executor.OutcomeReplaced
+	default:
+		return executor.OutcomeRead
+	}
+}
+
+// track 返回资源的跟踪状态，不存在时加入跟踪
+func (t *ProgressTracker) track(info *ResourceInfo, action string) *trackedResource {
+	r, ok := t.resources[info.Addr]
+	if !ok {
+		r = &trackedResource{ResourceProgress: executor.ResourceProgress{
+			Address: info.Addr,
+			Type:    info.ResourceType,
+			Action:  action,
+			Status:  ResourcePending,
+		}}
+		t.resources[info.Addr] = r
+		t.order = append(t.order, info.Addr)
+	}
+	return r
+}
+
+// eta 估算剩余时间：并发执行时不短于最慢的资源，也不短于总耗时除以并发数。
+// 任一未完成资源无法估算时返回 0
+func (t *ProgressTracker) eta(now time.Time) time.Duration {
+	var spent time.Duration
+	var done int
+	for _, r := range t.resources {
+		if r.Status == ResourceComplete {
+			spent += r.spent
+			done++
+		}
+	}
+
+	var longest, total time.Duration
+	for _, r := range t.resources {
+		if r.Status == ResourceComplete || r.Status == ResourceErrored {
+			continue
+		}
+		estimate, ok := t.estimate(r.Type, r.Action)
+		if !ok {
+			if done == 0 {
+				return 0
+			}
+			estimate = spent / time.Duration(done)
+		}
+		remaining := estimate - r.elapsed(now)
+		if remaining < 0 {
+			remaining = 0
+		}
+		total += remaining
+		if remaining > longest {
+			longest = remaining
+		}
+	}
+
+	if avg := total / defaultParallelism; avg > longest {
+		return avg
+	}
+	return longest
+}
+
+// estimate 返回资源类型某操作的历史耗时，首次使用时查询并缓存，避免每次计算进度都查询历史记录
+func (t *ProgressTracker) estimate(resourceType, action string) (time.Duration, bool) {
+	if t.history == nil || resourceType == "" {
+		return 0, false
+	}
+	key := resourceType + "/" + action
+	e, cached := t.estimates[key]
+	if !cached {
+		e.duration, e.ok = t.history.Estimate(resourceType, action)
+		t.estimates[key] = e
+	}
+	return e.duration, e.ok
+}
+
+// elapsed 返回资源累计耗时
+func (r *trackedResource) elapsed(now time.Time) time.Duration {
+	if r.started.IsZero() {
+		return r.spent
+	}
+	return r.spent + now.Sub(r.started)
+}
+
+// countedAction 判断计划变更是否会在 apply 阶段执行
+func countedAction(action string) bool {
+	switch action {
+	case "create", "update", "delete", "replace", "read":
+		return true
+	}
+	return false
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
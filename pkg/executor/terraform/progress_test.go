@@ -0,0 +1,176 @@
+package terraform
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/executor"
+)
+
+// fakeHistory 固定耗时的历史记录，queries 为 Estimate 的调用次数
+type fakeHistory struct {
+	estimates map[string]time.Duration
+	recorded  map[string]time.Duration
+	queries   int
+}
+
+func (h *fakeHistory) Estimate(resourceType, action string) (time.Duration, bool) {
+	h.queries++
+	d, ok := h.estimates[resourceType+"/"+action]
+	return d, ok
+}
+
+func (h *fakeHistory) Record(resourceType, action string, elapsed time.Duration) error {
+	h.recorded[resourceType+"/"+action] = elapsed
+	return nil
+}
+
+func observe(t *testing.T, tracker *ProgressTracker, line string) bool {
+	t.Helper()
+	var msg TerraformMessage
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	return tracker.Observe(&msg)
+}
+
+func TestProgressTracker(t *testing.T) {
+	history := &fakeHistory{
+		estimates: map[string]time.Duration{"aws_instance/create": 60 * time.Second},
+		recorded:  map[string]time.Duration{},
+	}
+	now := time.Unix(1000, 0)
+	tracker := NewProgressTracker(history)
+	tracker.now = func() time.Time { return now }
+
+	tracker.SetPhase("apply", "Running terraform apply...")
+	if p := tracker.Progress(); p.Percent != 30 || p.Planned != 0 {
+		t.Errorf("unexpected initial progress: %+v", p)
+	}
+
+	observe(t, tracker, `{"type":"planned_change","change":{"resource":{"addr":"aws_instance.web","resource_type":"aws_instance"},"action":"create"}}`)
+	observe(t, tracker, `{"type":"planned_change","change":{"resource":{"addr":"aws_eip.web","resource_type":"aws_eip"},"action":"create"}}`)
+	if observe(t, tracker, `{"type":"planned_change","change":{"resource":{"addr":"aws_vpc.main","resource_type":"aws_vpc"},"action":"noop"}}`) {
+		t.Error("noop change should be ignored")
+	}
+
+	observe(t, tracker, `{"type":"apply_start","hook":{"resource":{"addr":"aws_eip.web","resource_type":"aws_eip"},"action":"create"}}`)
+	observe(t, tracker, `{"type":"apply_start","hook":{"resource":{"addr":"aws_instance.web","resource_type":"aws_instance"},"action":"create"}}`)
+	now = now.Add(4 * time.Second)
+	observe(t, tracker, `{"@message":"aws_eip.web: Creation complete","type":"apply_complete","hook":{"resource":{"addr":"aws_eip.web","resource_type":"aws_eip"},"action":"create","elapsed_seconds":4}}`)
+	now = now.Add(6 * time.Second)
+
+	p := tracker.Progress()
+	if p.Planned != 2 || p.Completed != 1 {
+		t.Fatalf("expected 1/2 completed, got %d/%d", p.Completed, p.Planned)
+	}
+	if p.Percent != 62 {
+		t.Errorf("percent should be 62, got %d", p.Percent)
+	}
+	if p.Message != "aws_eip.web: Creation complete" {
+		t.Errorf("unexpected message: %s", p.Message)
+	}
+	// aws_instance 历史耗时 60s，已运行 10s
+	if p.ETA != (50 * time.Second).Milliseconds() {
+		t.Errorf("eta should be 50s, got %dms", p.ETA)
+	}
+	if r := p.Resources[0]; r.Address != "aws_instance.web" || r.Status != ResourceRunning || r.Elapsed != 10000 {
+		t.Errorf("unexpected resource progress: %+v", r)
+	}
+	if r := p.Resources[1]; r.Status != ResourceComplete || r.Elapsed != 4000 {
+		t.Errorf("unexpected resource progress: %+v", r)
+	}
+	// 历史耗时按资源类型与操作缓存，重复计算进度不再查询
+	queries := history.queries
+	tracker.Progress()
+	tracker.Progress()
+	if history.queries != queries || queries > 2 {
+		t.Errorf("estimates should be cached, got %d queries", history.queries)
+	}
+
+	observe(t, tracker, `{"type":"apply_complete","hook":{"resource":{"addr":"aws_instance.web","resource_type":"aws_instance"},"action":"create","elapsed_seconds":10}}`)
+	if p := tracker.Progress(); p.Percent != 95 || p.ETA != 0 {
+		t.Errorf("unexpected final progress: %+v", p)
+	}
+
+	tracker.Record()
+	if history.recorded["aws_instance/create"] != 10*time.Second || history.recorded["aws_eip/create"] != 4*time.Second {
+		t.Errorf("unexpected recorded durations: %v", history.recorded)
+	}
+}
+
+func TestProgressTracker_ETAWithoutHistory(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tracker := NewProgressTracker(nil)
+	tracker.now = func() time.Time { return now }
+	tracker.SetPhase("destroy", "")
+
+	for _, addr := range []string{"null_resource.a", "null_resource.b"} {
+		observe(t, tracker, `{"type":"apply_start","hook":{"resource":{"addr":"`+addr+`","resource_type":"null_resource"},"action":"delete"}}`)
+	}
+	// 没有历史记录且没有已完成资源时无法估算
+	if p := tracker.Progress(); p.ETA != 0 || p.Planned != 2 {
+		t.Errorf("unexpected progress: %+v", p)
+	}
+
+	now = now.Add(2 * time.Second)
+	observe(t, tracker, `{"type":"apply_complete","hook":{"resource":{"addr":"null_resource.a","resource_type":"null_resource"},"action":"delete","elapsed_seconds":3}}`)
+	// 使用本次执行的平均耗时 3s，null_resource.b 已运行 2s
+	if p := tracker.Progress(); p.ETA != 1000 {
+		t.Errorf("eta should be 1s, got %dms", p.ETA)
+	}
+
+	tracker.Reset()
+	if p := tracker.Progress(); p.Planned != 0 || p.Phase != "" {
+		t.Errorf("reset should clear progress: %+v", p)
+	}
+}
+
+func TestProgressTracker_Outcomes(t *testing.T) {
+	tracker := NewProgressTracker(nil)
+	tracker.SetPhase("apply", "")
+
+	for _, line := range []string{
+		`{"type":"planned_change","change":{"resource":{"addr":"aws_eip.web","resource_type":"aws_eip"},"action":"create"}}`,
+		`{"type":"planned_change","change":{"resource":{"addr":"aws_instance.web","resource_type":"aws_instance"},"action":"replace"}}`,
+		`{"type":"planned_change","change":{"resource":{"addr":"aws_instance.db","resource_type":"aws_instance"},"action":"update"}}`,
+		`{"type":"planned_change","change":{"resource":{"addr":"aws_route53_record.web","resource_type":"aws_route53_record"},"action":"create"}}`,
+		`{"type":"apply_start","hook":{"resource":{"addr":"aws_eip.web"},"action":"create"}}`,
+		`{"type":"apply_complete","hook":{"resource":{"addr":"aws_eip.web"},"action":"create","id_key":"id","id_value":"eipalloc-1","elapsed_seconds":2}}`,
+		`{"type":"apply_start","hook":{"resource":{"addr":"aws_instance.web"},"action":"delete"}}`,
+		`{"type":"apply_complete","hook":{"resource":{"addr":"aws_instance.web"},"action":"delete","elapsed_seconds":5}}`,
+		`{"type":"apply_start","hook":{"resource":{"addr":"aws_instance.web"},"action":"create"}}`,
+		`{"type":"apply_errored","hook":{"resource":{"addr":"aws_instance.web"},"action":"create","elapsed_seconds":3}}`,
+		`{"type":"apply_start","hook":{"resource":{"addr":"aws_instance.db"},"action":"update"}}`,
+	} {
+		observe(t, tracker, line)
+	}
+
+	outcomes := tracker.Outcomes([]Diagnostic{{Severity: "error", Summary: "quota exceeded", Address: "aws_instance.web"}})
+	if len(outcomes) != 4 {
+		t.Fatalf("expected 4 outcomes, got %d", len(outcomes))
+	}
+
+	expected := []struct {
+		address string
+		outcome executor.Outcome
+	}{
+		{"aws_eip.web", executor.OutcomeCreated},
+		{"aws_instance.web", executor.OutcomeFailed},
+		{"aws_instance.db", executor.OutcomeInterrupted},
+		{"aws_route53_record.web", executor.OutcomeSkipped},
+	}
+	for i, want := range expected {
+		if outcomes[i].Address != want.address || outcomes[i].Outcome != want.outcome {
+			t.Errorf("outcome %d: expected %s %s, got %s %s", i, want.address, want.outcome, outcomes[i].Address, outcomes[i].Outcome)
+		}
+	}
+	if outcomes[0].ID != "eipalloc-1" || outcomes[0].Duration != 2000 {
+		t.Errorf("unexpected created outcome: %+v", outcomes[0])
+	}
+	// replace 的 delete 与 create 耗时累计
+	if outcomes[1].Error != "quota exceeded" || outcomes[1].Duration != 8000 || outcomes[1].Action != "replace" {
+		t.Errorf("unexpected failed outcome: %+v", outcomes[1])
+	}
+}
@@ -0,0 +1,186 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/executor/ws"
+	"github.com/cylonchau/prism/pkg/logger"
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// rollbackTaskID 返回回滚子任务的 ID
+func rollbackTaskID(taskID string) string {
+	return taskID + "-rollback"
+}
+
+// rollbackTargets 返回本次 apply 创建的资源地址，包括创建失败或被中断、可能已部分创建的资源。
+// 替换失败或被中断时旧资源已销毁、新资源可能已部分创建，同样销毁；
+// 替换成功的资源是原有资源的新版本，不销毁，作为 skipped 返回
+func rollbackTargets(outcomes []executor.ResourceOutcome) (targets, skipped []string) {
+	for _, o := range outcomes {
+		switch o.Action {
+		case "create":
+			switch o.Outcome {
+			case executor.OutcomeCreated, executor.OutcomeFailed, executor.OutcomeInterrupted:
+				targets = append(targets, o.Address)
+			}
+		case "replace":
+			switch o.Outcome {
+			case executor.OutcomeFailed, executor.OutcomeInterrupted:
+				targets = append(targets, o.Address)
+			case executor.OutcomeReplaced:
+				skipped = append(skipped, o.Address)
+			}
+		}
+	}
+	return targets, skipped
+}
+
+// changed 判断 apply 是否变更过资源
+func changed(outcomes []executor.ResourceOutcome) bool {
+	for _, o := range outcomes {
+		if o.Outcome != executor.OutcomeSkipped && o.Outcome != executor.OutcomeRead {
+			return true
+		}
+	}
+	return false
+}
+
+// rollback 在 apply 失败后以子任务回滚本次变更，子任务有独立的任务记录与日志。
+// 回滚基于失败后的 state 执行，terraform 据此得知本次创建的资源；
+// 执行前的 state 即 req.State，回滚成功后的 state 与之等价。
+// 未变更任何资源时不回滚，返回 nil
+func (t *task) rollback(ctx context.Context, workDir string, req *executor.ExecuteRequest, outcomes []executor.ResourceOutcome) *executor.ExecuteResult {
+	if !changed(outcomes) {
+		return nil
+	}
+
+	child := *req
+	child.TaskID = rollbackTaskID(req.TaskID)
+	result := &executor.ExecuteResult{TaskID: child.TaskID, Status: executor.StatusRunning}
+	start := time.Now()
+
+	// 取消 apply 不应跳过清理，回滚使用独立的取消函数
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	t.SetCancel(cancel)
+
+	if t.taskDAO != nil {
+		if _, err := t.taskDAO.CreateChild(child.TaskID, req.TaskID, req.ResourceID, "rollback"); err != nil {
+			t.log.Error("Failed to create rollback task",
+				logger.String("task_id", req.TaskID),
+				logger.Err(err))
+		}
+		t.taskDAO.Start(child.TaskID)
+	}
+	if t.hub != nil {
+		t.hub.Bind(child.TaskID, ws.TaskMeta{ResourceID: req.ResourceID, Provider: req.Provider, Tenant: req.Tenant})
+		defer t.hub.Unbind(child.TaskID)
+	}
+	t.sendRawLog(req.TaskID, ws.LevelWarn, fmt.Sprintf("Rolling back in task %s (%s)", child.TaskID, req.Rollback))
+
+	// 子任务有独立的诊断与进度，沿用 apply 的进程环境与脱敏
+	sub := t.child()
+
+	var err error
+	switch req.Rollback {
+	case executor.RollbackDestroy:
+		child.Action = executor.ActionDestroy
+		targets, skipped := rollbackTargets(outcomes)
+		if len(skipped) > 0 {
+			t.sendRawLog(req.TaskID, ws.LevelWarn, fmt.Sprintf("Replaced resources are not rolled back: %s", strings.Join(skipped, ", ")))
+		}
+		err = sub.destroyTargets(ctx, workDir, &child, targets)
+	case executor.RollbackReapply:
+		child.Action = executor.ActionApply
+		err = sub.reapply(ctx, workDir, &child)
+	default:
+		err = fmt.Errorf("unsupported rollback mode: %s", req.Rollback)
+	}
+
+	result.Duration = time.Since(start).Milliseconds()
+	result.Output = sub.getErrorSummary()
+	result.Resources = sub.progress.Outcomes(sub.GetErrors())
+	sub.saveOutcomes(child.TaskID, result.Resources)
+	result.Diagnostics = sub.GetDiagnostics()
+	sub.saveDiagnostics(child.TaskID, result.Diagnostics)
+	result.State = sub.readState(workDir)
+
+	if err != nil {
+		sub.failTask(ctx, &child, result, err)
+		sub.sendError(child.TaskID, result.Error)
+		sub.sendComplete(child.TaskID, false, result)
+		sub.log.Error("Terraform rollback failed",
+			logger.String("task_id", req.TaskID),
+			logger.String("rollback_task_id", child.TaskID),
+			logger.Err(err))
+		return result
+	}
+
+	result.Status = executor.StatusSuccess
+	sub.setPhase(child.TaskID, models.TaskPhaseCompleted, "Rolled back")
+	sub.completeTask(child.TaskID)
+	sub.sendComplete(child.TaskID, true, result)
+	return result
+}
+
+// destroyTargets 销毁指定地址的资源
+func (t *task) destroyTargets(ctx context.Context, workDir string, req *executor.ExecuteRequest, targets []string) error {
+	if len(targets) == 0 {
+		t.sendLog(req.TaskID, "No resources created by the failed apply, nothing to destroy")
+		return nil
+	}
+
+	t.setPhase(req.TaskID, "destroy", fmt.Sprintf("Destroying %d resources created by the failed apply...", len(targets)))
+
+	args := []string{
+		t.config.BinaryPath,
+		"-chdir=" + workDir,
+		"destroy",
+		"-auto-approve",
+		"-json",
+	}
+	for _, target := range targets {
+		args = append(args, "-target="+target)
+	}
+
+	result := t.run(ctx, workDir, req, args)
+
+	if result.Error != nil {
+		return fmt.Errorf("terraform destroy failed: %w", result.Error)
+	}
+	return nil
+}
+
+// reapply 恢复上一版本的配置并重新 apply
+func (t *task) reapply(ctx context.Context, workDir string, req *executor.ExecuteRequest) error {
+	if req.PreviousConfig == "" {
+		return fmt.Errorf("no previous config to re-apply")
+	}
+	if err := t.workspace.WriteFile(workDir, "main.tf", []byte(req.PreviousConfig)); err != nil {
+		return fmt.Errorf("failed to restore previous config: %w", err)
+	}
+	return t.apply(ctx, workDir, req)
+}
+
+// readState 读取工作目录中的 tfstate，不存在时返回空
+func (e *Executor) readState(workDir string) string {
+	data, err := e.workspace.ReadFile(workDir, stateFile)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// stateAttributes 返回 tfstate 中资源的 id、arn 与根模块输出，输出以名称为键，敏感输出不返回
+func (e *Executor) stateAttributes(state string) map[string]string {
+	attrs := e.parser.ParseTfstate([]byte(state))
+	for name, value := range e.parser.StateOutputs([]byte(state)) {
+		attrs[name] = value
+	}
+	return attrs
+}
@@ -0,0 +1,143 @@
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/executor"
+)
+
+// fakeTerraform 写入一个模拟 terraform 的脚本：apply 创建 aws_instance.a 后在 aws_instance.b 失败，
+// 配置含 drift 时 plan 报告偏离，其余命令成功。每次调用的参数追加到返回的日志文件
+func fakeTerraform(t *testing.T) (binary, calls string) {
+	dir := t.TempDir()
+	binary = filepath.Join(dir, "terraform")
+	calls = filepath.Join(dir, "calls")
+	script := `#!/bin/sh
+dir=${1#-chdir=}
+shift
+echo "$@" >> ` + calls + `
+case "$1" in
+apply)
+	echo '{"type":"apply_start","hook":{"resource":{"addr":"aws_instance.a","resource_type":"aws_instance"},"action":"create"}}'
+	echo '{"type":"apply_complete","hook":{"resource":{"addr":"aws_instance.a","resource_type":"aws_instance"},"action":"create","id_value":"i-1"}}'
+	echo '{"type":"apply_start","hook":{"resource":{"addr":"aws_instance.b","resource_type":"aws_instance"},"action":"create"}}'
+	echo '{"type":"apply_errored","hook":{"resource":{"addr":"aws_instance.b","resource_type":"aws_instance"},"action":"create"}}'
+	echo '{"version":4,"serial":2}' > "$dir/terraform.tfstate"
+	grep -qs previous "$dir/main.tf" && exit 0
+	exit 1
+	;;
+destroy)
+	echo '{"version":4,"serial":3}' > "$dir/terraform.tfstate"
+	;;
+plan)
+	grep -qs drift "$dir/main.tf" && echo '{"type":"resource_drift","change":{"resource":{"addr":"aws_instance.a"},"action":"update"}}'
+	;;
+validate)
+	printf '{\n  "valid": false,\n  "diagnostics": [{"severity": "error", "summary": "Unsupported argument"}]\n}\n'
+	grep -qs invalid "$dir/main.tf" && exit 1
+	;;
+output)
+	echo '{"ip":{"sensitive":false,"type":"string","value":"10.0.0.1"},"tags":{"sensitive":false,"value":{"env":"dev"}},"password":{"sensitive":true,"value":"s3cret"}}'
+	;;
+esac
+exit 0
+`
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return binary, calls
+}
+
+func TestExecutor_Rollback(t *testing.T) {
+	tests := []struct {
+		mode     executor.RollbackMode
+		previous string
+		call     string
+		success  bool
+	}{
+		{executor.RollbackDestroy, "", "destroy -auto-approve -json -target=aws_instance.a -target=aws_instance.b", true},
+		{executor.RollbackReapply, "# previous", "apply -auto-approve -json", true},
+		{executor.RollbackReapply, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+tt.previous, func(t *testing.T) {
+			binary, calls := fakeTerraform(t)
+			exec := New(&Config{BinaryPath: binary, BasePath: t.TempDir()}, nil, nil, nil)
+
+			result, err := exec.Execute(context.Background(), &executor.ExecuteRequest{
+				TaskID:         "task-1",
+				ResourceID:     1,
+				Action:         executor.ActionApply,
+				Config:         "# current",
+				State:          `{"version":4,"serial":1}`,
+				Rollback:       tt.mode,
+				PreviousConfig: tt.previous,
+			})
+			if err == nil || result.Status != executor.StatusFailed {
+				t.Fatalf("apply should fail, got %v", result.Status)
+			}
+			if result.Rollback == nil {
+				t.Fatal("rollback should run")
+			}
+			if result.Rollback.TaskID != "task-1-rollback" {
+				t.Errorf("unexpected rollback task: %s", result.Rollback.TaskID)
+			}
+			if success := result.Rollback.Status == executor.StatusSuccess; success != tt.success {
+				t.Errorf("rollback success = %v, want %v: %s", success, tt.success, result.Rollback.Error)
+			}
+
+			data, _ := os.ReadFile(calls)
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if tt.call != "" && lines[len(lines)-1] != tt.call {
+				t.Errorf("unexpected rollback command: %q", lines[len(lines)-1])
+			}
+			if tt.mode == executor.RollbackDestroy && result.State != `{"version":4,"serial":3}`+"\n" {
+				t.Errorf("result should carry state after rollback: %q", result.State)
+			}
+		})
+	}
+}
+
+func TestExecutor_Rollback_Disabled(t *testing.T) {
+	binary, calls := fakeTerraform(t)
+	exec := New(&Config{BinaryPath: binary, BasePath: t.TempDir()}, nil, nil, nil)
+
+	result, _ := exec.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-1", ResourceID: 1, Action: executor.ActionApply})
+	if result.Rollback != nil {
+		t.Error("rollback should be opt-in")
+	}
+	if result.State != `{"version":4,"serial":2}`+"\n" {
+		t.Errorf("result should carry state after apply: %q", result.State)
+	}
+	data, _ := os.ReadFile(calls)
+	if strings.Contains(string(data), "destroy") {
+		t.Error("destroy should not run")
+	}
+}
+
+func TestRollbackTargets(t *testing.T) {
+	outcomes := []executor.ResourceOutcome{
+		{Address: "a", Action: "create", Outcome: executor.OutcomeCreated},
+		{Address: "b", Action: "create", Outcome: executor.OutcomeInterrupted},
+		{Address: "c", Action: "create", Outcome: executor.OutcomeSkipped},
+		{Address: "d", Action: "update", Outcome: executor.OutcomeUpdated},
+		{Address: "e", Action: "replace", Outcome: executor.OutcomeReplaced},
+		{Address: "f", Action: "replace", Outcome: executor.OutcomeFailed},
+		{Address: "g", Action: "replace", Outcome: executor.OutcomeInterrupted},
+	}
+	// 替换失败或被中断的资源一并销毁，替换成功的资源保留并报告
+	targets, skipped := rollbackTargets(outcomes)
+	if strings.Join(targets, ",") != "a,b,f,g" {
+		t.Errorf("unexpected targets: %v", targets)
+	}
+	if strings.Join(skipped, ",") != "e" {
+		t.Errorf("unexpected skipped: %v", skipped)
+	}
+	if changed(outcomes[2:3]) {
+		t.Error("skipped resources are not changes")
+	}
+}
@@ -0,0 +1,54 @@
+package terraform
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/cylonchau/prism/pkg/executor"
+	"github.com/cylonchau/prism/pkg/executor/lock"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestExecutor_Timeline(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := dao.NewTaskEventDAO(db)
+
+	binary, _ := fakeTerraform(t)
+	exec := New(&Config{BinaryPath: binary, BasePath: t.TempDir()}, lock.NewMemoryLocker(nil), nil, nil)
+	exec.SetEventDAO(events)
+
+	exec.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-1", ResourceID: 1, Action: executor.ActionPlan})
+	exec.Execute(context.Background(), &executor.ExecuteRequest{TaskID: "task-2", ResourceID: 1, Action: executor.ActionApply})
+
+	tests := map[string]string{
+		"task-1": "queued lock_wait running init plan completed success",
+		"task-2": "queued lock_wait running init apply failed",
+	}
+	for taskID, expected := range tests {
+		timeline, err := events.ListByTask(taskID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(timeline))
+		for _, event := range timeline {
+			names = append(names, event.Name)
+		}
+		if got := strings.Join(names, " "); got != expected {
+			t.Errorf("%s: expected timeline %q, got %q", taskID, expected, got)
+		}
+	}
+
+	durations, err := events.Durations("task-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := durations["lock_wait"]; !ok {
+		t.Errorf("lock wait should be measured: %v", durations)
+	}
+}
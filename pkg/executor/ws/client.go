@@ -0,0 +1,106 @@
+package ws
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second    // 单次写超时
+	pongWait       = 60 * time.Second    // 等待 pong 的超时
+	pingPeriod     = (pongWait * 9) / 10 // 发送 ping 的间隔，必须小于 pongWait
+	maxMessageSize = 512                 // 客户端消息最大字节数
+	sendBufferSize = 256                 // 客户端发送缓冲区大小
+)
+
+// SetCheckOrigin 设置跨域校验函数，默认仅允许同源请求
+func (h *Hub) SetCheckOrigin(fn func(r *http.Request) bool) {
+	h.upgrader.CheckOrigin = fn
+}
+
+// ServeWS 升级 HTTP 请求为 WebSocket 并订阅任务或主题，参数见 parseSubscription。
+// since 参数为客户端已收到的最后 seq，服务端先回放其后的历史消息再推送实时消息。
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
+	client, since, ok := parseSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade 已写入错误响应
+	}
+
+	client.conn = conn
+	if err := h.Subscribe(client, since); err != nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "failed to load task log"),
+			time.Now().Add(writeWait))
+		conn.Close()
+		return
+	}
+
+	go client.writePump()
+	go client.readPump(h)
+}
+
+// readPump 读取客户端消息以处理 pong 与关闭帧，连接断开时注销客户端
+func (c *Client) readPump(h *Hub) {
+	defer func() {
+		h.Unregister(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		// 客户端只订阅不发送业务消息，读到的内容直接丢弃
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// writePump 将 send 中的消息写入连接并定时发送 ping
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	// 先回放历史消息
+	for _, data := range c.backlog {
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return
+		}
+	}
+	c.backlog = nil
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// Hub 已注销该客户端（断开或发送过慢）
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dialTask(t *testing.T, server *httptest.Server, taskID string) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?task_id=" + taskID
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial should succeed: %v", err)
+	}
+	return conn
+}
+
+// waitClients 等待 taskID 的订阅数达到 n
+func waitClients(t *testing.T, hub *Hub, taskID string, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		hub.mu.RLock()
+		count := len(hub.clients[taskID])
+		hub.mu.RUnlock()
+		if count == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d clients for %s", n, taskID)
+}
+
+func TestHub_ServeWS(t *testing.T) {
+	hub := NewHub()
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+	defer server.Close()
+
+	conn := dialTask(t, server, "task-1")
+	defer conn.Close()
+	waitClients(t, hub, "task-1", 1)
+
+	hub.SendLog("task-1", "hello")
+	hub.SendLog("task-2", "other task")
+
+	var msg Message
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read should succeed: %v", err)
+	}
+	if msg.Type != TypeLog || msg.TaskID != "task-1" || msg.Data != "hello" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestHub_ServeWS_MissingTaskID(t *testing.T) {
+	hub := NewHub()
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("dial without task_id should fail")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status should be 400, got %v", resp)
+	}
+}
+
+func TestHub_ServeWS_Disconnect(t *testing.T) {
+	hub := NewHub()
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+	defer server.Close()
+
+	conn := dialTask(t, server, "task-1")
+	waitClients(t, hub, "task-1", 1)
+
+	conn.Close()
+	waitClients(t, hub, "task-1", 0)
+
+	// 断开后广播不应 panic
+	hub.SendLog("task-1", "after close")
+}
+
+func TestHub_UnregisterTwice(t *testing.T) {
+	hub := NewHub()
+	client := &Client{taskID: "task-1", send: make(chan []byte, 1)}
+	hub.Register(client)
+
+	hub.Unregister(client)
+	hub.Unregister(client)
+}
+
+func TestHub_ServeWS_Replay(t *testing.T) {
+	hub := NewHub()
+	hub.SetLogStore(NewMemoryStore())
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+	defer server.Close()
+
+	hub.SendLog("task-1", "one")
+	hub.SendLog("task-1", "two")
+
+	conn := dialTask(t, server, "task-1&since=1")
+	defer conn.Close()
+	waitClients(t, hub, "task-1", 1)
+	hub.SendLog("task-1", "three")
+
+	for _, want := range []string{"two", "three"} {
+		var msg Message
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read should succeed: %v", err)
+		}
+		if msg.Data != want {
+			t.Errorf("expected %q, got %v", want, msg.Data)
+		}
+	}
+}
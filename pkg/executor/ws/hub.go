@@ -2,10 +2,13 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/cylonchau/prism/pkg/logger"
 	"github.com/gorilla/websocket"
 )
 
@@ -21,49 +24,176 @@ const (
 
 // Message WebSocket 消息
 type Message struct {
-	Type   MessageType `json:"type"`
-	TaskID string      `json:"task_id"`
-	Data   interface{} `json:"data"`
-	Time   time.Time   `json:"time"`
+	Seq        int64       `json:"seq"` // 任务内递增序号，用于断线续传与去重
+	Type       MessageType `json:"type"`
+	TaskID     string      `json:"task_id"`
+	Level      string      `json:"level,omitempty"` // 日志级别，仅 log 消息
+	ResourceID int64       `json:"resource_id,omitempty"`
+	Provider   string      `json:"provider,omitempty"`
+	Tenant     string      `json:"tenant,omitempty"`
+	Data       interface{} `json:"data"`
+	Time       time.Time   `json:"time"`
 }
 
 // ProgressData 进度数据
 type ProgressData struct {
-	Phase   string `json:"phase"`
-	Percent int    `json:"percent"`
-	Elapsed int64  `json:"elapsed"`
-	Message string `json:"message"`
+	Phase     string           `json:"phase"`
+	Percent   int              `json:"percent"`
+	Elapsed   int64            `json:"elapsed"` // 已用时间(ms)
+	Message   string           `json:"message"`
+	Planned   int              `json:"planned,omitempty"`   // 计划变更的资源数
+	Completed int              `json:"completed,omitempty"` // 已完成变更的资源数
+	ETA       int64            `json:"eta,omitempty"`       // 预计剩余时间(ms)
+	Resources []ResourceTiming `json:"resources,omitempty"`
+}
+
+// ResourceTiming 单个资源的执行状态与耗时
+type ResourceTiming struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Status  string `json:"status"`
+	Elapsed int64  `json:"elapsed"` // ms
 }
 
 // Client WebSocket 客户端
 type Client struct {
-	conn   *websocket.Conn
-	taskID string
-	send   chan []byte
+	conn    *websocket.Conn
+	taskID  string  // 订阅的任务，与 topic 二选一
+	topic   string  // 订阅的资源、云厂商或租户主题
+	filter  *Filter // 消息过滤条件，为空时接收全部
+	send    chan []byte
+	backlog [][]byte // 订阅时需回放的历史消息，先于 send 写出
+	lastSeq int64    // 已入队的最大 seq，用于回放与实时消息去重
 }
 
 // Hub 连接管理中心
 type Hub struct {
-	mu      sync.RWMutex
-	clients map[string]map[*Client]bool // taskID -> clients
+	mu       sync.RWMutex
+	clients  map[string]map[*Client]bool // taskID -> clients
+	topics   map[string]map[*Client]bool // topic -> clients
+	upgrader websocket.Upgrader
+
+	seqMu     sync.Mutex          // 保护 lanes、tasks、store 与 transport
+	lanes     map[string]*lane    // taskID -> 消息通道
+	tasks     map[string]TaskMeta // taskID -> 元数据
+	store     LogStore
+	transport Transport
+}
+
+// lane 单个任务的消息通道，序号分配、持久化与发布在通道锁内完成，
+// 保证同一任务的消息按 seq 顺序到达，且慢存储只阻塞该任务
+type lane struct {
+	mu     sync.Mutex
+	seq    int64 // 最后分配的 seq
+	loaded bool  // 是否已从存储恢复序号
 }
 
 // NewHub 创建 Hub
 func NewHub() *Hub {
 	return &Hub{
 		clients: make(map[string]map[*Client]bool),
+		topics:  make(map[string]map[*Client]bool),
+		lanes:   make(map[string]*lane),
+		tasks:   make(map[string]TaskMeta),
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
 	}
 }
 
+// SetLogStore 设置消息持久化存储，未设置时不支持回放
+func (h *Hub) SetLogStore(store LogStore) {
+	h.seqMu.Lock()
+	defer h.seqMu.Unlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.store = store
+}
+
+// SetTransport 设置跨实例消息通道。设置后消息经由 transport 发布，
+// 由各实例（包括本实例）收到后投递给本地订阅者。
+func (h *Hub) SetTransport(ctx context.Context, transport Transport) error {
+	if err := transport.Subscribe(ctx, h.deliver); err != nil {
+		return fmt.Errorf("failed to subscribe transport: %w", err)
+	}
+
+	h.seqMu.Lock()
+	defer h.seqMu.Unlock()
+	h.transport = transport
+	return nil
+}
+
+// Bind 关联任务元数据，之后该任务的消息同时发往对应的资源、云厂商与租户主题。
+// 任务完成后自动解除关联。
+func (h *Hub) Bind(taskID string, meta TaskMeta) {
+	h.seqMu.Lock()
+	defer h.seqMu.Unlock()
+
+	h.tasks[taskID] = meta
+}
+
+// Unbind 解除任务元数据关联
+func (h *Hub) Unbind(taskID string) {
+	h.seqMu.Lock()
+	defer h.seqMu.Unlock()
+
+	delete(h.tasks, taskID)
+}
+
 // Register 注册客户端
 func (h *Hub) Register(client *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if h.clients[client.taskID] == nil {
-		h.clients[client.taskID] = make(map[*Client]bool)
+	h.register(client)
+}
+
+// Subscribe 注册客户端并加载 seq 之后的历史消息。
+// 消息先持久化再投递，且投递时跳过 seq 不大于 lastSeq 的消息，
+// 因此回放与实时消息之间既无缺口也无重复。主题订阅只接收实时消息。
+func (h *Hub) Subscribe(client *Client, since int64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client.lastSeq = since
+	if h.store != nil && client.topic == "" {
+		messages, err := h.store.Since(client.taskID, since)
+		if err != nil {
+			return fmt.Errorf("failed to load task log: %w", err)
+		}
+		for _, msg := range messages {
+			client.lastSeq = msg.Seq
+			if !client.filter.Match(msg) {
+				continue
+			}
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			client.backlog = append(client.backlog, data)
+		}
+	}
+	h.register(client)
+	return nil
+}
+
+func (h *Hub) register(client *Client) {
+	index, key := h.index(client)
+	if index[key] == nil {
+		index[key] = make(map[*Client]bool)
 	}
-	h.clients[client.taskID][client] = true
+	index[key][client] = true
+}
+
+// index 返回客户端所在的订阅表及键
+func (h *Hub) index(client *Client) (map[string]map[*Client]bool, string) {
+	if client.topic != "" {
+		return h.topics, client.topic
+	}
+	return h.clients, client.taskID
 }
 
 // Unregister 注销客户端
@@ -71,44 +201,144 @@ func (h *Hub) Unregister(client *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if clients, ok := h.clients[client.taskID]; ok {
-		delete(clients, client)
-		if len(clients) == 0 {
-			delete(h.clients, client.taskID)
-		}
+	h.remove(client)
+}
+
+func (h *Hub) remove(client *Client) {
+	index, key := h.index(client)
+	clients, ok := index[key]
+	if !ok || !clients[client] {
+		return // 已注销，避免重复关闭 send
+	}
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(index, key)
 	}
 	close(client.send)
 }
 
-// Broadcast 广播消息
+// Broadcast 分配序号、持久化并广播消息。
+// 客户端缓冲区满时断开该客户端而不是丢弃消息，客户端可携带最后的 seq 重连回放。
 func (h *Hub) Broadcast(taskID string, msg *Message) {
-	h.mu.RLock()
-	clients := h.clients[taskID]
-	h.mu.RUnlock()
+	h.seqMu.Lock()
+	l, ok := h.lanes[taskID]
+	if !ok {
+		l = &lane{}
+		h.lanes[taskID] = l
+	}
+	meta, bound := h.tasks[taskID]
+	store, transport := h.store, h.transport
+	h.seqMu.Unlock()
 
-	if clients == nil {
-		return
+	// 全局锁只用于查找通道，持久化与发布只持有任务自己的通道锁
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	msg.TaskID = taskID
+	msg.Seq = l.next(taskID, store)
+	if bound {
+		msg.ResourceID, msg.Provider, msg.Tenant = meta.ResourceID, meta.Provider, meta.Tenant
+	}
+	if store != nil {
+		if err := store.Append(msg); err != nil {
+			logger.Warn("Failed to persist task log",
+				logger.String("task_id", taskID),
+				logger.Int64("seq", msg.Seq),
+				logger.Err(err))
+		}
+	}
+	if msg.Type == TypeComplete {
+		h.seqMu.Lock()
+		// 有存储时下次从存储恢复序号；没有存储时保留通道，
+		// 重试复用任务 ID 时序号继续递增，仍在线的客户端不会因 seq 回退丢弃新消息
+		if store != nil && h.lanes[taskID] == l {
+			delete(h.lanes, taskID)
+		}
+		delete(h.tasks, taskID)
+		h.seqMu.Unlock()
 	}
 
+	if transport != nil {
+		err := transport.Publish(context.Background(), msg)
+		if err == nil {
+			return
+		}
+		// 发布失败时至少保证本实例的订阅者收到
+		logger.Warn("Failed to publish hub message",
+			logger.String("task_id", taskID),
+			logger.Int64("seq", msg.Seq),
+			logger.Err(err))
+	}
+	h.deliver(msg)
+}
+
+// deliver 将消息投递给本实例的订阅者
+func (h *Hub) deliver(msg *Message) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return
 	}
 
-	for client := range clients {
-		select {
-		case client.send <- data:
-		default:
-			// 缓冲区满，跳过
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients[msg.TaskID] {
+		if msg.Seq <= client.lastSeq {
+			continue // 已通过回放发送
+		}
+		client.lastSeq = msg.Seq
+		h.push(client, msg, data)
+	}
+	if len(h.topics) == 0 {
+		return
+	}
+	for _, topic := range msg.topics() {
+		for client := range h.topics[topic] {
+			h.push(client, msg, data)
 		}
 	}
 }
 
-// SendLog 发送日志消息
+// push 按过滤条件将消息放入客户端发送队列，必须持有 h.mu
+func (h *Hub) push(client *Client, msg *Message, data []byte) {
+	if !client.filter.Match(msg) {
+		return
+	}
+	select {
+	case client.send <- data:
+	default:
+		// 缓冲区满，断开慢客户端
+		h.remove(client)
+	}
+}
+
+// next 返回任务的下一个序号，首次使用时从存储恢复，必须持有 l.mu
+func (l *lane) next(taskID string, store LogStore) int64 {
+	if !l.loaded && store != nil {
+		last, err := store.LastSeq(taskID)
+		if err != nil {
+			logger.Warn("Failed to load last task log seq",
+				logger.String("task_id", taskID),
+				logger.Err(err))
+		}
+		l.seq = last
+	}
+	l.loaded = true
+	l.seq++
+	return l.seq
+}
+
+// SendLog 发送 info 级别日志消息
 func (h *Hub) SendLog(taskID, message string) {
+	h.SendLogLevel(taskID, LevelInfo, message)
+}
+
+// SendLogLevel 发送指定级别的日志消息
+func (h *Hub) SendLogLevel(taskID, level, message string) {
 	h.Broadcast(taskID, &Message{
 		Type:   TypeLog,
 		TaskID: taskID,
+		Level:  level,
 		Data:   message,
 		Time:   time.Now(),
 	})
@@ -136,3 +366,13 @@ func (h *Hub) SendComplete(taskID string, success bool, result interface{}) {
 		Time: time.Now(),
 	})
 }
+
+// SendError 发送错误消息
+func (h *Hub) SendError(taskID, message string) {
+	h.Broadcast(taskID, &Message{
+		Type:   TypeError,
+		TaskID: taskID,
+		Data:   message,
+		Time:   time.Now(),
+	})
+}
@@ -3,6 +3,7 @@ package ws
 import (
 	"encoding/json"
 	"testing"
+	"time"
 )
 
 func TestHub_New(t *testing.T) {
@@ -204,3 +205,160 @@ func TestHub_MultipleClients(t *testing.T) {
 	hub.Unregister(client2)
 	hub.Unregister(client3)
 }
+
+func TestHub_Seq(t *testing.T) {
+	hub := NewHub()
+	client := &Client{taskID: "task-1", send: make(chan []byte, 10)}
+	hub.Register(client)
+
+	hub.SendLog("task-1", "a")
+	hub.SendLog("task-1", "b")
+	hub.SendLog("task-2", "c")
+
+	for want := int64(1); want <= 2; want++ {
+		var msg Message
+		json.Unmarshal(<-client.send, &msg)
+		if msg.Seq != want {
+			t.Errorf("seq should be %d, got %d", want, msg.Seq)
+		}
+	}
+}
+
+func TestHub_SeqContinuesAfterComplete(t *testing.T) {
+	hub := NewHub()
+	client := &Client{taskID: "task-1", send: make(chan []byte, 10)}
+	hub.Register(client)
+
+	// 没有存储时重试复用任务 ID，序号继续递增，在线客户端仍收到新消息
+	hub.SendLog("task-1", "a")
+	hub.SendComplete("task-1", false, nil)
+	hub.SendLog("task-1", "retry")
+
+	for want := int64(1); want <= 3; want++ {
+		select {
+		case data := <-client.send:
+			var msg Message
+			json.Unmarshal(data, &msg)
+			if msg.Seq != want {
+				t.Errorf("seq should be %d, got %d", want, msg.Seq)
+			}
+		default:
+			t.Fatalf("client should receive message %d", want)
+		}
+	}
+}
+
+func TestHub_SlowClientDisconnected(t *testing.T) {
+	hub := NewHub()
+	client := &Client{taskID: "task-1", send: make(chan []byte, 1)}
+	hub.Register(client)
+
+	hub.SendLog("task-1", "first")
+	hub.SendLog("task-1", "overflow")
+
+	hub.mu.RLock()
+	registered := hub.clients["task-1"][client]
+	hub.mu.RUnlock()
+	if registered {
+		t.Error("slow client should be disconnected")
+	}
+
+	<-client.send
+	if _, ok := <-client.send; ok {
+		t.Error("send should be closed after disconnect")
+	}
+}
+
+func TestHub_SubscribeReplay(t *testing.T) {
+	hub := NewHub()
+	store := NewMemoryStore()
+	hub.SetLogStore(store)
+
+	hub.SendLog("task-1", "one")
+	hub.SendLog("task-1", "two")
+	hub.SendLog("task-1", "three")
+
+	client := &Client{taskID: "task-1", send: make(chan []byte, 10)}
+	if err := hub.Subscribe(client, 1); err != nil {
+		t.Fatalf("subscribe should succeed: %v", err)
+	}
+	hub.SendLog("task-1", "four")
+
+	var seqs []int64
+	for _, data := range client.backlog {
+		var msg Message
+		json.Unmarshal(data, &msg)
+		seqs = append(seqs, msg.Seq)
+	}
+	var live Message
+	json.Unmarshal(<-client.send, &live)
+	seqs = append(seqs, live.Seq)
+
+	if len(seqs) != 3 || seqs[0] != 2 || seqs[1] != 3 || seqs[2] != 4 {
+		t.Errorf("replay and live should be contiguous, got %v", seqs)
+	}
+}
+
+func TestHub_SeqResumesFromStore(t *testing.T) {
+	store := NewMemoryStore()
+	store.Append(&Message{Seq: 7, Type: TypeLog, TaskID: "task-1"})
+
+	hub := NewHub()
+	hub.SetLogStore(store)
+	hub.SendLog("task-1", "after restart")
+
+	if last, _ := store.LastSeq("task-1"); last != 8 {
+		t.Errorf("seq should continue from store, got %d", last)
+	}
+}
+
+// blockingStore 阻塞指定任务的写入，模拟慢存储
+type blockingStore struct {
+	*MemoryStore
+	taskID  string
+	release chan struct{}
+}
+
+func (s *blockingStore) Append(msg *Message) error {
+	if msg.TaskID == s.taskID {
+		<-s.release
+	}
+	return s.MemoryStore.Append(msg)
+}
+
+func TestHub_SlowStoreDoesNotBlockOtherTasks(t *testing.T) {
+	hub := NewHub()
+	store := &blockingStore{MemoryStore: NewMemoryStore(), taskID: "task-1", release: make(chan struct{})}
+	hub.SetLogStore(store)
+
+	client := &Client{taskID: "task-2", send: make(chan []byte, 10)}
+	hub.Register(client)
+
+	go hub.SendLog("task-1", "stuck")
+	go hub.SendLog("task-1", "queued")
+	done := make(chan struct{})
+	go func() {
+		hub.SendLog("task-2", "free")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow store of task-1 should not block task-2")
+	}
+	close(store.release)
+
+	// 被阻塞任务的消息仍按 seq 顺序持久化
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if messages, _ := store.Since("task-1", 0); len(messages) == 2 {
+			if messages[0].Seq != 1 || messages[1].Seq != 2 {
+				t.Errorf("messages should be persisted in seq order: %d %d", messages[0].Seq, messages[1].Seq)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("task-1 messages should be persisted after release")
+}
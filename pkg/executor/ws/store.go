@@ -0,0 +1,130 @@
+package ws
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	models "github.com/cylonchau/prism/pkg/model"
+)
+
+// LogStore 消息持久化存储，用于迟到或重连的订阅者回放
+type LogStore interface {
+	// Append 追加消息，msg.Seq 已由 Hub 分配
+	Append(msg *Message) error
+	// Since 返回任务中 seq 大于 seq 的消息，按 seq 升序
+	Since(taskID string, seq int64) ([]*Message, error)
+	// LastSeq 返回任务最大的 seq，没有消息时为 0
+	LastSeq(taskID string) (int64, error)
+}
+
+// MemoryStore 内存存储，适用于测试和单实例部署
+type MemoryStore struct {
+	mu       sync.RWMutex
+	messages map[string][]*Message
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{messages: make(map[string][]*Message)}
+}
+
+// Append 追加消息
+func (s *MemoryStore) Append(msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages[msg.TaskID] = append(s.messages[msg.TaskID], msg)
+	return nil
+}
+
+// Since 返回 seq 之后的消息
+func (s *MemoryStore) Since(taskID string, seq int64) ([]*Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var messages []*Message
+	for _, msg := range s.messages[taskID] {
+		if msg.Seq > seq {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+// LastSeq 返回最大 seq
+func (s *MemoryStore) LastSeq(taskID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	messages := s.messages[taskID]
+	if len(messages) == 0 {
+		return 0, nil
+	}
+	return messages[len(messages)-1].Seq, nil
+}
+
+// Delete 删除任务的全部消息
+func (s *MemoryStore) Delete(taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.messages, taskID)
+}
+
+// DBStore 基于 task_log 表的存储
+type DBStore struct {
+	dao *dao.TaskLogDAO
+}
+
+// NewDBStore 创建数据库存储
+func NewDBStore(taskLogDAO *dao.TaskLogDAO) *DBStore {
+	return &DBStore{dao: taskLogDAO}
+}
+
+// Append 追加消息
+func (s *DBStore) Append(msg *Message) error {
+	data, err := json.Marshal(msg.Data)
+	if err != nil {
+		return err
+	}
+	return s.dao.Append(&models.TaskLog{
+		TaskID: msg.TaskID,
+		Seq:    msg.Seq,
+		Type:   string(msg.Type),
+		Level:  msg.Level,
+		Data:   string(data),
+		Time:   msg.Time,
+	})
+}
+
+// Since 返回 seq 之后的消息
+func (s *DBStore) Since(taskID string, seq int64) ([]*Message, error) {
+	logs, err := s.dao.ListSince(taskID, seq)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*Message, 0, len(logs))
+	for _, log := range logs {
+		msg := &Message{
+			Seq:    log.Seq,
+			Type:   MessageType(log.Type),
+			TaskID: log.TaskID,
+			Level:  log.Level,
+			Time:   log.Time,
+		}
+		if log.Data != "" {
+			if err := json.Unmarshal([]byte(log.Data), &msg.Data); err != nil {
+				return nil, err
+			}
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// LastSeq 返回最大 seq
+func (s *DBStore) LastSeq(taskID string) (int64, error) {
+	return s.dao.LastSeq(taskID)
+}
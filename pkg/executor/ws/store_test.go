@@ -0,0 +1,57 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func testStore(t *testing.T, store LogStore) {
+	for i := int64(1); i <= 3; i++ {
+		if err := store.Append(&Message{Seq: i, Type: TypeLog, TaskID: "task-1", Data: "line", Time: time.Now()}); err != nil {
+			t.Fatalf("append should succeed: %v", err)
+		}
+	}
+	store.Append(&Message{Seq: 1, Type: TypeLog, TaskID: "task-2", Data: "other"})
+
+	last, err := store.LastSeq("task-1")
+	if err != nil || last != 3 {
+		t.Errorf("last seq should be 3, got %d (%v)", last, err)
+	}
+
+	messages, err := store.Since("task-1", 1)
+	if err != nil {
+		t.Fatalf("since should succeed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Seq != 2 || messages[1].Seq != 3 {
+		t.Errorf("unexpected messages: %+v", messages)
+	}
+	if messages[0].Data != "line" {
+		t.Errorf("data should be restored, got %v", messages[0].Data)
+	}
+
+	if last, _ := store.LastSeq("missing"); last != 0 {
+		t.Errorf("last seq of missing task should be 0, got %d", last)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	testStore(t, store)
+
+	store.Delete("task-1")
+	if messages, _ := store.Since("task-1", 0); len(messages) != 0 {
+		t.Error("messages should be deleted")
+	}
+}
+
+func TestDBStore(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	testStore(t, NewDBStore(dao.NewTaskLogDAO(db)))
+}
@@ -0,0 +1,242 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	sseKeepAlive       = 15 * time.Second // SSE 注释行心跳间隔，防止代理断开空闲连接
+	defaultPollTimeout = 30 * time.Second // 长轮询默认等待时间
+	maxPollTimeout     = 60 * time.Second // 长轮询最大等待时间
+	maxPollMessages    = 1000             // 单次长轮询最多返回的消息数
+)
+
+// PollResponse 长轮询响应
+type PollResponse struct {
+	Messages []json.RawMessage `json:"messages"`
+	Next     int64             `json:"next"`     // 下次请求的 since
+	Complete bool              `json:"complete"` // 已收到任务的 complete 消息，无需继续轮询
+
+	task bool // 任务订阅，主题订阅不因 complete 结束
+}
+
+// ServeSSE 以 Server-Sent Events 推送订阅的消息，参数见 parseSubscription。
+// 事件 id 为消息 seq，任务订阅断线重连时通过 Last-Event-ID 请求头（或 since 参数）续传；
+// 任务订阅收到 complete 消息后结束响应。
+func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	client, since, ok := parseSubscription(w, r)
+	if !ok {
+		return
+	}
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		since = id
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.Subscribe(client, since); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer h.Unregister(client)
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, data := range client.backlog {
+		complete, err := writeEvent(w, data)
+		if err != nil || (complete && client.topic == "") {
+			flusher.Flush()
+			return
+		}
+	}
+	client.backlog = nil
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-client.send:
+			if !ok {
+				// 发送过慢被 Hub 断开，客户端可携带 Last-Event-ID 重连
+				return
+			}
+			complete, err := writeEvent(w, data)
+			flusher.Flush()
+			if err != nil || (complete && client.topic == "") {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// ServePoll 长轮询：返回 since 之后的消息，没有新消息时最多等待 timeout 秒。
+// 响应中的 next 作为下次请求的 since；主题订阅只返回等待期间到达的消息。
+func (h *Hub) ServePoll(w http.ResponseWriter, r *http.Request) {
+	client, since, ok := parseSubscription(w, r)
+	if !ok {
+		return
+	}
+	timeout := defaultPollTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+		if timeout > maxPollTimeout {
+			timeout = maxPollTimeout
+		}
+	}
+
+	if err := h.Subscribe(client, since); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer h.Unregister(client)
+
+	resp := &PollResponse{Messages: []json.RawMessage{}, Next: since, task: client.topic == ""}
+	for _, data := range client.backlog {
+		if !resp.add(data) {
+			break
+		}
+	}
+
+	if len(resp.Messages) == 0 && timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+		case data, ok := <-client.send:
+			if ok {
+				resp.add(data)
+			}
+		}
+	}
+
+	// 取走等待期间已到达的消息
+drain:
+	for !resp.Complete && len(resp.Messages) < maxPollMessages {
+		select {
+		case data, ok := <-client.send:
+			if !ok {
+				break drain
+			}
+			resp.add(data)
+		default:
+			break drain
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// add 追加消息，返回是否可以继续追加
+func (p *PollResponse) add(data []byte) bool {
+	var head messageHead
+	if err := json.Unmarshal(data, &head); err != nil {
+		return true
+	}
+	p.Messages = append(p.Messages, json.RawMessage(data))
+	p.Next = head.Seq
+	if p.task && head.Type == TypeComplete {
+		p.Complete = true
+	}
+	return !p.Complete && len(p.Messages) < maxPollMessages
+}
+
+// messageHead 消息的序号与类型，用于不完整解码
+type messageHead struct {
+	Seq  int64       `json:"seq"`
+	Type MessageType `json:"type"`
+}
+
+// writeEvent 写出一条 SSE 事件，返回该消息是否为 complete
+func writeEvent(w http.ResponseWriter, data []byte) (bool, error) {
+	var head messageHead
+	if err := json.Unmarshal(data, &head); err != nil {
+		return false, nil
+	}
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", head.Seq, head.Type, data)
+	return head.Type == TypeComplete, err
+}
+
+// parseSubscription 解析订阅参数并创建客户端，失败时写入 400 响应。
+// task_id 或 topic（resource:42、provider:aws、tenant:x）二选一；
+// since 为已收到的最后 seq；types 为逗号分隔的消息类型；level 为日志最低级别。
+func parseSubscription(w http.ResponseWriter, r *http.Request) (*Client, int64, bool) {
+	query := r.URL.Query()
+	client := newClient(query.Get("task_id"))
+	if v := query.Get("topic"); v != "" {
+		if client.taskID != "" {
+			http.Error(w, "task_id and topic are mutually exclusive", http.StatusBadRequest)
+			return nil, 0, false
+		}
+		topic, err := ParseTopic(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, 0, false
+		}
+		if topic.Kind == TopicTask {
+			client.taskID = topic.Value
+		} else {
+			client.topic = topic.String()
+		}
+	}
+	if client.taskID == "" && client.topic == "" {
+		http.Error(w, "task_id or topic is required", http.StatusBadRequest)
+		return nil, 0, false
+	}
+
+	filter, err := ParseFilter(query.Get("types"), query.Get("level"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, 0, false
+	}
+	client.filter = filter
+
+	var since int64
+	if v := query.Get("since"); v != "" {
+		if since, err = strconv.ParseInt(v, 10, 64); err != nil || since < 0 {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return nil, 0, false
+		}
+	}
+	return client, since, true
+}
+
+// newClient 创建订阅客户端，SSE 与长轮询客户端不持有 WebSocket 连接
+func newClient(taskID string) *Client {
+	return &Client{taskID: taskID, send: make(chan []byte, sendBufferSize)}
+}
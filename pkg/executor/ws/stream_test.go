@@ -0,0 +1,170 @@
+package ws
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvents 读取 SSE 事件直到响应结束
+func readEvents(t *testing.T, resp *http.Response) []map[string]string {
+	var events []map[string]string
+	event := map[string]string{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(event) > 0 {
+				events = append(events, event)
+			}
+			event = map[string]string{}
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ": "); ok && key != "" {
+			event[key] = value
+		}
+	}
+	return events
+}
+
+func TestHub_ServeSSE(t *testing.T) {
+	hub := NewHub()
+	hub.SetLogStore(NewMemoryStore())
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeSSE))
+	defer server.Close()
+
+	hub.SendLog("task-1", "one")
+	hub.SendLog("task-1", "two")
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"?task_id=task-1", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request should succeed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("content type should be text/event-stream, got %s", ct)
+	}
+
+	waitClients(t, hub, "task-1", 1)
+	hub.SendError("task-1", "boom")
+	hub.SendComplete("task-1", false, nil)
+
+	// 收到 complete 后服务端结束响应
+	events := readEvents(t, resp)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %v", events)
+	}
+	wantTypes := []string{"log", "error", "complete"}
+	for i, event := range events {
+		if event["event"] != wantTypes[i] {
+			t.Errorf("event %d type should be %s, got %s", i, wantTypes[i], event["event"])
+		}
+		var msg Message
+		json.Unmarshal([]byte(event["data"]), &msg)
+		if event["id"] != strconv.Itoa(2+i) || msg.Type != MessageType(wantTypes[i]) {
+			t.Errorf("event %d id/data mismatch: %v", i, event)
+		}
+	}
+}
+
+func TestHub_ServeSSE_InvalidLastEventID(t *testing.T) {
+	hub := NewHub()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/?task_id=task-1", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	hub.ServeSSE(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status should be 400, got %d", rec.Code)
+	}
+}
+
+func poll(t *testing.T, server *httptest.Server, query string) *PollResponse {
+	resp, err := http.Get(server.URL + "?" + query)
+	if err != nil {
+		t.Fatalf("poll should succeed: %v", err)
+	}
+	defer resp.Body.Close()
+	var result PollResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decode should succeed: %v", err)
+	}
+	return &result
+}
+
+func TestHub_ServePoll(t *testing.T) {
+	hub := NewHub()
+	hub.SetLogStore(NewMemoryStore())
+	server := httptest.NewServer(http.HandlerFunc(hub.ServePoll))
+	defer server.Close()
+
+	hub.SendLog("task-1", "one")
+	hub.SendLog("task-1", "two")
+
+	// 已有消息立即返回
+	result := poll(t, server, "task_id=task-1&since=0")
+	if len(result.Messages) != 2 || result.Next != 2 || result.Complete {
+		t.Fatalf("unexpected poll result: %+v", result)
+	}
+
+	// 没有新消息时超时返回空列表
+	result = poll(t, server, "task_id=task-1&since=2&timeout=0")
+	if len(result.Messages) != 0 || result.Next != 2 {
+		t.Errorf("empty poll should keep next, got %+v", result)
+	}
+
+	// 等待期间到达的消息
+	go func() {
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			hub.mu.RLock()
+			n := len(hub.clients["task-1"])
+			hub.mu.RUnlock()
+			if n > 0 {
+				break
+			}
+		}
+		hub.SendComplete("task-1", true, nil)
+	}()
+	result = poll(t, server, "task_id=task-1&since=2&timeout=5")
+	if len(result.Messages) != 1 || !result.Complete || result.Next != 3 {
+		t.Errorf("poll should return complete message, got %+v", result)
+	}
+
+	var msg Message
+	json.Unmarshal(result.Messages[0], &msg)
+	if msg.Type != TypeComplete {
+		t.Errorf("message type should be complete, got %s", msg.Type)
+	}
+}
+
+func TestHub_ServePoll_Timeout(t *testing.T) {
+	hub := NewHub()
+	server := httptest.NewServer(http.HandlerFunc(hub.ServePoll))
+	defer server.Close()
+
+	start := time.Now()
+	result := poll(t, server, "task_id=task-1&timeout=1")
+	if len(result.Messages) != 0 || time.Since(start) < time.Second {
+		t.Errorf("poll should wait for timeout, got %+v", result)
+	}
+}
+
+func TestHub_SendError(t *testing.T) {
+	hub := NewHub()
+	client := &Client{taskID: "task-1", send: make(chan []byte, 1)}
+	hub.Register(client)
+
+	hub.SendError("task-1", "failed")
+
+	var msg Message
+	json.Unmarshal(<-client.send, &msg)
+	if msg.Type != TypeError || msg.Data != "failed" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
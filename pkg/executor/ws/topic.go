@@ -0,0 +1,143 @@
+package ws
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TopicKind 订阅主题类型
+type TopicKind string
+
+const (
+	TopicTask     TopicKind = "task"
+	TopicResource TopicKind = "resource"
+	TopicProvider TopicKind = "provider"
+	TopicTenant   TopicKind = "tenant"
+)
+
+// Topic 订阅主题，字符串形式为 <kind>:<value>，如 resource:42、provider:aws
+type Topic struct {
+	Kind  TopicKind
+	Value string
+}
+
+// String 返回主题的字符串形式
+func (t Topic) String() string {
+	return string(t.Kind) + ":" + t.Value
+}
+
+// ParseTopic 解析主题字符串
+func ParseTopic(s string) (Topic, error) {
+	kind, value, ok := strings.Cut(s, ":")
+	if !ok || value == "" {
+		return Topic{}, fmt.Errorf("invalid topic %q, expected <kind>:<value>", s)
+	}
+	topic := Topic{Kind: TopicKind(kind), Value: value}
+	switch topic.Kind {
+	case TopicTask, TopicProvider, TopicTenant:
+	case TopicResource:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return Topic{}, fmt.Errorf("invalid resource id %q", value)
+		}
+	default:
+		return Topic{}, fmt.Errorf("unknown topic kind %q", kind)
+	}
+	return topic, nil
+}
+
+// TaskMeta 任务元数据，用于将任务消息路由到资源、云厂商与租户主题
+type TaskMeta struct {
+	ResourceID int64
+	Provider   string
+	Tenant     string
+}
+
+// topics 返回消息所属的非任务主题
+func (m *Message) topics() []string {
+	var topics []string
+	if m.ResourceID != 0 {
+		topics = append(topics, Topic{TopicResource, strconv.FormatInt(m.ResourceID, 10)}.String())
+	}
+	if m.Provider != "" {
+		topics = append(topics, Topic{TopicProvider, m.Provider}.String())
+	}
+	if m.Tenant != "" {
+		topics = append(topics, Topic{TopicTenant, m.Tenant}.String())
+	}
+	return topics
+}
+
+// 日志级别，兼容 Terraform JSON UI 的 @level
+const (
+	LevelTrace = "trace"
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+var levelRank = map[string]int{
+	LevelTrace: 0,
+	LevelDebug: 1,
+	LevelInfo:  2,
+	LevelWarn:  3,
+	LevelError: 4,
+}
+
+// Filter 客户端消息过滤条件
+type Filter struct {
+	Types    map[MessageType]bool // 接收的消息类型，为空表示全部
+	MinLevel string               // 日志消息的最低级别，为空表示全部
+}
+
+// ParseFilter 解析逗号分隔的消息类型与最低日志级别
+func ParseFilter(types, level string) (*Filter, error) {
+	if types == "" && level == "" {
+		return nil, nil
+	}
+	filter := &Filter{}
+	for _, t := range strings.Split(types, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		switch MessageType(t) {
+		case TypeLog, TypeProgress, TypeComplete, TypeError:
+		default:
+			return nil, fmt.Errorf("unknown message type %q", t)
+		}
+		if filter.Types == nil {
+			filter.Types = make(map[MessageType]bool)
+		}
+		filter.Types[MessageType(t)] = true
+	}
+	if level != "" {
+		if _, ok := levelRank[level]; !ok {
+			return nil, fmt.Errorf("unknown log level %q", level)
+		}
+		filter.MinLevel = level
+	}
+	return filter, nil
+}
+
+// Match 判断消息是否满足过滤条件，nil 过滤器匹配全部消息
+func (f *Filter) Match(msg *Message) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Types) > 0 && !f.Types[msg.Type] {
+		return false
+	}
+	if f.MinLevel != "" && msg.Type == TypeLog {
+		level := msg.Level
+		if level == "" {
+			level = LevelInfo
+		}
+		rank, ok := levelRank[level]
+		if ok && rank < levelRank[f.MinLevel] {
+			return false
+		}
+	}
+	return true
+}
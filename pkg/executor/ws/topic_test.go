@@ -0,0 +1,141 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTopic(t *testing.T) {
+	topic, err := ParseTopic("resource:42")
+	if err != nil || topic.Kind != TopicResource || topic.Value != "42" {
+		t.Errorf("unexpected topic %+v %v", topic, err)
+	}
+	if topic.String() != "resource:42" {
+		t.Errorf("string should round trip, got %s", topic.String())
+	}
+
+	for _, invalid := range []string{"", "resource", "resource:abc", "region:us", "provider:"} {
+		if _, err := ParseTopic(invalid); err == nil {
+			t.Errorf("%q should be invalid", invalid)
+		}
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	filter, err := ParseFilter("progress,complete", "")
+	if err != nil {
+		t.Fatalf("parse should succeed: %v", err)
+	}
+	if filter.Match(&Message{Type: TypeLog}) || !filter.Match(&Message{Type: TypeProgress}) {
+		t.Error("type filter mismatch")
+	}
+
+	filter, _ = ParseFilter("", LevelWarn)
+	if filter.Match(&Message{Type: TypeLog, Level: LevelInfo}) || filter.Match(&Message{Type: TypeLog}) {
+		t.Error("info logs should be filtered")
+	}
+	if !filter.Match(&Message{Type: TypeLog, Level: LevelError}) || !filter.Match(&Message{Type: TypeProgress}) {
+		t.Error("error logs and non-log messages should pass")
+	}
+
+	var none *Filter
+	if !none.Match(&Message{Type: TypeLog}) {
+		t.Error("nil filter should match all")
+	}
+	if _, err := ParseFilter("unknown", ""); err == nil {
+		t.Error("unknown type should fail")
+	}
+	if _, err := ParseFilter("", "verbose"); err == nil {
+		t.Error("unknown level should fail")
+	}
+}
+
+func TestHub_TopicSubscription(t *testing.T) {
+	hub := NewHub()
+	hub.Bind("task-1", TaskMeta{ResourceID: 42, Provider: "aws", Tenant: "team-a"})
+	hub.Bind("task-2", TaskMeta{ResourceID: 7, Provider: "aws"})
+
+	byResource := &Client{topic: "resource:42", send: make(chan []byte, 10)}
+	byProvider := &Client{topic: "provider:aws", send: make(chan []byte, 10)}
+	progressOnly := &Client{topic: "tenant:team-a", send: make(chan []byte, 10), filter: &Filter{Types: map[MessageType]bool{TypeProgress: true}}}
+	byTask := &Client{taskID: "task-1", send: make(chan []byte, 10)}
+	for _, c := range []*Client{byResource, byProvider, progressOnly, byTask} {
+		hub.Subscribe(c, 0)
+	}
+
+	hub.SendLog("task-1", "one")
+	hub.SendProgress("task-1", &ProgressData{Phase: "plan"})
+	hub.SendLog("task-2", "two")
+
+	counts := map[string]int{
+		"resource": len(byResource.send),
+		"provider": len(byProvider.send),
+		"progress": len(progressOnly.send),
+		"task":     len(byTask.send),
+	}
+	want := map[string]int{"resource": 2, "provider": 3, "progress": 1, "task": 2}
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("%s subscriber should receive %d messages, got %d", name, n, counts[name])
+		}
+	}
+
+	var msg Message
+	json.Unmarshal(<-byResource.send, &msg)
+	if msg.ResourceID != 42 || msg.Provider != "aws" || msg.Tenant != "team-a" {
+		t.Errorf("message should carry task metadata: %+v", msg)
+	}
+
+	// 任务完成后解除关联
+	hub.SendComplete("task-1", true, nil)
+	hub.SendLog("task-1", "after complete")
+	if n := len(byResource.send); n != 2 {
+		t.Errorf("resource subscriber should only receive complete after unbind, got %d", n)
+	}
+
+	hub.Unregister(byResource)
+	hub.mu.RLock()
+	_, ok := hub.topics["resource:42"]
+	hub.mu.RUnlock()
+	if ok {
+		t.Error("empty topic should be removed")
+	}
+}
+
+func TestHub_FilteredReplay(t *testing.T) {
+	hub := NewHub()
+	hub.SetLogStore(NewMemoryStore())
+	hub.SendLogLevel("task-1", LevelInfo, "info")
+	hub.SendLogLevel("task-1", LevelError, "error")
+
+	client := &Client{taskID: "task-1", send: make(chan []byte, 10), filter: &Filter{MinLevel: LevelWarn}}
+	hub.Subscribe(client, 0)
+	if len(client.backlog) != 1 || client.lastSeq != 2 {
+		t.Errorf("replay should be filtered, backlog=%d lastSeq=%d", len(client.backlog), client.lastSeq)
+	}
+}
+
+func TestParseSubscription(t *testing.T) {
+	cases := map[string]int{
+		"/?task_id=task-1":                   http.StatusOK,
+		"/?topic=resource:42&types=progress": http.StatusOK,
+		"/?topic=task:task-1":                http.StatusOK,
+		"/":                                  http.StatusBadRequest,
+		"/?task_id=a&topic=provider:aws":     http.StatusBadRequest,
+		"/?topic=bogus:1":                    http.StatusBadRequest,
+		"/?task_id=a&types=nope":             http.StatusBadRequest,
+		"/?task_id=a&level=loud":             http.StatusBadRequest,
+	}
+	for target, status := range cases {
+		rec := httptest.NewRecorder()
+		client, _, ok := parseSubscription(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if (status == http.StatusOK) != ok || (!ok && rec.Code != status) {
+			t.Errorf("%s: expected %d, got ok=%v code=%d", target, status, ok, rec.Code)
+		}
+		if target == "/?topic=task:task-1" && client.taskID != "task-1" {
+			t.Error("task topic should subscribe the task")
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// DefaultChannel 默认的跨实例消息通道名
+const DefaultChannel = "prism_hub"
+
+// Transport 跨实例消息通道。
+// Publish 发布的消息会回调所有实例（包括发布者自身）的 Subscribe 处理函数。
+type Transport interface {
+	// Publish 发布消息
+	Publish(ctx context.Context, msg *Message) error
+	// Subscribe 开始接收消息，返回前订阅必须已生效；ctx 取消后停止接收
+	Subscribe(ctx context.Context, handler func(msg *Message)) error
+	// Close 关闭通道
+	Close() error
+}
+
+// MemoryTransport 进程内消息通道，多个 Hub 共享同一实例即可模拟多实例部署
+type MemoryTransport struct {
+	mu       sync.RWMutex
+	handlers map[int]func(msg *Message)
+	next     int
+}
+
+// NewMemoryTransport 创建进程内消息通道
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{handlers: make(map[int]func(msg *Message))}
+}
+
+// Publish 同步回调所有订阅者。
+// 与网络通道一致，每个订阅者收到的都是独立解码的副本。
+func (t *MemoryTransport) Publish(ctx context.Context, msg *Message) error {
+	payload, err := encodeMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, handler := range t.handlers {
+		decoded, err := decodeMessage(payload)
+		if err != nil {
+			return err
+		}
+		handler(decoded)
+	}
+	return nil
+}
+
+// Subscribe 注册订阅者
+func (t *MemoryTransport) Subscribe(ctx context.Context, handler func(msg *Message)) error {
+	t.mu.Lock()
+	id := t.next
+	t.next++
+	t.handlers[id] = handler
+	t.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		t.mu.Lock()
+		delete(t.handlers, id)
+		t.mu.Unlock()
+	}()
+	return nil
+}
+
+// Close 移除所有订阅者
+func (t *MemoryTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.handlers = make(map[int]func(msg *Message))
+	return nil
+}
+
+func encodeMessage(msg *Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func decodeMessage(payload []byte) (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
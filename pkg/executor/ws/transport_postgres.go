@@ -0,0 +1,196 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/logger"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxNotifyPayload PostgreSQL NOTIFY 载荷上限为 8000 字节，预留余量
+	maxNotifyPayload = 7900
+
+	maxReconnectDelay = 30 * time.Second
+)
+
+// notification NOTIFY 载荷。消息过大时只携带 task_id 与 seq，接收方从共享存储读取完整消息
+type notification struct {
+	Message *Message `json:"message,omitempty"`
+	TaskID  string   `json:"task_id,omitempty"`
+	Seq     int64    `json:"seq,omitempty"`
+}
+
+// PostgresTransport 基于 PostgreSQL LISTEN/NOTIFY 的跨实例消息通道
+type PostgresTransport struct {
+	dsn     string
+	channel string
+	store   LogStore // 共享存储，用于传递超出载荷上限的消息
+
+	mu      sync.Mutex
+	pub     *pgx.Conn
+	cancels []context.CancelFunc
+}
+
+// NewPostgresTransport 创建 PostgreSQL 消息通道。
+// store 应为各实例共享的存储（如 DBStore），为空时超出载荷上限的消息发布失败。
+func NewPostgresTransport(dsn, channel string, store LogStore) *PostgresTransport {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	return &PostgresTransport{dsn: dsn, channel: channel, store: store}
+}
+
+// Publish 通过 pg_notify 发布消息
+func (t *PostgresTransport) Publish(ctx context.Context, msg *Message) error {
+	payload, err := encodeNotification(msg, t.store != nil)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pub == nil {
+		conn, err := pgx.Connect(ctx, t.dsn)
+		if err != nil {
+			return fmt.Errorf("failed to connect postgres: %w", err)
+		}
+		t.pub = conn
+	}
+	if _, err := t.pub.Exec(ctx, "SELECT pg_notify($1, $2)", t.channel, string(payload)); err != nil {
+		// 连接可能已失效，下次发布时重连
+		t.pub.Close(context.Background())
+		t.pub = nil
+		return fmt.Errorf("failed to notify: %w", err)
+	}
+	return nil
+}
+
+// Subscribe 建立 LISTEN 连接并在后台接收通知，连接断开时自动重连
+func (t *PostgresTransport) Subscribe(ctx context.Context, handler func(msg *Message)) error {
+	conn, err := t.listen(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	t.mu.Lock()
+	t.cancels = append(t.cancels, cancel)
+	t.mu.Unlock()
+
+	go t.receive(ctx, conn, handler)
+	return nil
+}
+
+// Close 停止接收并关闭连接
+func (t *PostgresTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, cancel := range t.cancels {
+		cancel()
+	}
+	t.cancels = nil
+	if t.pub != nil {
+		err := t.pub.Close(context.Background())
+		t.pub = nil
+		return err
+	}
+	return nil
+}
+
+func (t *PostgresTransport) listen(ctx context.Context) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, t.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect postgres: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{t.channel}.Sanitize()); err != nil {
+		conn.Close(context.Background())
+		return nil, fmt.Errorf("failed to listen on %s: %w", t.channel, err)
+	}
+	return conn, nil
+}
+
+func (t *PostgresTransport) receive(ctx context.Context, conn *pgx.Conn, handler func(msg *Message)) {
+	delay := time.Second
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err == nil {
+			delay = time.Second
+			msg, err := t.resolve([]byte(n.Payload))
+			if err != nil {
+				logger.Warn("Failed to decode hub notification", logger.Err(err))
+				continue
+			}
+			handler(msg)
+			continue
+		}
+
+		conn.Close(context.Background())
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warn("Postgres hub listener disconnected, reconnecting",
+			logger.String("channel", t.channel),
+			logger.Err(err))
+
+		// 重连期间的实时消息会丢失，客户端可通过 seq 回放补齐
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if conn, err = t.listen(ctx); err == nil {
+				break
+			}
+			if delay *= 2; delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+		}
+	}
+}
+
+// resolve 解码通知，必要时从存储读取完整消息
+func (t *PostgresTransport) resolve(payload []byte) (*Message, error) {
+	var n notification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return nil, err
+	}
+	if n.Message != nil {
+		return n.Message, nil
+	}
+	if t.store == nil {
+		return nil, fmt.Errorf("message %s#%d not inlined and no store configured", n.TaskID, n.Seq)
+	}
+	messages, err := t.store.Since(n.TaskID, n.Seq-1)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range messages {
+		if msg.Seq == n.Seq {
+			return msg, nil
+		}
+	}
+	return nil, fmt.Errorf("message %s#%d not found in store", n.TaskID, n.Seq)
+}
+
+// encodeNotification 编码通知，超出载荷上限且允许引用时只携带 task_id 与 seq
+func encodeNotification(msg *Message, allowRef bool) ([]byte, error) {
+	payload, err := json.Marshal(&notification{Message: msg})
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) <= maxNotifyPayload {
+		return payload, nil
+	}
+	if !allowRef {
+		return nil, fmt.Errorf("message size %d exceeds notify payload limit", len(payload))
+	}
+	return json.Marshal(&notification{TaskID: msg.TaskID, Seq: msg.Seq})
+}
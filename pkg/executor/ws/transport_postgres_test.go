@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEncodeNotification(t *testing.T) {
+	small := &Message{Seq: 1, Type: TypeLog, TaskID: "task-1", Data: "line"}
+	payload, err := encodeNotification(small, false)
+	if err != nil {
+		t.Fatalf("encode should succeed: %v", err)
+	}
+	var n notification
+	json.Unmarshal(payload, &n)
+	if n.Message == nil || n.Message.Data != "line" {
+		t.Errorf("small message should be inlined: %s", payload)
+	}
+
+	large := &Message{Seq: 2, Type: TypeLog, TaskID: "task-1", Data: strings.Repeat("x", maxNotifyPayload)}
+	if _, err := encodeNotification(large, false); err == nil {
+		t.Error("oversized message without store should fail")
+	}
+	payload, err = encodeNotification(large, true)
+	if err != nil {
+		t.Fatalf("encode should succeed: %v", err)
+	}
+	n = notification{}
+	json.Unmarshal(payload, &n)
+	if n.Message != nil || n.TaskID != "task-1" || n.Seq != 2 {
+		t.Errorf("oversized message should be a reference: %s", payload)
+	}
+}
+
+func TestPostgresTransport_Resolve(t *testing.T) {
+	store := NewMemoryStore()
+	store.Append(&Message{Seq: 1, Type: TypeLog, TaskID: "task-1", Data: "first"})
+	store.Append(&Message{Seq: 2, Type: TypeLog, TaskID: "task-1", Data: "second"})
+	transport := NewPostgresTransport("", "", store)
+
+	msg, err := transport.resolve([]byte(`{"task_id":"task-1","seq":2}`))
+	if err != nil || msg.Data != "second" {
+		t.Errorf("reference should resolve from store, got %+v %v", msg, err)
+	}
+	if _, err := transport.resolve([]byte(`{"task_id":"task-1","seq":3}`)); err == nil {
+		t.Error("missing message should return error")
+	}
+}
+
+// 设置 PRISM_TEST_POSTGRES_DSN 后运行真实 LISTEN/NOTIFY 测试
+func TestPostgresTransport_FanOut(t *testing.T) {
+	dsn := os.Getenv("PRISM_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("PRISM_TEST_POSTGRES_DSN not set")
+	}
+	nodeA, nodeB := NewPostgresTransport(dsn, "prism_hub_test", nil), NewPostgresTransport(dsn, "prism_hub_test", nil)
+	defer nodeA.Close()
+	defer nodeB.Close()
+	testFanOut(t, nodeA, nodeB)
+}
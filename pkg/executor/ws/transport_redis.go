@@ -0,0 +1,295 @@
+package ws
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cylonchau/prism/pkg/logger"
+)
+
+// RedisConfig Redis 消息通道配置
+type RedisConfig struct {
+	Addr        string        // host:port
+	Password    string        // 为空时不认证
+	Channel     string        // 默认 DefaultChannel
+	DialTimeout time.Duration // 默认 5s
+}
+
+// RedisTransport 基于 Redis pub/sub 的跨实例消息通道，内置最小 RESP 客户端
+type RedisTransport struct {
+	config RedisConfig
+
+	mu      sync.Mutex
+	pub     *redisConn
+	cancels []context.CancelFunc
+}
+
+// NewRedisTransport 创建 Redis 消息通道
+func NewRedisTransport(config RedisConfig) *RedisTransport {
+	if config.Channel == "" {
+		config.Channel = DefaultChannel
+	}
+	if config.DialTimeout == 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	return &RedisTransport{config: config}
+}
+
+// Publish 通过 PUBLISH 发布消息
+func (t *RedisTransport) Publish(ctx context.Context, msg *Message) error {
+	payload, err := encodeMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pub == nil {
+		conn, err := t.dial()
+		if err != nil {
+			return err
+		}
+		t.pub = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		t.pub.conn.SetDeadline(deadline)
+	} else {
+		t.pub.conn.SetDeadline(time.Time{})
+	}
+	if _, err := t.pub.do("PUBLISH", t.config.Channel, string(payload)); err != nil {
+		t.pub.close()
+		t.pub = nil
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+	return nil
+}
+
+// Subscribe 建立 SUBSCRIBE 连接并在后台接收消息，连接断开时自动重连
+func (t *RedisTransport) Subscribe(ctx context.Context, handler func(msg *Message)) error {
+	conn, err := t.subscribe()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	t.mu.Lock()
+	t.cancels = append(t.cancels, cancel)
+	t.mu.Unlock()
+
+	go t.receive(ctx, conn, handler)
+	return nil
+}
+
+// Close 停止接收并关闭连接
+func (t *RedisTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, cancel := range t.cancels {
+		cancel()
+	}
+	t.cancels = nil
+	if t.pub != nil {
+		err := t.pub.close()
+		t.pub = nil
+		return err
+	}
+	return nil
+}
+
+func (t *RedisTransport) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", t.config.Addr, t.config.DialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect redis: %w", err)
+	}
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if t.config.Password != "" {
+		conn.SetDeadline(time.Now().Add(t.config.DialTimeout))
+		if _, err := rc.do("AUTH", t.config.Password); err != nil {
+			rc.close()
+			return nil, fmt.Errorf("redis auth failed: %w", err)
+		}
+		conn.SetDeadline(time.Time{})
+	}
+	return rc, nil
+}
+
+func (t *RedisTransport) subscribe() (*redisConn, error) {
+	conn, err := t.dial()
+	if err != nil {
+		return nil, err
+	}
+	conn.conn.SetDeadline(time.Now().Add(t.config.DialTimeout))
+	if _, err := conn.do("SUBSCRIBE", t.config.Channel); err != nil {
+		conn.close()
+		return nil, fmt.Errorf("failed to subscribe %s: %w", t.config.Channel, err)
+	}
+	conn.conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func (t *RedisTransport) receive(ctx context.Context, conn *redisConn, handler func(msg *Message)) {
+	// ctx 取消时关闭当前连接以中断阻塞读取
+	var mu sync.Mutex
+	current := conn
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		current.close()
+		mu.Unlock()
+	}()
+
+	delay := time.Second
+	for {
+		reply, err := conn.read()
+		if err == nil {
+			delay = time.Second
+			if payload, ok := pubsubMessage(reply); ok {
+				msg, err := decodeMessage(payload)
+				if err != nil {
+					logger.Warn("Failed to decode hub message", logger.Err(err))
+					continue
+				}
+				handler(msg)
+			}
+			continue
+		}
+
+		conn.close()
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warn("Redis hub subscriber disconnected, reconnecting",
+			logger.String("channel", t.config.Channel),
+			logger.Err(err))
+
+		// 重连期间的实时消息会丢失，客户端可通过 seq 回放补齐
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if conn, err = t.subscribe(); err == nil {
+				break
+			}
+			if delay *= 2; delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+		}
+		mu.Lock()
+		current = conn
+		mu.Unlock()
+		if ctx.Err() != nil {
+			conn.close()
+			return
+		}
+	}
+}
+
+// pubsubMessage 从 ["message", channel, payload] 回复中提取 payload
+func pubsubMessage(reply interface{}) ([]byte, bool) {
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 3 {
+		return nil, false
+	}
+	kind, _ := items[0].([]byte)
+	payload, ok := items[2].([]byte)
+	if string(kind) != "message" || !ok {
+		return nil, false
+	}
+	return payload, true
+}
+
+// redisConn 最小 RESP 连接
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// do 发送命令并读取一个回复
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	if err := c.write(args...); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+func (c *redisConn) write(args ...string) error {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// read 读取一个 RESP 回复：简单字符串为 string，整数为 int64，
+// 批量字符串为 []byte（空值为 nil），数组为 []interface{}，错误回复返回 error
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed redis reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown redis reply type %q", kind)
+	}
+}
+
+func (c *redisConn) close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,129 @@
+package ws
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRedis 支持 AUTH/SUBSCRIBE/PUBLISH 的最小 Redis 服务
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu          sync.Mutex
+	subscribers map[string][]*redisConn
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeRedis{listener: listener, password: password, subscribers: make(map[string][]*redisConn)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(&redisConn{conn: conn, reader: bufio.NewReader(conn)})
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeRedis) serve(c *redisConn) {
+	defer c.close()
+	authed := s.password == ""
+	for {
+		reply, err := c.read()
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] != s.password {
+				c.conn.Write([]byte("-WRONGPASS invalid password\r\n"))
+				continue
+			}
+			authed = true
+			c.conn.Write([]byte("+OK\r\n"))
+		case "SUBSCRIBE":
+			if !authed {
+				c.conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+				continue
+			}
+			s.mu.Lock()
+			s.subscribers[args[1]] = append(s.subscribers[args[1]], c)
+			s.mu.Unlock()
+			c.write("subscribe", args[1]) // 回复格式不影响客户端解析
+		case "PUBLISH":
+			if !authed {
+				c.conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+				continue
+			}
+			s.mu.Lock()
+			subs := s.subscribers[args[1]]
+			for _, sub := range subs {
+				sub.write("message", args[1], args[2])
+			}
+			s.mu.Unlock()
+			c.conn.Write([]byte(":" + strconv.Itoa(len(subs)) + "\r\n"))
+		}
+	}
+}
+
+func TestRedisTransport_FanOut(t *testing.T) {
+	server := newFakeRedis(t, "pass")
+	config := RedisConfig{Addr: server.listener.Addr().String(), Password: "pass"}
+
+	nodeA, nodeB := NewRedisTransport(config), NewRedisTransport(config)
+	defer nodeA.Close()
+	defer nodeB.Close()
+	testFanOut(t, nodeA, nodeB)
+}
+
+func TestRedisTransport_AuthFailed(t *testing.T) {
+	server := newFakeRedis(t, "pass")
+	transport := NewRedisTransport(RedisConfig{Addr: server.listener.Addr().String(), Password: "wrong"})
+	if err := transport.Subscribe(t.Context(), func(*Message) {}); err == nil {
+		t.Error("subscribe with wrong password should fail")
+	}
+}
+
+func TestRedisConn_Read(t *testing.T) {
+	c := &redisConn{reader: bufio.NewReader(strings.NewReader(
+		"*3\r\n$7\r\nmessage\r\n$4\r\nchan\r\n$5\r\nhello\r\n:42\r\n$-1\r\n-ERR boom\r\n"))}
+
+	reply, err := c.read()
+	if err != nil {
+		t.Fatalf("read should succeed: %v", err)
+	}
+	if payload, ok := pubsubMessage(reply); !ok || string(payload) != "hello" {
+		t.Errorf("unexpected pubsub message: %v", reply)
+	}
+	if n, _ := c.read(); n != int64(42) {
+		t.Errorf("integer reply should be 42, got %v", n)
+	}
+	if v, err := c.read(); v != nil || err != nil {
+		t.Errorf("nil bulk should be nil, got %v %v", v, err)
+	}
+	if _, err := c.read(); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("error reply should return error, got %v", err)
+	}
+}
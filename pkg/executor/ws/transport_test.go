@@ -0,0 +1,96 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// testFanOut 验证实例 A 发送的消息能到达实例 B 的订阅者
+func testFanOut(t *testing.T, nodeA, nodeB Transport) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hubA, hubB := NewHub(), NewHub()
+	if err := hubA.SetTransport(ctx, nodeA); err != nil {
+		t.Fatalf("set transport should succeed: %v", err)
+	}
+	if err := hubB.SetTransport(ctx, nodeB); err != nil {
+		t.Fatalf("set transport should succeed: %v", err)
+	}
+
+	local := &Client{taskID: "task-1", send: make(chan []byte, 10)}
+	remote := &Client{taskID: "task-1", send: make(chan []byte, 10)}
+	hubA.Register(local)
+	hubB.Register(remote)
+
+	hubA.SendLog("task-1", "from a")
+	hubA.SendComplete("task-1", true, nil)
+
+	for name, client := range map[string]*Client{"local": local, "remote": remote} {
+		for _, want := range []MessageType{TypeLog, TypeComplete} {
+			select {
+			case data := <-client.send:
+				var msg Message
+				json.Unmarshal(data, &msg)
+				if msg.Type != want || msg.TaskID != "task-1" {
+					t.Errorf("%s client: unexpected message %+v", name, msg)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("%s client should receive %s", name, want)
+			}
+		}
+	}
+}
+
+func TestMemoryTransport_FanOut(t *testing.T) {
+	transport := NewMemoryTransport()
+	testFanOut(t, transport, transport)
+}
+
+func TestMemoryTransport_Unsubscribe(t *testing.T) {
+	transport := NewMemoryTransport()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	received := make(chan *Message, 1)
+	transport.Subscribe(ctx, func(msg *Message) { received <- msg })
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		transport.mu.RLock()
+		n := len(transport.handlers)
+		transport.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	transport.Publish(context.Background(), &Message{Type: TypeLog, TaskID: "task-1"})
+	select {
+	case <-received:
+		t.Error("cancelled subscriber should not receive messages")
+	default:
+	}
+}
+
+func TestHub_RemoteDedupe(t *testing.T) {
+	// 远端实例订阅时已从共享存储回放的消息，经通道到达时不应重复投递
+	store := NewMemoryStore()
+	transport := NewMemoryTransport()
+	hub := NewHub()
+	hub.SetLogStore(store)
+	hub.SetTransport(context.Background(), transport)
+
+	msg := &Message{Seq: 1, Type: TypeLog, TaskID: "task-1", Data: "persisted"}
+	store.Append(msg)
+
+	client := &Client{taskID: "task-1", send: make(chan []byte, 10)}
+	hub.Subscribe(client, 0)
+	transport.Publish(context.Background(), msg)
+
+	if len(client.backlog) != 1 || len(client.send) != 0 {
+		t.Errorf("message should be delivered once, backlog=%d live=%d", len(client.backlog), len(client.send))
+	}
+}
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RedactFunc 脱敏函数
+type RedactFunc func(string) string
+
+// redactingLogger 在写入前对消息与字段值脱敏
+type redactingLogger struct {
+	logger Logger
+	redact RedactFunc
+}
+
+// WithRedaction 返回对消息、字符串字段与错误字段脱敏的 Logger
+func WithRedaction(l Logger, redact RedactFunc) Logger {
+	return &redactingLogger{logger: l, redact: redact}
+}
+
+func (r *redactingLogger) Debug(msg string, fields ...Field) {
+	r.logger.Debug(r.redact(msg), r.fields(fields)...)
+}
+
+func (r *redactingLogger) Info(msg string, fields ...Field) {
+	r.logger.Info(r.redact(msg), r.fields(fields)...)
+}
+
+func (r *redactingLogger) Warn(msg string, fields ...Field) {
+	r.logger.Warn(r.redact(msg), r.fields(fields)...)
+}
+
+func (r *redactingLogger) Error(msg string, fields ...Field) {
+	r.logger.Error(r.redact(msg), r.fields(fields)...)
+}
+
+func (r *redactingLogger) Fatal(msg string, fields ...Field) {
+	r.logger.Fatal(r.redact(msg), r.fields(fields)...)
+}
+
+func (r *redactingLogger) With(fields ...Field) Logger {
+	return &redactingLogger{logger: r.logger.With(r.fields(fields)...), redact: r.redact}
+}
+
+func (r *redactingLogger) Named(name string) Logger {
+	return &redactingLogger{logger: r.logger.Named(name), redact: r.redact}
+}
+
+func (r *redactingLogger) Sync() error {
+	return r.logger.Sync()
+}
+
+// fields 脱敏字段值，非文本类型的值原样保留
+func (r *redactingLogger) fields(fields []Field) []Field {
+	if len(fields) == 0 {
+		return fields
+	}
+	result := make([]Field, len(fields))
+	for i, f := range fields {
+		switch v := f.Value.(type) {
+		case string:
+			f.Value = r.redact(v)
+		case error:
+			if v != nil {
+				f.Value = errors.New(r.redact(v.Error()))
+			}
+		case fmt.Stringer:
+			f.Value = r.redact(v.String())
+		case []string:
+			redacted := make([]string, len(v))
+			for j, s := range v {
+				redacted[j] = r.redact(s)
+			}
+			f.Value = redacted
+		}
+		result[i] = f
+	}
+	return result
+}
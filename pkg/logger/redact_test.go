@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// captureLogger 记录最后一条日志
+type captureLogger struct {
+	msg    string
+	fields []Field
+}
+
+func (c *captureLogger) Debug(msg string, fields ...Field) { c.msg, c.fields = msg, fields }
+func (c *captureLogger) Info(msg string, fields ...Field)  { c.msg, c.fields = msg, fields }
+func (c *captureLogger) Warn(msg string, fields ...Field)  { c.msg, c.fields = msg, fields }
+func (c *captureLogger) Error(msg string, fields ...Field) { c.msg, c.fields = msg, fields }
+func (c *captureLogger) Fatal(msg string, fields ...Field) { c.msg, c.fields = msg, fields }
+func (c *captureLogger) With(fields ...Field) Logger       { c.fields = fields; return c }
+func (c *captureLogger) Named(name string) Logger          { return c }
+func (c *captureLogger) Sync() error                       { return nil }
+
+func TestWithRedaction(t *testing.T) {
+	base := &captureLogger{}
+	l := WithRedaction(base, func(s string) string {
+		return strings.ReplaceAll(s, "hunter2", "******")
+	})
+
+	l.Error("login with hunter2 failed",
+		String("password", "hunter2"),
+		Err(errors.New("bad password hunter2")),
+		Int("attempt", 3))
+
+	if strings.Contains(base.msg, "hunter2") {
+		t.Errorf("message should be redacted: %s", base.msg)
+	}
+	if base.fields[0].Value != "******" {
+		t.Errorf("string field should be redacted: %v", base.fields[0].Value)
+	}
+	if err, ok := base.fields[1].Value.(error); !ok || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("error field should be redacted: %v", base.fields[1].Value)
+	}
+	if base.fields[2].Value != 3 {
+		t.Errorf("int field should be unchanged: %v", base.fields[2].Value)
+	}
+
+	l.With(String("token", "hunter2"))
+	if base.fields[0].Value != "******" {
+		t.Errorf("With fields should be redacted: %v", base.fields[0].Value)
+	}
+}
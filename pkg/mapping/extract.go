@@ -0,0 +1,149 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/cylonchau/prism/pkg/transform"
+	"github.com/tidwall/gjson"
+)
+
+// Extract 按规则从 tfstate 中提取属性。托管资源的实例按在 state 中的顺序编号为 ResourceIndex，
+// AttributeName 为源属性路径，MappedName 为统一属性名；null 值与敏感属性不提取。
+// 转换失败的属性被跳过，错误合并后与其余属性一并返回
+func Extract(state []byte, rules []models.AttributeMappingRule) ([]models.TerraformResourceAttribute, error) {
+	byType := make(map[string][]models.AttributeMappingRule)
+	for _, rule := range rules {
+		key := rule.Provider + "/" + rule.ResourceType
+		byType[key] = append(byType[key], rule)
+	}
+
+	var attrs []models.TerraformResourceAttribute
+	var errs []error
+	index := 0
+	gjson.GetBytes(state, "resources").ForEach(func(_, resource gjson.Result) bool {
+		if resource.Get("mode").String() != "managed" {
+			return true
+		}
+		resourceType := resource.Get("type").String()
+		address := resourceType + "." + resource.Get("name").String()
+		matched := byType[providerName(resource.Get("provider").String())+"/"+resourceType]
+
+		resource.Get("instances").ForEach(func(_, instance gjson.Result) bool {
+			attributes := instance.Get("attributes")
+			sensitive := sensitivePaths(instance)
+			for _, rule := range matched {
+				value := attributes.Get(rule.SourcePath)
+				if !value.Exists() || value.Type == gjson.Null || isSensitive(rule.SourcePath, sensitive) {
+					continue
+				}
+				raw := value.Raw
+				if value.Type == gjson.String {
+					raw = value.String()
+				}
+				mapped, err := transform.Apply(rule.Transform, raw)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s[%d] %s: %w", address, index, rule.SourcePath, err))
+					continue
+				}
+				attrs = append(attrs, models.TerraformResourceAttribute{
+					ResourceIndex:  index,
+					AttributeName:  rule.SourcePath,
+					AttributeValue: mapped,
+					ValueType:      valueType(value, rule.Transform, mapped),
+					MappedName:     rule.CanonicalName,
+				})
+			}
+			index++
+			return true
+		})
+		return true
+	})
+	return attrs, errors.Join(errs...)
+}
+
+// Providers 返回 tfstate 中托管资源的云厂商
+func Providers(state []byte) []string {
+	var providers []string
+	seen := make(map[string]bool)
+	gjson.GetBytes(state, "resources").ForEach(func(_, resource gjson.Result) bool {
+		name := providerName(resource.Get("provider").String())
+		if resource.Get("mode").String() == "managed" && name != "" && !seen[name] {
+			seen[name] = true
+			providers = append(providers, name)
+		}
+		return true
+	})
+	return providers
+}
+
+// providerName 由 state 中的 provider 地址解析云厂商名，
+// 如 provider["registry.terraform.io/hashicorp/aws"].west，或 Terraform 0.12 的 provider.aws.west
+func providerName(addr string) string {
+	start := strings.Index(addr, `["`)
+	if start < 0 {
+		name, _, _ := strings.Cut(strings.TrimPrefix(addr, "provider."), ".")
+		return name
+	}
+	if end := strings.Index(addr[start:], `"]`); end >= 0 {
+		addr = addr[start+2 : start+end]
+	}
+	return addr[strings.LastIndex(addr, "/")+1:]
+}
+
+// sensitivePaths 返回实例的 sensitive_attributes，路径以 . 连接
+func sensitivePaths(instance gjson.Result) []string {
+	var paths []string
+	instance.Get("sensitive_attributes").ForEach(func(_, path gjson.Result) bool {
+		var steps []string
+		path.ForEach(func(_, step gjson.Result) bool {
+			key := step.Get("value")
+			if key.IsObject() {
+				key = key.Get("value")
+			}
+			steps = append(steps, key.String())
+			return true
+		})
+		if len(steps) > 0 {
+			paths = append(paths, strings.Join(steps, "."))
+		}
+		return true
+	})
+	return paths
+}
+
+// isSensitive 判断路径本身、其上级或其下级是否为敏感属性
+func isSensitive(path string, sensitive []string) bool {
+	for _, s := range sensitive {
+		if path == s || strings.HasPrefix(path, s+".") || strings.HasPrefix(s, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// valueType 推断映射后的值类型。转换后的值为字符串，
+// 除非由数值转换而来 (scale、unit 或原值为数值) 且结果仍是数值
+func valueType(value gjson.Result, expr, mapped string) string {
+	if expr == "" {
+		switch value.Type {
+		case gjson.Number:
+			return "number"
+		case gjson.True, gjson.False:
+			return "bool"
+		case gjson.JSON:
+			return "json"
+		}
+		return "string"
+	}
+	name, _, _ := strings.Cut(expr, ":")
+	if name == "scale" || name == "unit" || value.Type == gjson.Number {
+		if _, err := strconv.ParseFloat(mapped, 64); err == nil {
+			return "number"
+		}
+	}
+	return "string"
+}
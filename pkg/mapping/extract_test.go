@@ -0,0 +1,104 @@
+package mapping
+
+import (
+	"fmt"
+	"testing"
+
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testState = `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "data",
+      "type": "aws_ami",
+      "name": "ubuntu",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [{"attributes": {"id": "ami-1"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "web",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"].west",
+      "instances": [
+        {
+          "index_key": 0,
+          "attributes": {
+            "id": "i-1",
+            "private_dns": "ip-10-0-0-1.ec2.internal",
+            "monitoring": true,
+            "tags": {"Name": "web-1"},
+            "root_block_device": [{"volume_size": 8, "kms_key_id": "key-1"}],
+            "user_data": null
+          },
+          "sensitive_attributes": [[{"type": "get_attr", "value": "root_block_device"}, {"type": "index", "value": {"value": 0, "type": "number"}}, {"type": "get_attr", "value": "kms_key_id"}]]
+        },
+        {
+          "index_key": 1,
+          "attributes": {
+            "id": "i-2",
+            "private_dns": "web-2.internal",
+            "monitoring": false,
+            "tags": {"Name": "web-2"},
+            "root_block_device": [{"volume_size": 16}]
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+func testRules() []models.AttributeMappingRule {
+	return []models.AttributeMappingRule{
+		{Provider: "aws", ResourceType: "aws_instance", SourcePath: "tags.Name", CanonicalName: "hostname"},
+		{Provider: "aws", ResourceType: "aws_instance", SourcePath: "root_block_device.0.volume_size", CanonicalName: "disk_mb", Transform: "unit:GiB:MiB"},
+		{Provider: "aws", ResourceType: "aws_instance", SourcePath: "private_dns", CanonicalName: "private_ip", Transform: `regex:^ip-([0-9-]+)`},
+		{Provider: "aws", ResourceType: "aws_instance", SourcePath: "monitoring", CanonicalName: "monitored"},
+		{Provider: "aws", ResourceType: "aws_instance", SourcePath: "root_block_device", CanonicalName: "disks"},
+		{Provider: "aws", ResourceType: "aws_instance", SourcePath: "user_data", CanonicalName: "user_data"},
+		{Provider: "aws", ResourceType: "aws_ami", SourcePath: "id", CanonicalName: "image"},
+		{Provider: "alicloud", ResourceType: "aws_instance", SourcePath: "id", CanonicalName: "instance_id"},
+	}
+}
+
+func TestExtract(t *testing.T) {
+	attrs, err := Extract([]byte(testState), testRules())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "aws_instance.web[1] private_dns")
+
+	got := make(map[string]models.TerraformResourceAttribute)
+	for _, a := range attrs {
+		got[fmt.Sprintf("%s/%d", a.MappedName, a.ResourceIndex)] = a
+	}
+	assert.Len(t, got, 8)
+	assert.Equal(t, "web-1", got["hostname/0"].AttributeValue)
+	assert.Equal(t, "tags.Name", got["hostname/0"].AttributeName)
+	assert.Equal(t, "string", got["hostname/0"].ValueType)
+	assert.Equal(t, "8192", got["disk_mb/0"].AttributeValue)
+	assert.Equal(t, "number", got["disk_mb/0"].ValueType)
+	assert.Equal(t, "16384", got["disk_mb/1"].AttributeValue)
+	assert.Equal(t, "10-0-0-1", got["private_ip/0"].AttributeValue)
+	assert.Equal(t, "true", got["monitored/0"].AttributeValue)
+	assert.Equal(t, "bool", got["monitored/0"].ValueType)
+	assert.Equal(t, "false", got["monitored/1"].AttributeValue)
+	assert.Equal(t, "json", got["disks/1"].ValueType)
+
+	// 包含敏感属性的值、null 值、数据源与其他云厂商的规则不提取
+	assert.NotContains(t, got, "disks/0")
+	assert.NotContains(t, got, "user_data/0")
+	assert.NotContains(t, got, "image/0")
+	assert.NotContains(t, got, "instance_id/0")
+}
+
+func TestProviders(t *testing.T) {
+	assert.Equal(t, []string{"aws"}, Providers([]byte(testState)))
+	assert.Empty(t, Providers([]byte(`{"version":4,"resources":[]}`)))
+
+	assert.Equal(t, "aws", providerName(`provider["registry.terraform.io/hashicorp/aws"]`))
+	assert.Equal(t, "alicloud", providerName(`provider["registry.terraform.io/aliyun/alicloud"].hz`))
+	assert.Equal(t, "aws", providerName(`provider.aws.west`))
+}
@@ -0,0 +1,163 @@
+// Package mapping maps resource attributes recorded in terraform state to
+// canonical names by AttributeMappingRules, so that integrating systems such as
+// a CMDB only depend on canonical names instead of provider schemas.
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/tidwall/gjson"
+	"gorm.io/gorm"
+)
+
+// IDGenerator 生成 TerraformResourceAttribute 的 ID，如雪花算法
+type IDGenerator func() int64
+
+// Document 以统一属性名表示的资源，每个实例为统一属性名到值的映射
+type Document struct {
+	ID           int64                    `json:"id"`
+	Provider     string                   `json:"provider"`
+	ResourceType string                   `json:"resource_type"`
+	RegionID     string                   `json:"region_id"`
+	Tenant       string                   `json:"tenant"`
+	Status       string                   `json:"status"`
+	Instances    []map[string]interface{} `json:"instances"`
+}
+
+// Mapper 解析 state 时按映射规则记录资源属性，并提供统一属性名的资源文档
+type Mapper struct {
+	rules     *dao.AttributeMappingRuleDAO
+	attrs     *dao.TerraformResourceAttributeDAO
+	resources *dao.TerraformResourceDAO
+	ids       IDGenerator
+}
+
+// NewMapper 创建属性映射器
+func NewMapper(rules *dao.AttributeMappingRuleDAO, attrs *dao.TerraformResourceAttributeDAO, resources *dao.TerraformResourceDAO, ids IDGenerator) *Mapper {
+	return &Mapper{rules: rules, attrs: attrs, resources: resources, ids: ids}
+}
+
+// Index 按启用的规则提取 state 中的属性并替换资源已映射的实例属性，其他属性保留，实现 terraform.AttributeIndexer。
+// 部分属性转换失败时其余属性仍会记录，并返回转换错误
+func (m *Mapper) Index(resourceID int64, state string) error {
+	if state != "" && !gjson.Valid(state) {
+		return fmt.Errorf("invalid state of resource %d", resourceID)
+	}
+	var rules []models.AttributeMappingRule
+	for _, provider := range Providers([]byte(state)) {
+		enabled, err := m.rules.ListEnabled(provider)
+		if err != nil {
+			return fmt.Errorf("failed to load mapping rules of %s: %w", provider, err)
+		}
+		rules = append(rules, enabled...)
+	}
+
+	attrs, extractErr := Extract([]byte(state), rules)
+	for i := range attrs {
+		attrs[i].ID = m.ids()
+	}
+	if err := m.attrs.ReplaceMapped(resourceID, attrs); err != nil {
+		return fmt.Errorf("failed to save attributes of resource %d: %w", resourceID, err)
+	}
+	if extractErr != nil {
+		return fmt.Errorf("failed to map attributes of resource %d: %w", resourceID, extractErr)
+	}
+	return nil
+}
+
+// Reindex 按当前规则重新提取资源已保存的 state，用于规则变更后
+func (m *Mapper) Reindex(resourceID int64) error {
+	resource, err := m.resources.Get(resourceID)
+	if err != nil {
+		return fmt.Errorf("failed to load resource %d: %w", resourceID, err)
+	}
+	return m.Index(resourceID, resource.TfState)
+}
+
+// Document 返回资源的统一属性文档，只包含有统一属性名的属性，实例按 ResourceIndex 排序
+func (m *Mapper) Document(resourceID int64) (*Document, error) {
+	resource, err := m.resources.Get(resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load resource %d: %w", resourceID, err)
+	}
+	attrs, err := m.attrs.ListByResourceID(resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attributes of resource %d: %w", resourceID, err)
+	}
+
+	instances := make(map[int]map[string]interface{})
+	for _, attr := range attrs {
+		if attr.MappedName == "" {
+			continue
+		}
+		if instances[attr.ResourceIndex] == nil {
+			instances[attr.ResourceIndex] = make(map[string]interface{})
+		}
+		instances[attr.ResourceIndex][attr.MappedName] = typedValue(attr.AttributeValue, attr.ValueType)
+	}
+	indexes := make([]int, 0, len(instances))
+	for index := range instances {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	doc := &Document{
+		ID:           resource.ID,
+		Provider:     resource.Provider,
+		ResourceType: resource.ResourceType,
+		RegionID:     resource.RegionId,
+		Tenant:       resource.Tenant,
+		Status:       resource.Status,
+		Instances:    make([]map[string]interface{}, 0, len(indexes)),
+	}
+	for _, index := range indexes {
+		doc.Instances = append(doc.Instances, instances[index])
+	}
+	return doc, nil
+}
+
+// ServeDocument 处理 GET ?resource_id=<id>，返回资源的统一属性文档
+func (m *Mapper) ServeDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("resource_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid resource_id", http.StatusBadRequest)
+		return
+	}
+	doc, err := m.Document(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "resource not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// typedValue 按 ValueType 还原属性值，无法解析的值保留为字符串
+func typedValue(value, valueType string) interface{} {
+	switch valueType {
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "bool":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "json":
+		if json.Valid([]byte(value)) {
+			return json.RawMessage(value)
+		}
+	}
+	return value
+}
@@ -0,0 +1,140 @@
+package mapping
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/dao"
+	models "github.com/cylonchau/prism/pkg/model"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type fixture struct {
+	mapper    *Mapper
+	rules     *dao.AttributeMappingRuleDAO
+	attrs     *dao.TerraformResourceAttributeDAO
+	resources *dao.TerraformResourceDAO
+}
+
+func setupMapper(t *testing.T) *fixture {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	f := &fixture{
+		rules:     dao.NewAttributeMappingRuleDAO(db),
+		attrs:     dao.NewTerraformResourceAttributeDAO(db),
+		resources: dao.NewTerraformResourceDAO(db),
+	}
+	next := int64(1000)
+	f.mapper = NewMapper(f.rules, f.attrs, f.resources, func() int64 {
+		next++
+		return next
+	})
+	for _, rule := range testRules() {
+		rule.Enabled = true
+		require.NoError(t, f.rules.Create(&rule))
+	}
+	require.NoError(t, f.resources.Create(&models.TerraformResource{
+		ID: 1, Provider: "aws", ResourceType: "aws_instance", RegionId: "us-west-2", Tenant: "acme",
+		TfState: testState, Status: models.ResourceStatusActive,
+	}))
+	return f
+}
+
+func TestMapper_Index(t *testing.T) {
+	f := setupMapper(t)
+
+	// 转换失败的属性跳过，其余属性仍然记录
+	err := f.mapper.Index(1, testState)
+	require.Error(t, err)
+	attrs, err := f.attrs.ListByResourceID(1)
+	require.NoError(t, err)
+	assert.Len(t, attrs, 8)
+	for _, a := range attrs {
+		assert.NotZero(t, a.ID)
+		assert.NotEmpty(t, a.MappedName)
+	}
+
+	// 重新索引只替换映射的属性，未映射的属性与资源级属性保留
+	require.NoError(t, f.attrs.Create(&models.TerraformResourceAttribute{ID: 1, ResourceId: 1, AttributeName: "arn", AttributeValue: "arn:aws:ec2"}))
+	require.NoError(t, f.attrs.Create(&models.TerraformResourceAttribute{ID: 2, ResourceId: 1, ResourceIndex: models.ResourceLevelIndex, AttributeName: "public_ip", AttributeValue: "1.2.3.4"}))
+	require.Error(t, f.mapper.Index(1, testState))
+	attrs, _ = f.attrs.ListByResourceID(1)
+	assert.Len(t, attrs, 10)
+
+	// 销毁后的 state 清空映射的属性
+	require.NoError(t, f.mapper.Index(1, `{"version":4,"resources":[]}`))
+	attrs, _ = f.attrs.ListByResourceID(1)
+	assert.Len(t, attrs, 2)
+
+	assert.Error(t, f.mapper.Index(1, "{"))
+}
+
+func TestMapper_Reindex(t *testing.T) {
+	f := setupMapper(t)
+	rules, err := f.rules.ListEnabled("aws")
+	require.NoError(t, err)
+	for _, rule := range rules {
+		if rule.CanonicalName == "private_ip" {
+			require.NoError(t, f.rules.SetEnabled(rule.ID, false))
+		}
+	}
+
+	require.NoError(t, f.mapper.Reindex(1))
+	attrs, _ := f.attrs.ListByResourceID(1)
+	assert.Len(t, attrs, 7)
+
+	assert.Error(t, f.mapper.Reindex(2))
+}
+
+func TestMapper_Document(t *testing.T) {
+	f := setupMapper(t)
+	f.mapper.Index(1, testState)
+	require.NoError(t, f.attrs.Create(&models.TerraformResourceAttribute{ID: 1, ResourceId: 1, AttributeName: "arn", AttributeValue: "arn:aws:ec2"}))
+
+	doc, err := f.mapper.Document(1)
+	require.NoError(t, err)
+	assert.Equal(t, "us-west-2", doc.RegionID)
+	assert.Equal(t, "acme", doc.Tenant)
+	require.Len(t, doc.Instances, 2)
+
+	data, err := json.Marshal(doc.Instances)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"hostname":"web-1","disk_mb":8192,"private_ip":"10-0-0-1","monitored":true},
+		{"hostname":"web-2","disk_mb":16384,"monitored":false,"disks":[{"volume_size":16}]}
+	]`, string(data))
+}
+
+func TestMapper_ServeDocument(t *testing.T) {
+	f := setupMapper(t)
+	f.mapper.Index(1, testState)
+	server := httptest.NewServer(http.HandlerFunc(f.mapper.ServeDocument))
+	defer server.Close()
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"resource_id=1", http.StatusOK},
+		{"resource_id=2", http.StatusNotFound},
+		{"resource_id=web", http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + "?" + tt.query)
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.query)
+		if tt.status == http.StatusOK {
+			var doc Document
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+			assert.Equal(t, int64(1), doc.ID)
+			assert.Equal(t, "web-2", doc.Instances[1]["hostname"])
+		}
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// AbstractType 与云厂商无关的抽象资源类型，如 compute.instance、network.vpc、storage.bucket
+type AbstractType struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"type:varchar(256);not null;default:''" json:"description"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	Attributes []AbstractAttribute `gorm:"foreignKey:TypeID;constraint:OnDelete:CASCADE" json:"attributes"`
+}
+
+func (AbstractType) TableName() string {
+	return "abstract_type"
+}
+
+// AbstractAttribute 抽象类型的标准属性。ValueType 为 string、int、number、bool、list 或 map，
+// list 与 map 的值为 JSON
+type AbstractAttribute struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	TypeID       int64  `gorm:"not null;uniqueIndex:uk_abstract_attribute" json:"type_id"`
+	Name         string `gorm:"type:varchar(128);not null;uniqueIndex:uk_abstract_attribute" json:"name"`
+	ValueType    string `gorm:"type:varchar(32);not null;default:'string'" json:"value_type"`
+	IsRequired   bool   `gorm:"not null;default:false" json:"is_required"`
+	DefaultValue string `gorm:"type:text;not null;default:''" json:"default_value"`
+	Description  string `gorm:"type:varchar(256);not null;default:''" json:"description"`
+}
+
+func (AbstractAttribute) TableName() string {
+	return "abstract_attribute"
+}
+
+// ProviderTranslation 抽象类型在某个云厂商的资源类型，如 compute.instance + aws → aws_instance。
+// Source 非空时写入 required_providers，如 tencentcloudstack/tencentcloud
+type ProviderTranslation struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	TypeID       int64  `gorm:"not null;uniqueIndex:uk_provider_translation" json:"type_id"`
+	Provider     string `gorm:"type:varchar(64);not null;uniqueIndex:uk_provider_translation" json:"provider"`
+	ResourceType string `gorm:"type:varchar(128);not null" json:"resource_type"`
+	Source       string `gorm:"type:varchar(255);not null;default:''" json:"source"`
+
+	Attributes []AttributeTranslation `gorm:"foreignKey:TranslationID;constraint:OnDelete:CASCADE" json:"attributes"`
+}
+
+func (ProviderTranslation) TableName() string {
+	return "provider_translation"
+}
+
+// AttributeTranslation 标准属性到云厂商资源属性的映射。Target 为属性路径，
+// 以 . 分隔嵌套块 (如 root_block_device.volume_size)，以 [key] 指定 map 属性的键 (如 tags[Name])；
+// Transform 为值转换表达式 (见 transform 包)，ValueType 非空时覆盖标准属性的类型
+type AttributeTranslation struct {
+	ID            int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	TranslationID int64  `gorm:"not null;uniqueIndex:uk_attribute_translation" json:"translation_id"`
+	Attribute     string `gorm:"type:varchar(128);not null;uniqueIndex:uk_attribute_translation;comment:标准属性名" json:"attribute"`
+	Target        string `gorm:"type:varchar(256);not null;comment:云厂商属性路径" json:"target"`
+	Transform     string `gorm:"type:text;not null;default:'';comment:值转换表达式" json:"transform"`
+	ValueType     string `gorm:"type:varchar(32);not null;default:''" json:"value_type"`
+}
+
+func (AttributeTranslation) TableName() string {
+	return "attribute_translation"
+}
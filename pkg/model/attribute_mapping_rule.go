@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// AttributeMappingRule 属性映射规则，解析 state 时将云厂商资源的属性映射为统一的属性名，
+// 写入 TerraformResourceAttribute.MappedName。同一资源类型的属性名与源属性一一对应
+type AttributeMappingRule struct {
+	ID            int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Provider      string    `gorm:"type:varchar(50);not null;uniqueIndex:uk_attribute_mapping_rule;uniqueIndex:uk_attribute_mapping_source" json:"provider"`       // 云厂商，如 aws
+	ResourceType  string    `gorm:"type:varchar(128);not null;uniqueIndex:uk_attribute_mapping_rule;uniqueIndex:uk_attribute_mapping_source" json:"resource_type"` // Terraform 资源类型，如 aws_instance
+	SourcePath    string    `gorm:"type:varchar(255);not null;uniqueIndex:uk_attribute_mapping_source" json:"source_path"`                                         // 实例属性中的 gjson 路径，如 tags.Name、root_block_device.0.volume_size
+	CanonicalName string    `gorm:"type:varchar(128);not null;uniqueIndex:uk_attribute_mapping_rule" json:"canonical_name"`                                        // 统一属性名，如 hostname
+	Transform     string    `gorm:"type:varchar(512);not null;default:''" json:"transform"`                                                                        // 值转换表达式，见 transform 包，如 unit:GiB:MB
+	Enabled       bool      `gorm:"not null;comment:是否启用" json:"enabled"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (AttributeMappingRule) TableName() string {
+	return "attribute_mapping_rule"
+}
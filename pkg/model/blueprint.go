@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// Blueprint 可复用的多资源模板，同名蓝图按版本区分，发布后不再修改
+type Blueprint struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"type:varchar(100);not null;uniqueIndex:uk_blueprint_version" json:"name"`
+	Version     int       `gorm:"not null;uniqueIndex:uk_blueprint_version" json:"version"`
+	Description string    `gorm:"type:varchar(256);not null;default:''" json:"description"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	Resources []BlueprintResource `gorm:"foreignKey:BlueprintID;constraint:OnDelete:CASCADE" json:"resources"`
+	Params    []BlueprintParam    `gorm:"foreignKey:BlueprintID;constraint:OnDelete:CASCADE" json:"params"`
+	Wires     []BlueprintWire     `gorm:"foreignKey:BlueprintID;constraint:OnDelete:CASCADE" json:"wires"`
+}
+
+func (Blueprint) TableName() string {
+	return "blueprint"
+}
+
+// BlueprintResource 蓝图中的资源，Name 为蓝图内的资源名
+type BlueprintResource struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	BlueprintID  int64  `gorm:"not null;uniqueIndex:uk_blueprint_resource" json:"blueprint_id"`
+	Name         string `gorm:"type:varchar(64);not null;uniqueIndex:uk_blueprint_resource" json:"name"`
+	Provider     string `gorm:"type:varchar(64);not null" json:"provider"`
+	ResourceType string `gorm:"type:varchar(64);not null" json:"resource_type"`
+	RegionId     string `gorm:"type:varchar(128);not null;default:''" json:"region_id"`
+	TfConfig     string `gorm:"type:text;comment:Terraform 配置文件" json:"tf_config"`
+}
+
+func (BlueprintResource) TableName() string {
+	return "blueprint_resource"
+}
+
+// BlueprintParam 蓝图资源的参数。Attribute 指向 TerraformConfigMetadata 的属性，
+// 提供类型、是否必填、默认值与验证规则，为空时与 ParamName 同名；
+// DefaultValue 非空时覆盖元数据的默认值
+type BlueprintParam struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	BlueprintID  int64  `gorm:"not null;uniqueIndex:uk_blueprint_param" json:"blueprint_id"`
+	Resource     string `gorm:"type:varchar(64);not null;uniqueIndex:uk_blueprint_param;comment:蓝图内的资源名" json:"resource"`
+	ParamName    string `gorm:"type:varchar(128);not null;uniqueIndex:uk_blueprint_param" json:"param_name"`
+	Attribute    string `gorm:"type:varchar(128);not null;default:'';comment:元数据属性名" json:"attribute"`
+	DefaultValue string `gorm:"type:text;not null;default:''" json:"default_value"`
+}
+
+func (BlueprintParam) TableName() string {
+	return "blueprint_param"
+}
+
+// BlueprintWire 蓝图资源之间的依赖，实例化为 ResourceDependency
+type BlueprintWire struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	BlueprintID int64  `gorm:"not null;index" json:"blueprint_id"`
+	Resource    string `gorm:"type:varchar(64);not null;comment:下游资源名" json:"resource"`
+	DependsOn   string `gorm:"type:varchar(64);not null;comment:上游资源名" json:"depends_on"`
+	Attribute   string `gorm:"type:varchar(128);not null;default:''" json:"attribute"`
+	ParamName   string `gorm:"type:varchar(128);not null;default:''" json:"param_name"`
+}
+
+func (BlueprintWire) TableName() string {
+	return "blueprint_wire"
+}
+
+// BlueprintInstance 蓝图实例，记录实例化时的蓝图版本与输入
+type BlueprintInstance struct {
+	ID            int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name          string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"`
+	BlueprintName string    `gorm:"type:varchar(100);not null;index" json:"blueprint_name"`
+	Version       int       `gorm:"not null" json:"version"`
+	Tenant        string    `gorm:"type:varchar(64);not null;default:''" json:"tenant"`
+	Credential    string    `gorm:"type:varchar(128);not null;default:''" json:"credential"`
+	Inputs        string    `gorm:"type:text;comment:实例化输入 (JSON)，升级时重新解析参数" json:"inputs"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	Resources []BlueprintInstanceResource `gorm:"foreignKey:InstanceID;constraint:OnDelete:CASCADE" json:"resources"`
+}
+
+func (BlueprintInstance) TableName() string {
+	return "blueprint_instance"
+}
+
+// BlueprintInstanceResource 实例中蓝图资源名到 TerraformResource 的映射
+type BlueprintInstanceResource struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	InstanceID int64  `gorm:"not null;uniqueIndex:uk_instance_resource" json:"instance_id"`
+	Resource   string `gorm:"type:varchar(64);not null;uniqueIndex:uk_instance_resource" json:"resource"`
+	ResourceID int64  `gorm:"type:bigint;not null;index" json:"resource_id"`
+}
+
+func (BlueprintInstanceResource) TableName() string {
+	return "blueprint_instance_resource"
+}
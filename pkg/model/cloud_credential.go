@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// CloudCredential 云账号凭证，凭证内容以信封加密方式存储
+type CloudCredential struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Provider    string    `gorm:"type:varchar(64);not null;uniqueIndex:uk_provider_name;comment:云厂商 (aws, tencentcloud, alicloud, kubernetes)" json:"provider"`
+	Name        string    `gorm:"type:varchar(128);not null;uniqueIndex:uk_provider_name;comment:凭证名称" json:"name"`
+	Kind        string    `gorm:"type:varchar(32);not null;default:'access_key';comment:凭证类型 (access_key, kubeconfig)" json:"kind"`
+	KeyID       string    `gorm:"type:varchar(64);not null;index:idx_key_id;comment:加密数据密钥的主密钥 ID" json:"key_id"`
+	WrappedKey  string    `gorm:"type:text;not null;comment:被主密钥加密的数据密钥 (base64)" json:"-"`
+	Ciphertext  string    `gorm:"type:text;not null;comment:加密后的凭证内容 (base64)" json:"-"`
+	Description string    `gorm:"type:varchar(256);not null;default:'';comment:说明" json:"description"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (CloudCredential) TableName() string {
+	return "cloud_credential"
+}
+
+func (CloudCredential) Indexes() map[string]string {
+	return map[string]string{
+		"uk_provider_name": "unique:provider,name",
+	}
+}
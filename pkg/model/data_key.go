@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// DataKey 租户数据密钥，由主密钥加密后存储，用于加密 tfstate 与 plan
+type DataKey struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Tenant     string    `gorm:"type:varchar(64);not null;default:'';index:idx_data_key_tenant_active;comment:租户" json:"tenant"`
+	KeyID      string    `gorm:"type:varchar(64);not null;index:idx_data_key_key_id;comment:加密数据密钥的主密钥 ID" json:"key_id"`
+	WrappedKey string    `gorm:"type:text;not null;comment:被主密钥加密的数据密钥 (base64)" json:"-"`
+	Active     bool      `gorm:"not null;default:true;index:idx_data_key_tenant_active;comment:是否为租户当前使用的密钥" json:"active"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (DataKey) TableName() string {
+	return "data_key"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ErrorPattern 错误分类规则，按正则匹配诊断信息与命令输出
+type ErrorPattern struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"type:varchar(64);not null;default:'';index:idx_error_pattern_name" json:"name"`         // 内置规则标识，为空表示自定义规则
+	Provider    string    `gorm:"type:varchar(50);not null;default:'';index:idx_error_pattern_provider" json:"provider"` // 为空表示适用于全部云厂商
+	Code        string    `gorm:"type:varchar(32);not null" json:"code"`                                                 // executor.ErrorCode
+	Pattern     string    `gorm:"type:varchar(512);not null" json:"pattern"`                                             // 正则表达式
+	Priority    int       `gorm:"not null;default:0" json:"priority"`                                                    // 越大越先匹配
+	Enabled     bool      `gorm:"not null;comment:是否启用" json:"enabled"`
+	Description string    `gorm:"type:varchar(512);default:''" json:"description"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (ErrorPattern) TableName() string {
+	return "error_pattern"
+}
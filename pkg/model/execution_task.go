@@ -15,18 +15,24 @@ const (
 
 // ExecutionTask stores task execution information.
 type ExecutionTask struct {
-	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
-	TaskID     string     `gorm:"size:64;uniqueIndex;not null" json:"task_id"`
-	ResourceID int64      `gorm:"index;not null" json:"resource_id"`
-	Action     string     `gorm:"size:20;not null" json:"action"`
-	Status     TaskStatus `gorm:"not null;default:0" json:"status"`
-	Output     string     `gorm:"type:text" json:"output"`
-	Error      string     `gorm:"type:text" json:"error"`
-	StartedAt  *time.Time `json:"started_at"`
-	FinishedAt *time.Time `json:"finished_at"`
-	Duration   int64      `json:"duration"` // milliseconds
-	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt  time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	ID           int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID       string     `gorm:"size:64;uniqueIndex;not null" json:"task_id"`
+	ResourceID   int64      `gorm:"index;not null" json:"resource_id"`
+	Action       string     `gorm:"size:20;not null" json:"action"`
+	ParentTaskID string     `gorm:"size:64;index" json:"parent_task_id"` // pipeline of a step, or task this one rolls back
+	Step         int        `gorm:"not null;default:0" json:"step"`      // position in the parent pipeline, from 1
+	Status       TaskStatus `gorm:"not null;default:0" json:"status"`
+	Output       string     `gorm:"type:text" json:"output"`
+	Error        string     `gorm:"type:text" json:"error"`
+	ErrorCode    string     `gorm:"size:32;index" json:"error_code"`         // executor.ErrorCode of a failed task
+	Permanent    bool       `gorm:"not null;default:false" json:"permanent"` // failure will not go away by retrying
+	Attempt      int        `gorm:"not null;default:0" json:"attempt"`       // number of started attempts
+	NextRetryAt  *time.Time `gorm:"index" json:"next_retry_at"`              // scheduled automatic retry
+	StartedAt    *time.Time `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	Duration     int64      `json:"duration"` // milliseconds
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 func (ExecutionTask) TableName() string {
@@ -35,5 +41,5 @@ func (ExecutionTask) TableName() string {
 
 // IsRetryable checks if task can be retried.
 func (t *ExecutionTask) IsRetryable() bool {
-	return t.Status == TaskStatusFailed || t.Status == TaskStatusCancelled
+	return (t.Status == TaskStatusFailed && !t.Permanent) || t.Status == TaskStatusCancelled
 }
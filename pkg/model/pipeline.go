@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Pipeline statuses.
+const (
+	PipelineStatusPending  = "pending"
+	PipelineStatusRunning  = "running"
+	PipelineStatusWaiting  = "waiting" // waiting for approval
+	PipelineStatusSuccess  = "success"
+	PipelineStatusFailed   = "failed"
+	PipelineStatusRejected = "rejected"
+)
+
+// Pipeline step kinds, stored as ExecutionTask.Action of each step.
+const (
+	StepValidate = "validate"
+	StepPlan     = "plan"
+	StepPolicy   = "policy"
+	StepApproval = "approval"
+	StepApply    = "apply"
+	StepDestroy  = "destroy"
+	StepOutput   = "output"
+)
+
+// Pipeline groups ordered steps run against one resource. Each step is an
+// ExecutionTask whose ParentTaskID is the pipeline ID.
+type Pipeline struct {
+	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	PipelineID string     `gorm:"size:64;uniqueIndex;not null" json:"pipeline_id"`
+	ResourceID int64      `gorm:"index;not null" json:"resource_id"`
+	Status     string     `gorm:"size:20;not null;default:'pending';index" json:"status"`
+	Step       int        `gorm:"not null;default:0" json:"step"` // current or last run step
+	WorkDir    string     `gorm:"size:512" json:"work_dir"`       // shared by all steps
+	Approver   string     `gorm:"size:128" json:"approver"`
+	Error      string     `gorm:"type:text" json:"error"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Pipeline) TableName() string {
+	return "pipeline"
+}
+
+// IsFinished checks if the pipeline reached a final status.
+func (p *Pipeline) IsFinished() bool {
+	switch p.Status {
+	case PipelineStatusSuccess, PipelineStatusFailed, PipelineStatusRejected:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ResourceDependency 资源依赖：ResourceID 依赖 DependsOnID，上游资源先执行、后销毁。
+// Attribute 非空时将上游资源的该属性 (按 MappedName，其次 AttributeName 匹配) 作为参数
+// ParamName 注入下游配置，ParamName 为空时与 Attribute 同名；Attribute 为空表示仅约束顺序
+type ResourceDependency struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ResourceID  int64     `gorm:"not null;uniqueIndex:uk_resource_dependency" json:"resource_id"`
+	DependsOnID int64     `gorm:"not null;uniqueIndex:uk_resource_dependency;index" json:"depends_on_id"`
+	Attribute   string    `gorm:"type:varchar(128);not null;default:'';comment:上游资源的属性名" json:"attribute"`
+	ParamName   string    `gorm:"type:varchar(128);not null;default:'';uniqueIndex:uk_resource_dependency;comment:注入下游的参数名" json:"param_name"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (ResourceDependency) TableName() string {
+	return "resource_dependency"
+}
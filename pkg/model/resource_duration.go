@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ResourceDuration 资源类型各操作的历史耗时，用于估算任务剩余时间
+type ResourceDuration struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ResourceType string    `gorm:"size:128;not null;uniqueIndex:uk_resource_duration" json:"resource_type"` // 如 aws_instance
+	Action       string    `gorm:"size:20;not null;uniqueIndex:uk_resource_duration" json:"action"`         // create, update, delete, replace
+	Samples      int64     `gorm:"not null;default:0" json:"samples"`
+	AvgMs        int64     `gorm:"not null;default:0" json:"avg_ms"` // 指数加权平均耗时
+	LastMs       int64     `gorm:"not null;default:0" json:"last_ms"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (ResourceDuration) TableName() string {
+	return "resource_duration"
+}
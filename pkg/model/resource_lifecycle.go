@@ -0,0 +1,68 @@
+package models
+
+import (
+	"context"
+
+	"github.com/looplab/fsm"
+)
+
+// 资源生命周期状态
+const (
+	ResourceStatusPending      = "pending"      // 已登记，尚未创建
+	ResourceStatusProvisioning = "provisioning" // 创建中
+	ResourceStatusActive       = "active"       // 与配置一致
+	ResourceStatusUpdating     = "updating"     // 变更中
+	ResourceStatusDestroying   = "destroying"   // 销毁中
+	ResourceStatusDestroyed    = "destroyed"    // 已销毁
+	ResourceStatusError        = "error"        // 最近一次操作失败
+	ResourceStatusDrifted      = "drifted"      // 云上状态偏离配置
+	ResourceStatusImporting    = "importing"    // 导入中
+)
+
+// 资源生命周期事件
+const (
+	ResourceEventApply    = "apply"    // 开始 apply
+	ResourceEventDestroy  = "destroy"  // 开始 destroy
+	ResourceEventImport   = "import"   // 开始 import
+	ResourceEventComplete = "complete" // 操作成功
+	ResourceEventFail     = "fail"     // 操作失败
+	ResourceEventDrift    = "drift"    // plan 发现偏离
+	ResourceEventSync     = "sync"     // plan 未发现偏离
+)
+
+// resourceEvents 资源生命周期的合法状态转换，同一事件按源状态进入不同状态
+var resourceEvents = fsm.Events{
+	{Name: ResourceEventApply, Src: []string{ResourceStatusPending, ResourceStatusDestroyed, ResourceStatusError}, Dst: ResourceStatusProvisioning},
+	{Name: ResourceEventApply, Src: []string{ResourceStatusActive, ResourceStatusDrifted}, Dst: ResourceStatusUpdating},
+	{Name: ResourceEventDestroy, Src: []string{ResourceStatusPending, ResourceStatusActive, ResourceStatusDrifted, ResourceStatusError}, Dst: ResourceStatusDestroying},
+	{Name: ResourceEventImport, Src: []string{ResourceStatusPending, ResourceStatusDestroyed, ResourceStatusError}, Dst: ResourceStatusImporting},
+	{Name: ResourceEventComplete, Src: []string{ResourceStatusProvisioning, ResourceStatusUpdating, ResourceStatusImporting}, Dst: ResourceStatusActive},
+	{Name: ResourceEventComplete, Src: []string{ResourceStatusDestroying}, Dst: ResourceStatusDestroyed},
+	{Name: ResourceEventFail, Src: []string{ResourceStatusProvisioning, ResourceStatusUpdating, ResourceStatusDestroying, ResourceStatusImporting}, Dst: ResourceStatusError},
+	{Name: ResourceEventDrift, Src: []string{ResourceStatusActive}, Dst: ResourceStatusDrifted},
+	{Name: ResourceEventSync, Src: []string{ResourceStatusDrifted}, Dst: ResourceStatusActive},
+}
+
+// NewResourceLifecycle 创建从 status 开始的资源生命周期状态机
+func NewResourceLifecycle(status string) *fsm.FSM {
+	return fsm.NewFSM(status, resourceEvents, fsm.Callbacks{})
+}
+
+// ResourceTransitionTo 返回 status 经 event 进入的状态，转换不合法时返回 false
+func ResourceTransitionTo(status, event string) (string, bool) {
+	lifecycle := NewResourceLifecycle(status)
+	if err := lifecycle.Event(context.Background(), event); err != nil {
+		return "", false
+	}
+	return lifecycle.Current(), true
+}
+
+// ResourceEventFor 返回从 from 进入 to 的事件，不存在时返回 false
+func ResourceEventFor(from, to string) (string, bool) {
+	for _, event := range NewResourceLifecycle(from).AvailableTransitions() {
+		if dst, ok := ResourceTransitionTo(from, event); ok && dst == to {
+			return event, true
+		}
+	}
+	return "", false
+}
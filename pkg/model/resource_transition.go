@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ResourceTransition 资源状态转换记录
+type ResourceTransition struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ResourceID int64     `gorm:"not null;index:idx_resource_transition" json:"resource_id"`
+	FromStatus string    `gorm:"type:varchar(32);not null" json:"from_status"`
+	ToStatus   string    `gorm:"type:varchar(32);not null" json:"to_status"`
+	Event      string    `gorm:"type:varchar(32);not null" json:"event"`
+	TaskID     string    `gorm:"type:varchar(64);index;comment:触发转换的任务" json:"task_id"`
+	Reason     string    `gorm:"type:text;comment:转换原因，如失败信息" json:"reason"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index:idx_resource_transition" json:"created_at"`
+}
+
+func (ResourceTransition) TableName() string {
+	return "resource_transition"
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RetryPolicy 自动重试策略，按云厂商与动作匹配，为空表示任意。
+// MaxAttempts、InitialDelay、MaxDelay、Multiplier 为 0 时使用默认值
+type RetryPolicy struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Provider     string    `gorm:"type:varchar(50);not null;default:'';uniqueIndex:uk_retry_policy" json:"provider"`
+	Action       string    `gorm:"type:varchar(20);not null;default:'';uniqueIndex:uk_retry_policy" json:"action"`
+	MaxAttempts  int       `gorm:"not null;default:0" json:"max_attempts"`       // 含首次执行
+	InitialDelay int64     `gorm:"not null;default:0" json:"initial_delay"`      // 首次重试间隔(ms)
+	MaxDelay     int64     `gorm:"not null;default:0" json:"max_delay"`          // 最大重试间隔(ms)
+	Multiplier   float64   `gorm:"not null;default:0" json:"multiplier"`         // 间隔倍数
+	Jitter       float64   `gorm:"not null;default:0" json:"jitter"`             // 随机抖动比例 0-1
+	RetryOn      string    `gorm:"type:varchar(255);default:''" json:"retry_on"` // 逗号分隔的错误分类，为空表示全部可重试分类，unknown 需显式列出
+	Enabled      bool      `gorm:"not null;default:true;comment:是否启用" json:"enabled"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (RetryPolicy) TableName() string {
+	return "retry_policy"
+}
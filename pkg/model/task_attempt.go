@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// TaskAttempt stores the result of one execution attempt of a task.
+type TaskAttempt struct {
+	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID     string     `gorm:"size:64;not null;uniqueIndex:uk_task_attempt" json:"task_id"`
+	Attempt    int        `gorm:"not null;uniqueIndex:uk_task_attempt" json:"attempt"`
+	Status     TaskStatus `gorm:"not null" json:"status"`
+	ErrorCode  string     `gorm:"size:32" json:"error_code"`
+	Error      string     `gorm:"type:text" json:"error"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Duration   int64      `json:"duration"` // milliseconds
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (TaskAttempt) TableName() string {
+	return "task_attempt"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Diagnostic severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// TaskDiagnostic stores an error or warning reported during a task.
+type TaskDiagnostic struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID    string    `gorm:"size:64;not null;index:idx_task_diagnostic_severity" json:"task_id"`
+	Severity  string    `gorm:"size:20;not null;index:idx_task_diagnostic_severity" json:"severity"`
+	Summary   string    `gorm:"type:text" json:"summary"`
+	Detail    string    `gorm:"type:text" json:"detail"`
+	Address   string    `gorm:"size:255" json:"address"`
+	Filename  string    `gorm:"size:255" json:"filename"`
+	Line      int       `json:"line"`
+	Data      string    `gorm:"type:text" json:"data"` // JSON encoded diagnostic with range and snippet
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (TaskDiagnostic) TableName() string {
+	return "task_diagnostic"
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Task event kinds.
+const (
+	TaskEventState = "state" // executor state machine transition
+	TaskEventPhase = "phase" // execution phase change
+)
+
+// Task phases recorded besides the terraform phases (init, plan, apply, destroy, parse).
+const (
+	TaskPhaseQueued    = "queued"    // task record created
+	TaskPhaseLockWait  = "lock_wait" // waiting for the resource lock
+	TaskPhaseCompleted = "completed" // execution finished
+)
+
+// TaskEvent records a state transition or phase change of a task. Each event
+// lasts until the next event of the same kind of the same task.
+type TaskEvent struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID    string    `gorm:"size:64;not null;index" json:"task_id"`
+	Kind      string    `gorm:"size:20;not null" json:"kind"`
+	Name      string    `gorm:"size:32;not null" json:"name"` // new state or phase
+	From      string    `gorm:"size:32" json:"from"`          // previous state, state events only
+	Message   string    `gorm:"type:text" json:"message"`
+	Timestamp time.Time `gorm:"precision:3;not null;index" json:"timestamp"`
+}
+
+func (TaskEvent) TableName() string {
+	return "task_event"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// TaskLog stores a hub message of a task for replay.
+type TaskLog struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID    string    `gorm:"size:64;not null;uniqueIndex:uk_task_log_seq" json:"task_id"`
+	Seq       int64     `gorm:"not null;uniqueIndex:uk_task_log_seq" json:"seq"` // per-task sequence number
+	Type      string    `gorm:"size:20;not null" json:"type"`
+	Level     string    `gorm:"size:10" json:"level"`  // log level, log messages only
+	Data      string    `gorm:"type:text" json:"data"` // JSON encoded message data
+	Time      time.Time `gorm:"not null" json:"time"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (TaskLog) TableName() string {
+	return "task_log"
+}
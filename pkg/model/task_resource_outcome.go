@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// TaskResourceOutcome stores the outcome of a resource address in a task.
+type TaskResourceOutcome struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID       string    `gorm:"size:64;not null;uniqueIndex:uk_task_resource_outcome" json:"task_id"`
+	Address      string    `gorm:"size:255;not null;uniqueIndex:uk_task_resource_outcome" json:"address"`
+	ResourceType string    `gorm:"size:128" json:"resource_type"`
+	Action       string    `gorm:"size:20" json:"action"`           // planned action
+	Outcome      string    `gorm:"size:20;not null" json:"outcome"` // created, updated, destroyed, replaced, read, failed, interrupted, skipped
+	CloudID      string    `gorm:"size:255" json:"cloud_id"`        // resource id reported by terraform
+	Duration     int64     `json:"duration"`                        // milliseconds
+	Error        string    `gorm:"type:text" json:"error"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (TaskResourceOutcome) TableName() string {
+	return "task_resource_outcome"
+}
@@ -5,10 +5,13 @@ type TerraformResource struct {
 	Provider     string `gorm:"type:varchar(64);not null;index:idx_provider" json:"provider"`
 	ResourceType string `gorm:"type:varchar(64);not null;index:idx_resource_type" json:"resource_type"`
 	RegionId     string `gorm:"type:varchar(128);index:idx_region" json:"region_id"`
+	Tenant       string `gorm:"type:varchar(64);not null;default:'';index:idx_tenant;comment:租户 (决定状态加密使用的数据密钥)" json:"tenant"`
+	Credential   string `gorm:"type:varchar(128);not null;default:'';comment:云账号凭证名称 (cloud_credential.name)" json:"credential"`
 	TfConfig     string `gorm:"type:text;comment:Terraform 配置文件" json:"tf_config"`
-	TfState      string `gorm:"type:text;comment:Terraform 状态文件 (完整 tfstate)" json:"tf_state"`
+	TfState      string `gorm:"type:text;comment:Terraform 状态文件 (完整 tfstate，启用加密时为密文)" json:"tf_state"`
+	TfPlan       string `gorm:"type:text;comment:保存的 Terraform plan (base64，启用加密时为密文)" json:"tf_plan"`
 	Action       string `gorm:"type:varchar(32);comment:操作类型 (apply, destroy, import)" json:"action"`
-	Status       string `gorm:"type:varchar(32);default:'pending';comment:资源状态" json:"status"`
+	Status       string `gorm:"type:varchar(32);default:'pending';comment:资源生命周期状态 (见 ResourceStatus*)" json:"status"`
 }
 
 func (TerraformResource) TableName() string {
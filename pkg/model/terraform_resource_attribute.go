@@ -1,5 +1,8 @@
 package models
 
+// ResourceLevelIndex 资源级属性的 ResourceIndex，如 apply 结果中的资源地址属性与根模块输出，不属于任何实例
+const ResourceLevelIndex = -1
+
 type TerraformResourceAttribute struct {
 	ID             int64  `gorm:"type:bigint;primaryKey;autoIncrement:false" json:"id"`
 	ResourceId     int64  `gorm:"type:bigint;not null;index:idx_resource_id;index:idx_resource_index" json:"resource_id"`
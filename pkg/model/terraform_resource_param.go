@@ -0,0 +1,16 @@
+package models
+
+// TerraformResourceParam 资源参数，执行时以 TF_VAR_<name> 注入
+type TerraformResourceParam struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	ResourceID int64  `gorm:"type:bigint;not null;uniqueIndex:uk_resource_param" json:"resource_id"`
+	ParamName  string `gorm:"type:varchar(128);not null;uniqueIndex:uk_resource_param;comment:参数名" json:"param_name"`
+	ParamValue string `gorm:"type:text;not null;default:'';comment:参数值 (支持 secret://<provider>/<path>#<key> 引用)" json:"param_value"`
+	ValueType  string `gorm:"type:varchar(32);not null;default:'string';comment:参数值类型" json:"value_type"`
+
+	Resource *TerraformResource `gorm:"foreignKey:ResourceID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (TerraformResourceParam) TableName() string {
+	return "terraform_resource_param"
+}
@@ -0,0 +1,31 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvProvider 从 Prism 进程环境变量读取密钥，path 为变量名，key 被忽略。
+// prefix 限制可读取的变量，避免引用暴露数据库密码等无关变量。
+type EnvProvider struct {
+	prefix string
+}
+
+// NewEnvProvider 创建环境变量 provider
+func NewEnvProvider(prefix string) *EnvProvider {
+	return &EnvProvider{prefix: prefix}
+}
+
+// Get 读取环境变量
+func (p *EnvProvider) Get(ctx context.Context, path, key string) (string, error) {
+	if !strings.HasPrefix(path, p.prefix) {
+		return "", fmt.Errorf("environment variable %s is not allowed (prefix %q)", path, p.prefix)
+	}
+	value, ok := os.LookupEnv(path)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s not set", path)
+	}
+	return value, nil
+}
@@ -0,0 +1,113 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/cylonchau/prism/pkg/encrypt"
+)
+
+// fileAAD 绑定密文用途，防止与其他信封加密数据混用
+var fileAAD = []byte("prism-secret-file")
+
+// fileEnvelope 加密文件的磁盘格式
+type fileEnvelope struct {
+	KeyID      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// FileProvider 从本地加密文件读取密钥，文件内容解密后为 {"path": {"key": "value"}}
+type FileProvider struct {
+	path    string
+	keyring *encrypt.Keyring
+
+	mu      sync.Mutex
+	secrets map[string]map[string]string
+}
+
+// NewFileProvider 创建加密文件 provider，文件在首次读取时加载
+func NewFileProvider(path string, keyring *encrypt.Keyring) *FileProvider {
+	return &FileProvider{path: path, keyring: keyring}
+}
+
+// Get 读取密钥
+func (p *FileProvider) Get(ctx context.Context, path, key string) (string, error) {
+	secrets, err := p.load()
+	if err != nil {
+		return "", err
+	}
+	values, ok := secrets[path]
+	if !ok {
+		return "", fmt.Errorf("path %s not found", path)
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found at %s", key, path)
+	}
+	return value, nil
+}
+
+// Reload 丢弃缓存，下次读取时重新加载文件
+func (p *FileProvider) Reload() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.secrets = nil
+}
+
+// load 返回已加载的密钥，未加载时读取文件。返回的 map 加载后不再修改，Reload 只替换引用
+func (p *FileProvider) load() (map[string]map[string]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.secrets != nil {
+		return p.secrets, nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret file: %w", err)
+	}
+	var env fileEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid secret file: %w", err)
+	}
+	plaintext, err := p.keyring.OpenEnvelope(&encrypt.Envelope{
+		KeyID:      env.KeyID,
+		WrappedKey: env.WrappedKey,
+		Ciphertext: env.Ciphertext,
+	}, fileAAD)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]map[string]string)
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("invalid secret file content: %w", err)
+	}
+	p.secrets = secrets
+	return secrets, nil
+}
+
+// WriteSecretFile 加密并写入密钥文件
+func WriteSecretFile(path string, keyring *encrypt.Keyring, secrets map[string]map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	env, err := keyring.SealEnvelope(plaintext, fileAAD)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&fileEnvelope{
+		KeyID:      env.KeyID,
+		WrappedKey: env.WrappedKey,
+		Ciphertext: env.Ciphertext,
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,88 @@
+package secret
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cylonchau/prism/pkg/encrypt"
+)
+
+func TestFileProvider(t *testing.T) {
+	key, _ := encrypt.GenerateKey()
+	keyring, _ := encrypt.NewKeyring(key)
+	path := filepath.Join(t.TempDir(), "secrets.json")
+
+	err := WriteSecretFile(path, keyring, map[string]map[string]string{
+		"aws/prod": {"secret_key": "s3cr3t"},
+	})
+	if err != nil {
+		t.Fatalf("WriteSecretFile should succeed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "s3cr3t") {
+		t.Fatal("secret file should not contain plaintext")
+	}
+
+	p := NewFileProvider(path, keyring)
+	value, err := p.Get(context.Background(), "aws/prod", "secret_key")
+	if err != nil {
+		t.Fatalf("Get should succeed: %v", err)
+	}
+	if value != "s3cr3t" {
+		t.Errorf("value should be 's3cr3t', got %q", value)
+	}
+
+	if _, err := p.Get(context.Background(), "aws/prod", "missing"); err == nil {
+		t.Error("missing key should return error")
+	}
+	if _, err := p.Get(context.Background(), "aws/dev", "secret_key"); err == nil {
+		t.Error("missing path should return error")
+	}
+}
+
+func TestFileProvider_WrongKey(t *testing.T) {
+	key, _ := encrypt.GenerateKey()
+	keyring, _ := encrypt.NewKeyring(key)
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	WriteSecretFile(path, keyring, map[string]map[string]string{"a": {"b": "c"}})
+
+	other, _ := encrypt.GenerateKey()
+	otherKeyring, _ := encrypt.NewKeyring(other)
+	if _, err := NewFileProvider(path, otherKeyring).Get(context.Background(), "a", "b"); err == nil {
+		t.Error("wrong master key should fail")
+	}
+}
+
+func TestFileProvider_ConcurrentReload(t *testing.T) {
+	key, _ := encrypt.GenerateKey()
+	keyring, _ := encrypt.NewKeyring(key)
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	WriteSecretFile(path, keyring, map[string]map[string]string{"a": {"b": "c"}})
+
+	p := NewFileProvider(path, keyring)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if v, err := p.Get(context.Background(), "a", "b"); err != nil || v != "c" {
+					t.Errorf("Get during reload: %q %v", v, err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				p.Reload()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,102 @@
+package secret
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask 脱敏后的占位符
+const Mask = "******"
+
+// minSecretLength 过短的值（如 "1"、"on"）不做替换，避免把普通输出整体打码
+const minSecretLength = 4
+
+// Redactor 将已知密钥值替换为 Mask
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  map[string]struct{}
+	replacer *strings.Replacer
+}
+
+// NewRedactor 创建脱敏器
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{secrets: make(map[string]struct{})}
+	r.Add(secrets...)
+	return r
+}
+
+// Add 添加需要脱敏的值，同时覆盖其 JSON 转义形式
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := false
+	for _, s := range secrets {
+		if len(s) < minSecretLength {
+			continue
+		}
+		for _, variant := range variants(s) {
+			if _, ok := r.secrets[variant]; !ok {
+				r.secrets[variant] = struct{}{}
+				changed = true
+			}
+		}
+	}
+	if changed {
+		r.rebuild()
+	}
+}
+
+// Reset 清空所有已知密钥
+func (r *Redactor) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets = make(map[string]struct{})
+	r.replacer = nil
+}
+
+// Redact 替换 s 中出现的所有密钥
+func (r *Redactor) Redact(s string) string {
+	r.mu.RLock()
+	replacer := r.replacer
+	r.mu.RUnlock()
+
+	if replacer == nil || s == "" {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// rebuild 按长度降序构建替换器，保证较长的密钥优先匹配
+func (r *Redactor) rebuild() {
+	list := make([]string, 0, len(r.secrets))
+	for s := range r.secrets {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i]) != len(list[j]) {
+			return len(list[i]) > len(list[j])
+		}
+		return list[i] < list[j]
+	})
+
+	pairs := make([]string, 0, len(list)*2)
+	for _, s := range list {
+		pairs = append(pairs, s, Mask)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// variants 返回密钥本身及其在 JSON 字符串中的转义形式
+func variants(s string) []string {
+	result := []string{s}
+	if data, err := json.Marshal(s); err == nil {
+		escaped := string(data[1 : len(data)-1])
+		if escaped != s {
+			result = append(result, escaped)
+		}
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package secret
+
+import "testing"
+
+func TestRedactor_Redact(t *testing.T) {
+	r := NewRedactor("s3cr3t-key", "ab")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "Error: invalid key s3cr3t-key", expected: "Error: invalid key ******"},
+		{input: "no secret here", expected: "no secret here"},
+		{input: "ab is too short to redact", expected: "ab is too short to redact"},
+		{input: "", expected: ""},
+	}
+	for _, tt := range tests {
+		if got := r.Redact(tt.input); got != tt.expected {
+			t.Errorf("Redact(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestRedactor_LongestFirst(t *testing.T) {
+	r := NewRedactor("pass", "password123")
+
+	if got := r.Redact("password123"); got != Mask {
+		t.Errorf("longer secret should be matched first, got %q", got)
+	}
+}
+
+func TestRedactor_JSONEscaped(t *testing.T) {
+	r := NewRedactor(`pa"ss\word`)
+
+	line := `{"@message":"value: pa\"ss\\word"}`
+	if got := r.Redact(line); got != `{"@message":"value: ******"}` {
+		t.Errorf("JSON escaped secret should be redacted, got %q", got)
+	}
+}
+
+func TestRedactor_Reset(t *testing.T) {
+	r := NewRedactor("topsecret")
+	r.Reset()
+
+	if got := r.Redact("topsecret"); got != "topsecret" {
+		t.Errorf("reset redactor should not redact, got %q", got)
+	}
+
+	r.Add("topsecret")
+	if got := r.Redact("topsecret"); got != Mask {
+		t.Errorf("added secret should be redacted, got %q", got)
+	}
+}
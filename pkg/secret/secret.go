@@ -0,0 +1,122 @@
+// Package secret resolves secret references from pluggable providers.
+//
+// A reference has the form secret://<provider>/<path>#<key>, for example
+// secret://vault/cloud/aws/prod#secret_key or secret://env/TF_DB_PASSWORD.
+package secret
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Scheme 引用前缀
+const Scheme = "secret://"
+
+// Provider 密钥提供者
+type Provider interface {
+	// Get returns the value of key stored at path.
+	Get(ctx context.Context, path, key string) (string, error)
+}
+
+// Ref 解析后的密钥引用
+type Ref struct {
+	Provider string
+	Path     string
+	Key      string
+}
+
+// String 返回引用的原始形式
+func (r *Ref) String() string {
+	s := Scheme + r.Provider + "/" + r.Path
+	if r.Key != "" {
+		s += "#" + r.Key
+	}
+	return s
+}
+
+// IsRef 判断值是否为密钥引用
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+// ParseRef 解析 secret://<provider>/<path>#<key>
+func ParseRef(value string) (*Ref, error) {
+	if !IsRef(value) {
+		return nil, fmt.Errorf("not a secret reference")
+	}
+	rest := strings.TrimPrefix(value, Scheme)
+	provider, rest, ok := strings.Cut(rest, "/")
+	if !ok || provider == "" || rest == "" {
+		return nil, fmt.Errorf("invalid secret reference %q: expected %s<provider>/<path>#<key>", value, Scheme)
+	}
+	path, key, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return nil, fmt.Errorf("invalid secret reference %q: empty path", value)
+	}
+	return &Ref{Provider: provider, Path: path, Key: key}, nil
+}
+
+// Resolver 按 provider 名称分发引用解析
+type Resolver struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+// NewResolver 创建解析器
+func NewResolver() *Resolver {
+	return &Resolver{providers: make(map[string]Provider)}
+}
+
+// Register 注册 provider
+func (r *Resolver) Register(name string, provider Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = provider
+}
+
+// Resolve 解析单个值，非引用的值原样返回
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	ref, err := ParseRef(value)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.RLock()
+	provider, ok := r.providers[ref.Provider]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("secret provider %q not registered", ref.Provider)
+	}
+
+	secret, err := provider.Get(ctx, ref.Path, ref.Key)
+	if err != nil {
+		// 错误信息只包含引用本身，不包含任何密钥值
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return secret, nil
+}
+
+// ResolveParams 解析参数中的所有引用，返回解析后的新 map 及被解析的参数名。
+// 传入的 params 不会被修改，解析结果只应保存在内存中。
+func (r *Resolver) ResolveParams(ctx context.Context, params map[string]string) (map[string]string, []string, error) {
+	resolved := make(map[string]string, len(params))
+	var names []string
+	for name, value := range params {
+		if !IsRef(value) {
+			resolved[name] = value
+			continue
+		}
+		secret, err := r.Resolve(ctx, value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("param %s: %w", name, err)
+		}
+		resolved[name] = secret
+		names = append(names, name)
+	}
+	return resolved, names, nil
+}
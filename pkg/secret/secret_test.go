@@ -0,0 +1,120 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type mapProvider map[string]string
+
+func (m mapProvider) Get(ctx context.Context, path, key string) (string, error) {
+	value, ok := m[path+"#"+key]
+	if !ok {
+		return "", fmt.Errorf("not found")
+	}
+	return value, nil
+}
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Ref
+		wantErr bool
+	}{
+		{input: "secret://vault/cloud/aws/prod#secret_key", want: Ref{Provider: "vault", Path: "cloud/aws/prod", Key: "secret_key"}},
+		{input: "secret://env/TF_DB_PASSWORD", want: Ref{Provider: "env", Path: "TF_DB_PASSWORD"}},
+		{input: "secret://vault", wantErr: true},
+		{input: "secret:///path#key", wantErr: true},
+		{input: "secret://vault/#key", wantErr: true},
+		{input: "plain", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		ref, err := ParseRef(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRef(%q) should fail", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRef(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if *ref != tt.want {
+			t.Errorf("ParseRef(%q) = %+v, want %+v", tt.input, *ref, tt.want)
+		}
+		if ref.String() != tt.input {
+			t.Errorf("String() = %q, want %q", ref.String(), tt.input)
+		}
+	}
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	r := NewResolver()
+	r.Register("mem", mapProvider{"db#password": "hunter2"})
+
+	value, err := r.Resolve(context.Background(), "secret://mem/db#password")
+	if err != nil {
+		t.Fatalf("Resolve should succeed: %v", err)
+	}
+	if value != "hunter2" {
+		t.Errorf("value should be 'hunter2', got %q", value)
+	}
+
+	value, _ = r.Resolve(context.Background(), "t3.micro")
+	if value != "t3.micro" {
+		t.Errorf("plain value should pass through, got %q", value)
+	}
+
+	if _, err := r.Resolve(context.Background(), "secret://unknown/db#password"); err == nil {
+		t.Error("unregistered provider should return error")
+	}
+}
+
+func TestResolver_ResolveParams(t *testing.T) {
+	r := NewResolver()
+	r.Register("mem", mapProvider{"db#password": "hunter2"})
+
+	params := map[string]string{
+		"instance_type": "t3.micro",
+		"db_password":   "secret://mem/db#password",
+	}
+	resolved, names, err := r.ResolveParams(context.Background(), params)
+	if err != nil {
+		t.Fatalf("ResolveParams should succeed: %v", err)
+	}
+	if resolved["db_password"] != "hunter2" || resolved["instance_type"] != "t3.micro" {
+		t.Errorf("resolved params wrong: %v", resolved)
+	}
+	if len(names) != 1 || names[0] != "db_password" {
+		t.Errorf("resolved names should be [db_password], got %v", names)
+	}
+	if params["db_password"] != "secret://mem/db#password" {
+		t.Error("input params should not be modified")
+	}
+
+	_, _, err = r.ResolveParams(context.Background(), map[string]string{"x": "secret://mem/db#missing"})
+	if err == nil || !strings.Contains(err.Error(), "param x") {
+		t.Errorf("error should name the param, got %v", err)
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	t.Setenv("PRISM_SECRET_DB", "pw")
+	t.Setenv("DB_PASS", "hidden")
+
+	p := NewEnvProvider("PRISM_SECRET_")
+	value, err := p.Get(context.Background(), "PRISM_SECRET_DB", "")
+	if err != nil || value != "pw" {
+		t.Errorf("Get should return 'pw', got %q, %v", value, err)
+	}
+	if _, err := p.Get(context.Background(), "DB_PASS", ""); err == nil {
+		t.Error("variable outside prefix should be rejected")
+	}
+	if _, err := p.Get(context.Background(), "PRISM_SECRET_MISSING", ""); err == nil {
+		t.Error("missing variable should return error")
+	}
+}
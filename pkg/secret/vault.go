@@ -0,0 +1,113 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// VaultConfig Vault KV v2 配置
+type VaultConfig struct {
+	Address   string // e.g. http://127.0.0.1:8200
+	Token     string
+	Mount     string // KV v2 挂载点，默认 secret
+	Namespace string // Vault Enterprise namespace，可选
+	Timeout   time.Duration
+}
+
+// VaultProvider 通过 Vault KV v2 HTTP API 读取密钥
+type VaultProvider struct {
+	config *VaultConfig
+	client *http.Client
+}
+
+// NewVaultProvider 创建 Vault provider
+func NewVaultProvider(config *VaultConfig) *VaultProvider {
+	if config.Mount == "" {
+		config.Mount = "secret"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &VaultProvider{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// vaultKVResponse KV v2 读取响应
+type vaultKVResponse struct {
+	Data struct {
+		Data map[string]interface{} `json:"data"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// Get 读取 <mount>/data/<path> 中的 key
+func (p *VaultProvider) Get(ctx context.Context, path, key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("vault reference requires a #key")
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s",
+		strings.TrimRight(p.config.Address, "/"),
+		url.PathEscape(p.config.Mount),
+		escapePath(path))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.config.Token)
+	if p.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.config.Namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+
+	var kv vaultKVResponse
+	if err := json.Unmarshal(body, &kv); err != nil && resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(kv.Errors) > 0 {
+			return "", fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.Join(kv.Errors, "; "))
+		}
+		return "", fmt.Errorf("vault returned %d", resp.StatusCode)
+	}
+
+	value, ok := kv.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found at %s", key, path)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// escapePath 逐段转义路径，保留分隔符
+func escapePath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,79 @@
+package secret
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newVaultServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/cloud/aws/prod":
+			w.Write([]byte(`{"data":{"data":{"secret_key":"s3cr3t","port":5432},"metadata":{"version":1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+}
+
+func TestVaultProvider_Get(t *testing.T) {
+	server := newVaultServer(t)
+	defer server.Close()
+
+	p := NewVaultProvider(&VaultConfig{Address: server.URL, Token: "root"})
+
+	value, err := p.Get(context.Background(), "cloud/aws/prod", "secret_key")
+	if err != nil {
+		t.Fatalf("Get should succeed: %v", err)
+	}
+	if value != "s3cr3t" {
+		t.Errorf("value should be 's3cr3t', got %q", value)
+	}
+
+	value, err = p.Get(context.Background(), "cloud/aws/prod", "port")
+	if err != nil || value != "5432" {
+		t.Errorf("non-string value should be JSON encoded, got %q, %v", value, err)
+	}
+
+	if _, err := p.Get(context.Background(), "cloud/aws/prod", "missing"); err == nil {
+		t.Error("missing key should return error")
+	}
+	if _, err := p.Get(context.Background(), "cloud/aws/dev", "secret_key"); err == nil {
+		t.Error("missing path should return error")
+	}
+	if _, err := p.Get(context.Background(), "cloud/aws/prod", ""); err == nil {
+		t.Error("reference without key should return error")
+	}
+}
+
+func TestVaultProvider_PermissionDenied(t *testing.T) {
+	server := newVaultServer(t)
+	defer server.Close()
+
+	p := NewVaultProvider(&VaultConfig{Address: server.URL, Token: "bad"})
+	_, err := p.Get(context.Background(), "cloud/aws/prod", "secret_key")
+	if err == nil {
+		t.Fatal("invalid token should return error")
+	}
+}
+
+func TestResolver_Vault(t *testing.T) {
+	server := newVaultServer(t)
+	defer server.Close()
+
+	r := NewResolver()
+	r.Register("vault", NewVaultProvider(&VaultConfig{Address: server.URL, Token: "root"}))
+
+	value, err := r.Resolve(context.Background(), "secret://vault/cloud/aws/prod#secret_key")
+	if err != nil || value != "s3cr3t" {
+		t.Errorf("Resolve should return 's3cr3t', got %q, %v", value, err)
+	}
+}
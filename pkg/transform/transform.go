@@ -0,0 +1,144 @@
+// Package transform implements the value transform expressions used when
+// translating attribute values between canonical and provider representations.
+//
+// An expression is empty (the value is kept as-is) or one of:
+//
+//	map:{"small":"t3.small","large":"t3.large"}   look the value up, unknown values fail
+//	format:%s-data                                 format the value with fmt
+//	scale:1024                                     multiply a numeric value
+//	unit:GiB:MB                                    convert a numeric value between units
+//	regex:^ip-([0-9-]+)                            extract the first group, or the whole match
+//	lower / upper                                  change the case
+//
+// Size units are B, KB, MB, GB, TB (powers of 1000) and KiB, MiB, GiB, TiB
+// (powers of 1024); duration units are ms, s, min and h.
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Func transforms a single value.
+type Func func(value string) (string, error)
+
+// Compile parses an expression.
+func Compile(expr string) (Func, error) {
+	name, arg, _ := strings.Cut(expr, ":")
+	switch name {
+	case "":
+		return func(value string) (string, error) { return value, nil }, nil
+	case "lower":
+		return func(value string) (string, error) { return strings.ToLower(value), nil }, nil
+	case "upper":
+		return func(value string) (string, error) { return strings.ToUpper(value), nil }, nil
+	case "map":
+		var table map[string]string
+		if err := json.Unmarshal([]byte(arg), &table); err != nil {
+			return nil, fmt.Errorf("invalid map transform: %w", err)
+		}
+		return func(value string) (string, error) {
+			mapped, ok := table[value]
+			if !ok {
+				return "", fmt.Errorf("no mapping for %q", value)
+			}
+			return mapped, nil
+		}, nil
+	case "format":
+		if !strings.Contains(arg, "%s") {
+			return nil, fmt.Errorf("format transform needs a %%s verb: %q", arg)
+		}
+		return func(value string) (string, error) { return fmt.Sprintf(arg, value), nil }, nil
+	case "scale":
+		factor, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scale factor: %w", err)
+		}
+		return func(value string) (string, error) {
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "", fmt.Errorf("scale expects a number: %w", err)
+			}
+			return strconv.FormatFloat(n*factor, 'f', -1, 64), nil
+		}, nil
+	case "unit":
+		from, to, _ := strings.Cut(arg, ":")
+		factor, err := conversion(from, to)
+		if err != nil {
+			return nil, err
+		}
+		return func(value string) (string, error) {
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "", fmt.Errorf("unit expects a number: %w", err)
+			}
+			return strconv.FormatFloat(n*factor, 'f', -1, 64), nil
+		}, nil
+	case "regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex transform: %w", err)
+		}
+		return func(value string) (string, error) {
+			m := re.FindStringSubmatch(value)
+			if m == nil {
+				return "", fmt.Errorf("%q does not match %s", value, arg)
+			}
+			if len(m) > 1 {
+				return m[1], nil
+			}
+			return m[0], nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown transform: %s", name)
+}
+
+// unit 单位及其换算到基本单位 (字节、秒) 的系数
+type unit struct {
+	kind   string
+	factor float64
+}
+
+var units = map[string]unit{
+	"B":   {"size", 1},
+	"KB":  {"size", 1e3},
+	"MB":  {"size", 1e6},
+	"GB":  {"size", 1e9},
+	"TB":  {"size", 1e12},
+	"KiB": {"size", 1 << 10},
+	"MiB": {"size", 1 << 20},
+	"GiB": {"size", 1 << 30},
+	"TiB": {"size", 1 << 40},
+	"ms":  {"duration", 1e-3},
+	"s":   {"duration", 1},
+	"min": {"duration", 60},
+	"h":   {"duration", 3600},
+}
+
+// conversion 返回 from 单位的值换算为 to 单位时的系数
+func conversion(from, to string) (float64, error) {
+	f, ok := units[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit: %q", from)
+	}
+	t, ok := units[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit: %q", to)
+	}
+	if f.kind != t.kind {
+		return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+	}
+	return f.factor / t.factor, nil
+}
+
+// Apply transforms a value with an expression.
+func Apply(expr, value string) (string, error) {
+	fn, err := Compile(expr)
+	if err != nil {
+		return "", err
+	}
+	return fn(value)
+}
@@ -0,0 +1,43 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		expr  string
+		value string
+		want  string
+		fail  bool
+	}{
+		{"", "as-is", "as-is", false},
+		{"lower", "PROD", "prod", false},
+		{"upper", "prod", "PROD", false},
+		{`map:{"small":"t3.small"}`, "small", "t3.small", false},
+		{`map:{"small":"t3.small"}`, "huge", "", true},
+		{"format:%s-data", "web", "web-data", false},
+		{"scale:1024", "2", "2048", false},
+		{"scale:0.001", "1500", "1.5", false},
+		{"scale:2", "many", "", true},
+		{"unit:GiB:MiB", "8", "8192", false},
+		{"unit:GB:MB", "1.5", "1500", false},
+		{"unit:ms:s", "2500", "2.5", false},
+		{"unit:h:min", "many", "", true},
+		{`regex:^ip-([0-9-]+)`, "ip-10-0-0-1.ec2.internal", "10-0-0-1", false},
+		{`regex:[0-9]+`, "vol-42", "42", false},
+		{`regex:^ip-`, "host", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Apply(tt.expr, tt.value)
+		assert.Equal(t, tt.fail, err != nil, "%s(%s): %v", tt.expr, tt.value, err)
+		assert.Equal(t, tt.want, got, tt.expr)
+	}
+
+	for _, expr := range []string{"reverse", "map:{", "format:no verb", "scale:x", "unit:GiB:s", "unit:GiB", "unit:EiB:B", "regex:("} {
+		_, err := Compile(expr)
+		assert.Error(t, err, expr)
+	}
+}